@@ -18,7 +18,12 @@ package archive
 */
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"time"
 
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
@@ -32,14 +37,16 @@ type ArchiveItem struct {
 	FileSystemPath string
 	ArchivePath    string
 	Data           []byte
+	//timestamp for Data. If zero, the current time is used
+	ModTime time.Time
 }
 
 func ArchiveItemFromFileSystem(fileSystemPath, archivePath string) ArchiveItem {
-	return ArchiveItem{fileSystemPath, archivePath, nil}
+	return ArchiveItem{FileSystemPath: fileSystemPath, ArchivePath: archivePath}
 }
 
 func ArchiveItemFromBytes(data []byte, archivePath string) ArchiveItem {
-	return ArchiveItem{"", archivePath, data}
+	return ArchiveItem{ArchivePath: archivePath, Data: data}
 }
 
 // type definition for different archiving implementations
@@ -47,13 +54,7 @@ type Archiver func(archiveFilename string, itemsToArchive []ArchiveItem) error
 
 // goxc function to archive a binary along with supporting files (e.g. README or LICENCE).
 func ArchiveBinariesAndResources(outDir, platName string, binPaths []string, appName string, resources []string, settings config.Settings, archiver Archiver, ending string, includeTopLevelDir bool) (zipFilename string, err error) {
-	var zipName string
-	if settings.PackageVersion != "" && settings.PackageVersion != core.PACKAGE_VERSION_DEFAULT {
-		//0.1.6 using appname_version_platform. See issue 3
-		zipName = appName + "_" + settings.GetFullVersionName() + "_" + platName
-	} else {
-		zipName = appName + "_" + platName
-	}
+	zipName := ArtifactName(appName, platName, settings)
 	zipFilename = filepath.Join(outDir, zipName+"."+ending)
 	var zipDir string
 	if includeTopLevelDir {
@@ -79,3 +80,30 @@ func ArchiveBinariesAndResources(outDir, platName string, binPaths []string, app
 	err = archiver(zipFilename, toArchive)
 	return
 }
+
+// Base name (without extension) for a platform's artifact, e.g. appname_version_platform
+func ArtifactName(appName, platName string, settings config.Settings) string {
+	for _, part := range settings.GetMatrixVars().Parts() {
+		appName = appName + "_" + part
+	}
+	if settings.PackageVersion != "" && settings.PackageVersion != core.PACKAGE_VERSION_DEFAULT {
+		//0.1.6 using appname_version_platform. See issue 3
+		return appName + "_" + settings.GetFullVersionName() + "_" + platName
+	}
+	return appName + "_" + platName
+}
+
+// The hex-encoded SHA-256 of an artifact, as published alongside it by package managers
+func Sha256File(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
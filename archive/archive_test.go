@@ -0,0 +1,95 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSha256File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "a.txt")
+	err = ioutil.WriteFile(filename, []byte("abc"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := Sha256File(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("unexpected sha256 %s", sum)
+	}
+}
+
+func TestZipData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "a.zip")
+	err = Zip(filename, []ArchiveItem{ArchiveItemFromBytes([]byte("hello"), `dir\a.txt`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if len(r.File) != 1 || r.File[0].Name != "dir/a.txt" {
+		t.Fatalf("unexpected zip entries %v", r.File)
+	}
+	rc, err := r.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("unexpected content %q", data)
+	}
+}
+
+func TestZipDirectorySorted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "app"), 0755)
+	for _, name := range []string{"b", "d", "a", "e", "c"} {
+		err = ioutil.WriteFile(filepath.Join(dir, "app", name), []byte(name), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	filename := filepath.Join(dir, "a.zip")
+	err = Zip(filename, []ArchiveItem{ArchiveItemFromFileSystem(filepath.Join(dir, "app"), "app.app")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	entries := []string{}
+	for _, f := range r.File {
+		entries = append(entries, f.Name)
+	}
+	if fmt.Sprint(entries) != "[app.app/a app.app/b app.app/c app.app/d app.app/e]" {
+		t.Errorf("unexpected zip entries %v", entries)
+	}
+}
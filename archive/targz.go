@@ -22,6 +22,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -78,7 +79,10 @@ func TarGzWrite(item ArchiveItem, tw *tar.Writer, fi os.FileInfo) (err error) {
 		h.Name = strings.Replace(item.ArchivePath, "\\", "/", -1)
 		h.Size = int64(len(item.Data))
 		h.Mode = int64(0644) //? is this ok?
-		h.ModTime = time.Now()
+		h.ModTime = item.ModTime
+		if h.ModTime.IsZero() {
+			h.ModTime = time.Now()
+		}
 		err = tw.WriteHeader(h)
 		if err == nil {
 			_, err = tw.Write(item.Data)
@@ -110,6 +114,8 @@ func addDirectoryToTarGz(dirPath ArchiveItem, tw *tar.Writer) error {
 		defer dir.Close()
 		fis, err := dir.Readdir(0)
 		if err == nil {
+			//Readdir's order varies, and archives should be reproducible
+			sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
 			for _, fi := range fis {
 				curPath := ArchiveItemFromFileSystem(filepath.Join(dirPath.FileSystemPath, fi.Name()), filepath.Join(dirPath.ArchivePath, fi.Name()))
 				err = addItemToTarGz(curPath, tw)
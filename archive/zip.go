@@ -20,10 +20,13 @@ import (
 	"archive/zip"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
-//TODO: folder support
+// Zip implementation of Archiver. Directories are added recursively.
 func Zip(zipFilename string, itemsToArchive []ArchiveItem) error {
 	zf, err := os.Create(zipFilename)
 	if err != nil {
@@ -46,10 +49,16 @@ func Zip(zipFilename string, itemsToArchive []ArchiveItem) error {
 }
 
 func addFileToZIP(zw *zip.Writer, item ArchiveItem) (err error) {
+	if item.FileSystemPath == "" {
+		return addDataToZIP(zw, item)
+	}
 	binfo, err := os.Stat(item.FileSystemPath)
 	if err != nil {
 		return
 	}
+	if binfo.IsDir() {
+		return addDirectoryToZIP(zw, item)
+	}
 	header, err := zip.FileInfoHeader(binfo)
 	if err != nil {
 		return
@@ -70,3 +79,45 @@ func addFileToZIP(zw *zip.Writer, item ArchiveItem) (err error) {
 	_, err = io.Copy(w, bf)
 	return
 }
+
+// adds an item's Data, with the item's ModTime (set it for reproducible archives)
+func addDataToZIP(zw *zip.Writer, item ArchiveItem) error {
+	modTime := item.ModTime
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	header := &zip.FileHeader{
+		//always use forward slashes even on Windows
+		Name:     strings.Replace(item.ArchivePath, "\\", "/", -1),
+		Method:   zip.Deflate,
+		Modified: modTime}
+	header.SetMode(0644)
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(item.Data)
+	return err
+}
+
+func addDirectoryToZIP(zw *zip.Writer, dirItem ArchiveItem) error {
+	dir, err := os.Open(dirItem.FileSystemPath)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	fis, err := dir.Readdir(0)
+	if err != nil {
+		return err
+	}
+	//Readdir's order varies, and archives should be reproducible
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	for _, fi := range fis {
+		item := ArchiveItemFromFileSystem(filepath.Join(dirItem.FileSystemPath, fi.Name()), filepath.Join(dirItem.ArchivePath, fi.Name()))
+		err = addFileToZIP(zw, item)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
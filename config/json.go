@@ -267,6 +267,8 @@ func loadSettingsSection(settingsSection map[string]interface{}) (settings Setti
 			settings.ResourcesExclude, err = typeutils.ToString(v, k)
 		case "MainDirsExclude":
 			settings.MainDirsExclude, err = typeutils.ToString(v, k)
+		case "NestedModulesInclude":
+			settings.NestedModulesInclude, err = typeutils.ToString(v, k)
 		//deprecated
 		case "Resources":
 			for k2, v2 := range v.(map[string]interface{}) {
@@ -304,6 +306,10 @@ func loadSettingsSection(settingsSection map[string]interface{}) (settings Setti
 			}
 		case "Env":
 			settings.Env, err = typeutils.ToStringSlice(v, k)
+		case "GoToolchains":
+			settings.GoToolchains, err = typeutils.ToStringSlice(v, k)
+		case "Variants":
+			settings.Variants, err = variantsFromSlice(v, k)
 		default:
 			log.Printf("Warning!! Unrecognised Setting '%s' (value %v)", k, v)
 		}
@@ -67,3 +67,29 @@ func TestLoadParentFile(t *testing.T) {
 	}
 }
 */
+
+func TestLoadVariants(t *testing.T) {
+	m := map[string]interface{}{
+		"ConfigVersion": "0.9",
+		"Variants": []interface{}{
+			map[string]interface{}{"Name": "community"},
+			map[string]interface{}{"Name": "enterprise", "Tags": "enterprise", "LdFlagsXVars": map[string]interface{}{"main.Edition": "enterprise"}},
+		},
+	}
+	settings, err := loadSettingsSection(m)
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(settings.Variants) != 2 {
+		t.Fatalf("Unexpected variants: %+v", settings.Variants)
+	}
+	tags := "sqlite"
+	settings.BuildSettings = &BuildSettings{Tags: &tags, LdFlagsXVars: &map[string]interface{}{"Version": "main.VERSION"}}
+	enterprise := settings.ForVariant(settings.Variants[1])
+	if enterprise.Variant != "enterprise" || *enterprise.BuildSettings.Tags != "sqlite,enterprise" {
+		t.Fatalf("Unexpected variant settings: %+v", enterprise.BuildSettings)
+	}
+	if len(*enterprise.BuildSettings.LdFlagsXVars) != 2 || len(*settings.BuildSettings.LdFlagsXVars) != 1 {
+		t.Fatalf("Unexpected LdFlagsXVars: %v (base %v)", *enterprise.BuildSettings.LdFlagsXVars, *settings.BuildSettings.LdFlagsXVars)
+	}
+}
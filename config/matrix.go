@@ -0,0 +1,141 @@
+package config
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/typeutils"
+)
+
+// A build variant (e.g. a 'community' or 'enterprise' edition).
+// Tags, LdFlagsXVars and resources are added to the main settings when building the variant.
+type Variant struct {
+	Name             string
+	Tags             string                 `json:",omitempty"`
+	LdFlagsXVars     map[string]interface{} `json:",omitempty"`
+	ResourcesInclude string                 `json:",omitempty"`
+	ResourcesExclude string                 `json:",omitempty"`
+}
+
+// Returns a copy of the settings, with the variant applied.
+func (s *Settings) ForVariant(variant Variant) *Settings {
+	ret := *s
+	ret.Variant = variant.Name
+	bs := BuildSettings{}
+	if s.BuildSettings != nil {
+		bs = *s.BuildSettings
+	}
+	if variant.Tags != "" {
+		tags := variant.Tags
+		if bs.Tags != nil && *bs.Tags != "" {
+			tags = *bs.Tags + "," + tags
+		}
+		bs.Tags = &tags
+	}
+	if len(variant.LdFlagsXVars) > 0 {
+		xVars := map[string]interface{}{}
+		if bs.LdFlagsXVars != nil {
+			for k, v := range *bs.LdFlagsXVars {
+				xVars[k] = v
+			}
+		}
+		for k, v := range variant.LdFlagsXVars {
+			xVars[k] = v
+		}
+		bs.LdFlagsXVars = &xVars
+	}
+	ret.BuildSettings = &bs
+	if variant.ResourcesInclude != "" {
+		ret.ResourcesInclude = joinGlobs(s.ResourcesInclude, variant.ResourcesInclude)
+	}
+	if variant.ResourcesExclude != "" {
+		ret.ResourcesExclude = joinGlobs(s.ResourcesExclude, variant.ResourcesExclude)
+	}
+	return &ret
+}
+
+// Returns a copy of the settings, using the given Go installation.
+// GOTOOLCHAIN=local stops the go command switching to another version (e.g. due to a 'toolchain' directive in go.mod),
+// and GOROOT overrides any GOROOT in the environment, which would otherwise point the toolchain at another standard library.
+func (s *Settings) ForGoToolchain(goroot, goVersion string) *Settings {
+	ret := *s
+	ret.GoRoot = goroot
+	ret.GoVersion = goVersion
+	ret.Env = append(append([]string{}, s.Env...), "GOTOOLCHAIN=local", "GOROOT="+goroot)
+	return &ret
+}
+
+// Resolves the path of a binary from OutPath, including any build matrix variables (e.g. {{.Variant}})
+func (s *Settings) GetAbsoluteBin(goos, arch, exeName, workingDirectory string) (string, error) {
+	return core.GetAbsoluteBinForMatrix(goos, arch, s.AppName, exeName, workingDirectory, s.GetFullVersionName(), s.OutPath, s.ArtifactsDest, s.GetMatrixVars())
+}
+
+func (s *Settings) GetMatrixVars() core.MatrixVars {
+	return core.MatrixVars{Variant: s.Variant, GoVersion: s.GoVersion}
+}
+
+func joinGlobs(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func variantsFromSlice(v interface{}, k string) ([]Variant, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s should be a json array, not a %T", k, v)
+	}
+	variants := []Variant{}
+	for i, item := range items {
+		m, err := typeutils.ToMap(item, fmt.Sprintf("%s[%d]", k, i))
+		if err != nil {
+			return nil, err
+		}
+		variant := Variant{}
+		for k2, v2 := range m {
+			key := fmt.Sprintf("%s[%d]:%s", k, i, k2)
+			switch k2 {
+			case "Name":
+				variant.Name, err = typeutils.ToString(v2, key)
+			case "Tags":
+				variant.Tags, err = typeutils.ToString(v2, key)
+			case "LdFlagsXVars":
+				variant.LdFlagsXVars, err = typeutils.ToMap(v2, key)
+			case "ResourcesInclude":
+				variant.ResourcesInclude, err = typeutils.ToString(v2, key)
+			case "ResourcesExclude":
+				variant.ResourcesExclude, err = typeutils.ToString(v2, key)
+			default:
+				err = errors.New("Unrecognised variant setting '" + key + "'")
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if variant.Name == "" {
+			return nil, fmt.Errorf("%s[%d] has no Name", k, i)
+		}
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
@@ -74,6 +74,8 @@ type Settings struct {
 	MainDirsExclude string `json:",omitempty"`
 	//0.13.x source exclusion (source dirs)
 	SourceDirsExclude string `json:",omitempty"`
+	//Go modules: nested modules (dirs with their own go.mod) are skipped unless they match these comma-separated globs
+	NestedModulesInclude string `json:",omitempty"`
 
 	//versioning
 	PackageVersion string `json:",omitempty"`
@@ -97,11 +99,18 @@ type Settings struct {
 
 	GoRoot string `json:"-"` //only settable by a flag
 
-	//TODO?
-	//PreferredGoVersion string `json:",omitempty"` //try to use a go version...
+	//Go installations to build & test with: GOROOT dirs, or versions installed as SDKs (e.g. 'go1.21.5', in ~/sdk)
+	GoToolchains []string `json:",omitempty"`
+	//the version of the toolchain currently in use (only set when GoToolchains are specified)
+	GoVersion string `json:"-"`
 
 	//v0.10.x
 	Env []string `json:",omitempty"`
+
+	//build variants (editions). Artifact-producing tasks run once per variant
+	Variants []Variant `json:",omitempty"`
+	//the variant currently being built
+	Variant string `json:"-"`
 }
 
 func (s *Settings) IsVerbose() bool {
@@ -289,6 +298,9 @@ func Merge(high Settings, low Settings) Settings {
 	if high.MainDirsExclude == "" {
 		high.MainDirsExclude = low.MainDirsExclude
 	}
+	if high.NestedModulesInclude == "" {
+		high.NestedModulesInclude = low.NestedModulesInclude
+	}
 	if high.AppName == "" {
 		high.AppName = low.AppName
 	}
@@ -383,5 +395,11 @@ func Merge(high Settings, low Settings) Settings {
 	if len(high.Env) == 0 {
 		high.Env = low.Env
 	}
+	if len(high.GoToolchains) == 0 {
+		high.GoToolchains = low.GoToolchains
+	}
+	if len(high.Variants) == 0 {
+		high.Variants = low.Variants
+	}
 	return high
 }
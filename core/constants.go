@@ -56,6 +56,8 @@ const (
 
 	//taskname required by config/json
 	TASK_BUILD_TOOLCHAIN = "toolchain"
+	//taskname required for build metadata (vcs setting)
+	TASK_TAG = "tag"
 	//windows required inside core methods
 	WINDOWS = "windows"
 )
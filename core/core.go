@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"go/build"
 	"log"
 	"os"
 	"os/user"
@@ -184,6 +185,7 @@ func ParseIncludeResources(basedir, includeResources, excludeResources string, i
 }
 
 // Get application name (defaults to dirname)
+// For a module root, the name is derived from the module path instead.
 func GetAppName(specifiedAppName, workingDirectory string) string {
 	if specifiedAppName != "" {
 		return specifiedAppName
@@ -192,6 +194,14 @@ func GetAppName(specifiedAppName, workingDirectory string) string {
 	if err != nil {
 		log.Printf("Error: %v", err)
 	}
+	if moduleRoot := FindModuleRoot(appDirname); moduleRoot != "" && moduleRoot == appDirname {
+		modulePath, err := GetModulePath(filepath.Join(moduleRoot, GOMOD_FILENAME))
+		if err != nil {
+			log.Printf("Could not read module path: %v", err)
+		} else {
+			return AppNameFromModulePath(modulePath)
+		}
+	}
 	appName := filepath.Base(appDirname)
 	return appName
 }
@@ -199,10 +209,15 @@ func GetAppName(specifiedAppName, workingDirectory string) string {
 // Tries to find the most relevant GOPATH element.
 // First, tries to find an element which is a parent of the current directory.
 // If not, it uses the first one.
+// When GOPATH is unset (common with Go modules), the default GOPATH is used.
 func GetGoPathElement(workingDirectory string) string {
 	//build.Import(path, srcDir string, mode ImportMode) (*Package, error)
 	var gopath string
 	gopathVar := os.Getenv("GOPATH")
+	if gopathVar == "" {
+		// module-era default ($HOME/go), as reported by `go env GOPATH`
+		gopathVar = build.Default.GOPATH
+	}
 	if gopathVar == "" {
 		log.Printf("GOPATH env variable not set! Using '.'")
 		gopath = "."
@@ -304,6 +319,7 @@ type RootDirVars struct {
 }
 type BinNameVars struct {
 	RootDirVars
+	MatrixVars
 	Dest    string
 	ExeName string
 	Version string
@@ -312,7 +328,29 @@ type BinNameVars struct {
 	Ext     string
 }
 
+// Variables identifying one build in a build matrix (empty when not building a matrix)
+type MatrixVars struct {
+	Variant   string
+	GoVersion string
+}
+
+// The non-empty matrix variables, for use in file & directory names
+func (m MatrixVars) Parts() []string {
+	parts := []string{}
+	for _, part := range []string{m.Variant, m.GoVersion} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
 func GetAbsoluteBin(goos, arch string, appName, exeName, workingDirectory, fullVersionName, templateText string, artifactsDestSetting string) (string, error) {
+	return GetAbsoluteBinForMatrix(goos, arch, appName, exeName, workingDirectory, fullVersionName, templateText, artifactsDestSetting, MatrixVars{})
+}
+
+// As GetAbsoluteBin, also making the matrix variables (e.g. {{.Variant}}) available to the template
+func GetAbsoluteBinForMatrix(goos, arch string, appName, exeName, workingDirectory, fullVersionName, templateText string, artifactsDestSetting string, matrix MatrixVars) (string, error) {
 	tmpl, err := template.New("binTemplate").Parse(templateText)
 	if err != nil {
 		return "", err
@@ -328,7 +366,7 @@ func GetAbsoluteBin(goos, arch string, appName, exeName, workingDirectory, fullV
 	homeDir := UserHomeDir()
 	myGoPath := GetGoPathElement(workingDirectory)
 	rdv := RootDirVars{goBin, myGoPath, homeDir, appName, string(os.PathSeparator)}
-	data := BinNameVars{rdv, root, exeName, fullVersionName, goos, arch, ending}
+	data := BinNameVars{rdv, matrix, root, exeName, fullVersionName, goos, arch, ending}
 	var out bytes.Buffer
 	err = tmpl.Execute(&out, data)
 	if err != nil {
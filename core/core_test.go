@@ -81,3 +81,40 @@ func TestGoVersion(t *testing.T) {
 	v := runtime.Version()
 	t.Logf("Version is %s", v)
 }
+
+func TestAppNameFromModulePath(t *testing.T) {
+	for modulePath, expected := range map[string]string{
+		"example.com/app":    "app",
+		"example.com/app/v2": "app",
+		"app":                "app",
+		"v2":                 "v2",
+	} {
+		if actual := AppNameFromModulePath(modulePath); actual != expected {
+			t.Fatalf("unexpected app name for %s: %s != %s", modulePath, actual, expected)
+		}
+	}
+}
+
+func TestGetGoVersionFromVersionFile(t *testing.T) {
+	goroot, err := ioutil.TempDir("", "goxc-goroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(goroot)
+	err = ioutil.WriteFile(filepath.Join(goroot, "VERSION"), []byte("go1.21.5\ntime 2023-11-29T21:21:52Z\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := GetGoVersion(goroot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "go1.21.5" {
+		t.Fatalf("unexpected version %s", v)
+	}
+	for _, notAVersion := range []string{"1.21", "/usr/local/go", "go"} {
+		if IsGoVersion(notAVersion) {
+			t.Fatalf("'%s' should not be a go version", notAVersion)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package core
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	GOMOD_FILENAME  = "go.mod"
+	GOWORK_FILENAME = "go.work"
+)
+
+var majorVersionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// Finds the directory containing the nearest go.mod, searching upwards from the given directory.
+// Returns an empty string when modules are disabled (GO111MODULE=off) or no go.mod exists.
+func FindModuleRoot(dir string) string {
+	if os.Getenv("GO111MODULE") == "off" {
+		return ""
+	}
+	return findUpwards(dir, GOMOD_FILENAME)
+}
+
+// Finds the go.work file governing the given directory (honouring the GOWORK env variable).
+// Returns an empty string when there is no workspace.
+func FindWorkFile(dir string) string {
+	if os.Getenv("GO111MODULE") == "off" {
+		return ""
+	}
+	gowork := os.Getenv("GOWORK")
+	if gowork == "off" {
+		return ""
+	}
+	if gowork != "" {
+		return gowork
+	}
+	workDir := findUpwards(dir, GOWORK_FILENAME)
+	if workDir == "" {
+		return ""
+	}
+	return filepath.Join(workDir, GOWORK_FILENAME)
+}
+
+func findUpwards(dir, filename string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, filename)); err == nil && !fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Reads the module path from the 'module' directive of a go.mod file
+func GetModulePath(goModFile string) (string, error) {
+	directives, err := readModDirectives(goModFile, "module")
+	if err != nil {
+		return "", err
+	}
+	if len(directives) < 1 {
+		return "", errors.New("No 'module' directive found in " + goModFile)
+	}
+	return directives[0], nil
+}
+
+// Lists the module directories named by the 'use' directives of a go.work file.
+// Relative directories are resolved against the directory containing the go.work file.
+func GetWorkspaceModuleDirs(goWorkFile string) ([]string, error) {
+	directives, err := readModDirectives(goWorkFile, "use")
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{}
+	for _, dir := range directives {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(goWorkFile), dir)
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	return dirs, nil
+}
+
+// Derives an application name from a module path. The last path element is used, skipping any major version suffix (e.g. 'example.com/app/v2' => 'app').
+func AppNameFromModulePath(modulePath string) string {
+	parts := strings.Split(modulePath, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && majorVersionSuffix.MatchString(name) {
+		name = parts[len(parts)-2]
+	}
+	return name
+}
+
+// Reads the arguments of a given directive from go.mod/go.work syntax, in both single-line and block ('(...)') form.
+func readModDirectives(filename, directive string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret := []string{}
+	inBlock := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i > -1 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if inBlock {
+			if line == ")" {
+				inBlock = false
+			} else {
+				ret = append(ret, unquoteModToken(line))
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if fields[0] != directive || len(fields) < 2 {
+			continue
+		}
+		if fields[1] == "(" {
+			inBlock = true
+		} else {
+			ret = append(ret, unquoteModToken(fields[1]))
+		}
+	}
+	return ret, scanner.Err()
+}
+
+func unquoteModToken(token string) string {
+	token = strings.Fields(token)[0]
+	if strings.HasPrefix(token, "\"") || strings.HasPrefix(token, "`") {
+		if unquoted, err := strconv.Unquote(token); err == nil {
+			return unquoted
+		}
+	}
+	return token
+}
@@ -0,0 +1,71 @@
+package core
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var goVersionPattern = regexp.MustCompile(`^go[0-9]+(\.[0-9]+)*((rc|beta)[0-9]+)?$`)
+
+// Checks whether a string looks like a Go version name (e.g. 'go1.21.5')
+func IsGoVersion(s string) bool {
+	return goVersionPattern.MatchString(s)
+}
+
+// Resolves a toolchain (either a GOROOT directory, or a version such as 'go1.21.5') to a GOROOT directory.
+// Versions are looked up in the SDK directory used by `golang.org/dl` (~/sdk/<version>).
+func ResolveGoRoot(toolchain string) (string, error) {
+	goroot := toolchain
+	if IsGoVersion(toolchain) {
+		goroot = filepath.Join(UserHomeDir(), "sdk", toolchain)
+	} else if strings.HasPrefix(goroot, "~/") {
+		goroot = strings.Replace(goroot, "~", UserHomeDir(), 1)
+	}
+	goBin := filepath.Join(goroot, "bin", "go")
+	if _, err := os.Stat(goBin); err != nil {
+		if _, err := os.Stat(goBin + ".exe"); err != nil {
+			return "", errors.New("Go toolchain '" + toolchain + "' not found (no " + goBin + "). Install it with `go install golang.org/dl/" + filepath.Base(goroot) + "@latest && " + filepath.Base(goroot) + " download`")
+		}
+	}
+	return filepath.Abs(goroot)
+}
+
+// Gets the version of the Go installation at goroot, from its VERSION file or else from `go env GOVERSION`
+func GetGoVersion(goroot string) (string, error) {
+	f, err := os.Open(filepath.Join(goroot, "VERSION"))
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		if scanner.Scan() && IsGoVersion(strings.TrimSpace(scanner.Text())) {
+			return strings.TrimSpace(scanner.Text()), nil
+		}
+	}
+	cmd := exec.Command(filepath.Join(goroot, "bin", "go"), "env", "GOVERSION")
+	cmd.Env = append(os.Environ(), "GOROOT="+goroot, "GOTOOLCHAIN=local")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
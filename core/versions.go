@@ -0,0 +1,145 @@
+package core
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/typeutils"
+)
+
+// Directory names which look like versions, e.g. '1.2', '1.2.3', 'v1.2.3-rc1' or '1.2.3+b4' (but not '2023-archive' or '1.backup')
+var versionDirName = regexp.MustCompile(`^v?\d+\.\d+(\.\d+)?(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// Compares versions as semver does (see http://semver.org/): numeric parts numerically, and prereleases before releases. Build info ('+...') is only a tie-breaker.
+// Returns -1, 0 or 1
+func CompareVersions(a, b string) int {
+	a, aBuild := splitVersion(strings.TrimPrefix(a, "v"), "+")
+	b, bBuild := splitVersion(strings.TrimPrefix(b, "v"), "+")
+	aRelease, aPre := splitVersion(a, "-")
+	bRelease, bPre := splitVersion(b, "-")
+	if c := compareIdentifiers(aRelease, bRelease); c != 0 {
+		return c
+	}
+	//a release is newer than its prereleases
+	if aPre == "" && bPre != "" {
+		return 1
+	}
+	if aPre != "" && bPre == "" {
+		return -1
+	}
+	if c := compareIdentifiers(aPre, bPre); c != 0 {
+		return c
+	}
+	return compareIdentifiers(aBuild, bBuild)
+}
+
+func splitVersion(version, separator string) (string, string) {
+	parts := strings.SplitN(version, separator, 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// Compares dot-separated identifiers, numerically where both are numbers. Fewer identifiers sorts first
+func compareIdentifiers(a, b string) int {
+	if a == b {
+		return 0
+	}
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNum != bNum {
+				if aNum < bNum {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			//numbers sort before text
+			return -1
+		case bErr == nil:
+			return 1
+		case aParts[i] != bParts[i]:
+			if aParts[i] < bParts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(aParts) < len(bParts):
+		return -1
+	case len(aParts) > len(bParts):
+		return 1
+	}
+	return 0
+}
+
+// The version directories in 'dir', newest first. Other entries (including symlinks) are ignored
+func VersionDirs(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	versions := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && versionDirName.MatchString(entry.Name()) {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return CompareVersions(versions[i], versions[j]) > 0 })
+	return versions, nil
+}
+
+// Removes all but the newest 'keepLast' version directories in 'dir' (see VersionDirs). Versions in 'keep' are never removed.
+// Returns the removed directories
+func PruneVersions(dir string, keepLast int, keep []string, isVerbose bool) ([]string, error) {
+	versions, err := VersionDirs(dir)
+	if err != nil || keepLast < 1 || len(versions) <= keepLast {
+		return nil, err
+	}
+	removed := []string{}
+	for _, version := range versions[keepLast:] {
+		if typeutils.StringSliceContains(keep, version) {
+			continue
+		}
+		if isVerbose {
+			log.Printf("Pruning old version %s", filepath.Join(dir, version))
+		}
+		err = os.RemoveAll(filepath.Join(dir, version))
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, filepath.Join(dir, version))
+	}
+	return removed, nil
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	ordered := []string{"0.9", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "v1.0.1", "1.2", "1.10"}
+	for i := 0; i+1 < len(ordered); i++ {
+		if CompareVersions(ordered[i], ordered[i+1]) != -1 || CompareVersions(ordered[i+1], ordered[i]) != 1 {
+			t.Errorf("expected %s < %s", ordered[i], ordered[i+1])
+		}
+	}
+	if CompareVersions("1.0.0+b1", "1.0.0+b1") != 0 || CompareVersions("1.0.0+b2", "1.0.0+b1") != 1 {
+		t.Errorf("unexpected comparison of builds")
+	}
+}
+
+func TestPruneVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"0.9", "0.10", "1.0-rc1", "1.0", "docs", ".hidden", "2023-archive", "1.backup"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "2.0"), []byte("not a directory"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := PruneVersions(dir, 2, []string{"0.9"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != filepath.Join(dir, "0.10") {
+		t.Errorf("unexpected removals %v", removed)
+	}
+	versions, _ := VersionDirs(dir)
+	if len(versions) != 3 || versions[0] != "1.0" || versions[1] != "1.0-rc1" || versions[2] != "0.9" {
+		t.Errorf("unexpected versions %v", versions)
+	}
+	for _, name := range []string{"docs", ".hidden", "2.0", "2023-archive", "1.backup"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s should be left alone: %v", name, err)
+		}
+	}
+}
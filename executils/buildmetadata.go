@@ -0,0 +1,201 @@
+package executils
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+)
+
+// Build metadata, available to LdFlagsXVars templates (e.g. "main.Commit": "{{.Commit}}")
+type BuildMetadata struct {
+	Version     string
+	Commit      string
+	ShortCommit string
+	CommitDate  string
+	Branch      string
+	Dirty       bool
+	Tag         string
+	BuildDate   string
+	Builder     string
+}
+
+var (
+	// 'legacy' LdFlagsXVars keys, mapping a metadata field to a variable name (e.g. "Version": "main.VERSION")
+	xVarFieldNames = map[string]string{
+		"Version":     "Version",
+		"TimeNow":     "BuildDate",
+		"BuildDate":   "BuildDate",
+		"Commit":      "Commit",
+		"ShortCommit": "ShortCommit",
+		"CommitDate":  "CommitDate",
+		"Branch":      "Branch",
+		"Dirty":       "Dirty",
+		"Tag":         "Tag",
+		"Builder":     "Builder",
+	}
+	vcsMetadataCache = map[string]BuildMetadata{}
+	vcsMetadataMutex sync.Mutex
+)
+
+// Gathers build metadata for the given directory. VCS details are looked up using the 'vcs' configured for the 'tag' task.
+// Outside of a repository, the VCS fields are left empty.
+func GetBuildMetadata(workingDirectory string, settings *config.Settings) BuildMetadata {
+	vcs := settings.GetTaskSettingString(core.TASK_TAG, "vcs")
+	if vcs == "" {
+		vcs = "git"
+	}
+	md := getVcsMetadata(workingDirectory, vcs, settings.IsVerbose())
+	md.Version = settings.GetFullVersionName()
+	md.BuildDate = GetBuildDate().Format(time.RFC3339)
+	md.Builder = getBuilder()
+	return md
+}
+
+// The build date honours SOURCE_DATE_EPOCH (see https://reproducible-builds.org/specs/source-date-epoch/), defaulting to the current time.
+func GetBuildDate() time.Time {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		secs, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			log.Printf("WARNING: invalid SOURCE_DATE_EPOCH '%s': %v", epoch, err)
+		} else {
+			return time.Unix(secs, 0).UTC()
+		}
+	}
+	return time.Now()
+}
+
+func getBuilder() string {
+	username := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return username
+	}
+	return username + "@" + hostname
+}
+
+// vcs lookups are cached, as the same details are needed for each platform
+func getVcsMetadata(workingDirectory, vcs string, isVerbose bool) BuildMetadata {
+	vcsMetadataMutex.Lock()
+	defer vcsMetadataMutex.Unlock()
+	key := vcs + ":" + workingDirectory
+	if md, exists := vcsMetadataCache[key]; exists {
+		return md
+	}
+	md := BuildMetadata{}
+	if vcs != "git" {
+		log.Printf("WARNING: build metadata is only supported for 'git' (not '%s')", vcs)
+	} else {
+		commit, err := gitOutput(workingDirectory, "rev-parse", "HEAD")
+		if err != nil {
+			if isVerbose {
+				log.Printf("No git metadata available for %s: %v", workingDirectory, err)
+			}
+		} else {
+			md.Commit = commit
+			md.ShortCommit, _ = gitOutput(workingDirectory, "rev-parse", "--short", "HEAD")
+			md.CommitDate, _ = gitOutput(workingDirectory, "log", "-1", "--format=%cI")
+			md.Branch, _ = gitOutput(workingDirectory, "rev-parse", "--abbrev-ref", "HEAD")
+			status, err := gitOutput(workingDirectory, "status", "--porcelain")
+			md.Dirty = err == nil && status != ""
+			//no tags is not an error
+			md.Tag, _ = gitOutput(workingDirectory, "describe", "--tags", "--abbrev=0")
+		}
+	}
+	vcsMetadataCache[key] = md
+	return md
+}
+
+func gitOutput(workingDirectory string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = workingDirectory
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if stderr.Len() > 0 {
+			return "", &gitError{err, strings.TrimSpace(stderr.String())}
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+type gitError struct {
+	err    error
+	stderr string
+}
+
+func (e *gitError) Error() string {
+	return e.err.Error() + ": " + e.stderr
+}
+
+// resolve LdFlagsXVars into variable name/value pairs.
+// Values containing '{{' are templates, keyed by variable name. Otherwise, the key names a metadata field and the value is the variable name.
+func buildInterpolationVars(args map[string]interface{}, workingDirectory string, settings *config.Settings) (map[string]interface{}, error) {
+	ret := map[string]interface{}{}
+	var md *BuildMetadata
+	for k, v := range args {
+		switch typedV := v.(type) {
+		case string:
+			var varName, templateText string
+			if strings.Contains(typedV, "{{") {
+				varName = k
+				templateText = typedV
+			} else if field, ok := xVarFieldNames[k]; ok {
+				varName = typedV
+				templateText = "{{." + field + "}}"
+			} else {
+				log.Printf("WARNING: unrecognised LdFlagsXVars key '%s'", k)
+				continue
+			}
+			if md == nil {
+				m := GetBuildMetadata(workingDirectory, settings)
+				md = &m
+			}
+			tpl, err := template.New(k).Parse(templateText)
+			if err != nil {
+				return nil, err
+			}
+			var out bytes.Buffer
+			err = tpl.Execute(&out, md)
+			if err != nil {
+				return nil, err
+			}
+			ret[varName] = out.String()
+		default:
+			//error here?
+		}
+	}
+	return ret, nil
+}
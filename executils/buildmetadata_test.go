@@ -0,0 +1,51 @@
+package executils
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/laher/goxc/config"
+)
+
+func TestGetBuildDateSourceDateEpoch(t *testing.T) {
+	defer os.Setenv("SOURCE_DATE_EPOCH", os.Getenv("SOURCE_DATE_EPOCH"))
+	os.Setenv("SOURCE_DATE_EPOCH", "1500000000")
+	actual := GetBuildDate().Format("2006-01-02T15:04:05Z07:00")
+	expected := "2017-07-14T02:40:00Z"
+	if actual != expected {
+		t.Fatalf("unexpected build date %s != %s", actual, expected)
+	}
+}
+
+func TestBuildInterpolationVarsOutsideRepo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("SOURCE_DATE_EPOCH", os.Getenv("SOURCE_DATE_EPOCH"))
+	os.Setenv("SOURCE_DATE_EPOCH", "0")
+	settings := &config.Settings{PackageVersion: "1.2.3"}
+	args := map[string]interface{}{
+		"Version":     "main.VERSION",
+		"TimeNow":     "main.BUILD_DATE",
+		"main.Commit": "{{.Commit}}",
+		"main.Info":   "{{.Version}}-{{.Dirty}}",
+	}
+	actual, err := buildInterpolationVars(args, dir, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"main.VERSION":    "1.2.3",
+		"main.BUILD_DATE": "1970-01-01T00:00:00Z",
+		"main.Commit":     "",
+		"main.Info":       "1.2.3-false",
+	}
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("unexpected value for %s: '%v' != '%s'", k, actual[k], v)
+		}
+	}
+}
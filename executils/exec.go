@@ -30,7 +30,6 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
-	"time"
 
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
@@ -53,25 +52,6 @@ func GetLdFlagVersionArgs(fullVersionName string) string {
 	return GetInterpolationFlags(input, "-X")
 }
 */
-func buildInterpolationVars(args map[string]interface{}, fullVersionName string) map[string]interface{} {
-	ret := map[string]interface{}{}
-	for k, v := range args {
-		switch typedV := v.(type) {
-		case string:
-			switch k {
-			case "Version":
-				ret[typedV] = fullVersionName
-			case "TimeNow":
-				ret[typedV] = time.Now().Format(time.RFC3339)
-			}
-
-		default:
-			//error here?
-		}
-	}
-	return ret
-}
-
 // get list of args to be used in e.g. ldflags variable interpolation
 // v0.9 changed from ldflags-specific to more general flag building
 func buildFlags(args map[string]interface{}, flag string) string {
@@ -122,7 +102,6 @@ func splitEnvVar(asString string) (string, string, error) {
 // 0.3.1
 // v0.9 changed signature
 func InvokeGo(workingDirectory string, subCmd string, subCmdArgs []string, env []string, settings *config.Settings) error {
-	fullVersionName := settings.GetFullVersionName()
 	//var buildSettings config.BuildSettings
 	buildSettings := settings.BuildSettings
 	goRoot := settings.GoRoot
@@ -166,8 +145,11 @@ func InvokeGo(workingDirectory string, subCmd string, subCmdArgs []string, env [
 			ldflags = *buildSettings.LdFlags
 		}
 		if buildSettings.LdFlagsXVars != nil {
-			//TODO!
-			ldflags = ldflags + " " + buildFlags(buildInterpolationVars(*buildSettings.LdFlagsXVars, fullVersionName), "-X")
+			xVars, err := buildInterpolationVars(*buildSettings.LdFlagsXVars, workingDirectory, settings)
+			if err != nil {
+				return err
+			}
+			ldflags = ldflags + " " + buildFlags(xVars, "-X")
 		} else {
 			log.Printf("WARNING: LdFlagsXVars is nil. Not passing package version into compiler")
 		}
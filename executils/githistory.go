@@ -0,0 +1,79 @@
+package executils
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"strings"
+)
+
+// A commit, as listed by GitCommitsSince
+type Commit struct {
+	Hash      string
+	ShortHash string
+	Author    string
+	Date      string //ISO 8601
+	Subject   string
+	Body      string
+}
+
+// The most recent tag starting with 'prefix' which is reachable from HEAD, other than 'currentTag' (so that re-runs after tagging still look back to the previous release).
+// Returns "" if there is none.
+func GitPreviousTag(workingDirectory, prefix, currentTag string) (string, error) {
+	args := []string{"describe", "--tags", "--abbrev=0", "--match", prefix + "*"}
+	if currentTag != "" {
+		args = append(args, "--exclude", currentTag)
+	}
+	tag, err := gitOutput(workingDirectory, args...)
+	if err != nil {
+		if _, ok := err.(*gitError); ok {
+			//no matching tags is not an error (unless this isn't a repository at all)
+			if _, err := gitOutput(workingDirectory, "rev-parse", "HEAD"); err == nil {
+				return "", nil
+			}
+		}
+		return "", err
+	}
+	return tag, nil
+}
+
+// Commits after 'since' (a tag or commit; or "" for the whole history) up to HEAD, newest first. Merge commits are omitted
+func GitCommitsSince(workingDirectory, since string) ([]Commit, error) {
+	revisions := "HEAD"
+	if since != "" {
+		revisions = since + "..HEAD"
+	}
+	//unit & record separators
+	out, err := gitOutput(workingDirectory, "log", "--no-merges", "--format=%H%x1f%h%x1f%an%x1f%aI%x1f%s%x1f%b%x1e", revisions)
+	if err != nil {
+		return nil, err
+	}
+	commits := []Commit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) < 6 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:      fields[0],
+			ShortHash: fields[1],
+			Author:    fields[2],
+			Date:      fields[3],
+			Subject:   fields[4],
+			Body:      strings.TrimSpace(fields[5])})
+	}
+	return commits, nil
+}
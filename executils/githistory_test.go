@@ -0,0 +1,54 @@
+package executils
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Tester", "-c", "user.email=tester@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v %s", args, err, out)
+	}
+}
+
+func TestGitCommitsSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is required for this test")
+	}
+	dir, err := ioutil.TempDir("", "goxc-githistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	git(t, dir, "init", "-q")
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "initial")
+	tag, err := GitPreviousTag(dir, "v", "v0.1")
+	if err != nil || tag != "" {
+		t.Fatalf("expected no previous tag, got '%s' (%v)", tag, err)
+	}
+	git(t, dir, "tag", "v0.1")
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "feat: one", "-m", "details")
+	git(t, dir, "tag", "other-1")
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "fix: two")
+	git(t, dir, "tag", "v0.2")
+	//the current version's tag is skipped
+	tag, err = GitPreviousTag(dir, "v", "v0.2")
+	if err != nil || tag != "v0.1" {
+		t.Fatalf("unexpected previous tag '%s' (%v)", tag, err)
+	}
+	commits, err := GitCommitsSince(dir, tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].Subject != "fix: two" || commits[1].Body != "details" || commits[1].Author != "Tester" || len(commits[1].ShortHash) < 7 {
+		t.Errorf("unexpected commits %+v", commits)
+	}
+	commits, err = GitCommitsSince(dir, "")
+	if err != nil || len(commits) != 3 {
+		t.Errorf("unexpected commits %+v (%v)", commits, err)
+	}
+}
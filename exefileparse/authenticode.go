@@ -0,0 +1,215 @@
+package exefileparse
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"debug/pe"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"reflect"
+)
+
+const (
+	peImageDirectoryEntrySecurity = 4
+	peWinCertTypePKCSSignedData   = 2
+)
+
+var (
+	oidMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSpcIndirectData = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 4}
+)
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	//the [0] tagged content; its Bytes are the content itself
+	Content asn1.RawValue `asn1:"tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue     `asn1:"optional,tag:0"`
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7SignerInfo struct {
+	Version         int
+	IssuerAndSerial struct {
+		Issuer asn1.RawValue
+		Serial *big.Int
+	}
+	DigestAlgorithm    asn1.RawValue
+	SignedAttrs        asn1.RawValue `asn1:"tag:0"`
+	SignatureAlgorithm asn1.RawValue
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type pkcs7Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+type spcIndirectDataContent struct {
+	Data          asn1.RawValue
+	MessageDigest struct {
+		Algorithm asn1.RawValue
+		Digest    []byte
+	}
+}
+
+// Checks that a PE file has an Authenticode signature whose digest matches the file, signed by the certificate it contains.
+// The certificate chain is not checked against any trusted roots. Only sha256 digests are supported.
+func VerifyAuthenticode(filename string, isVerbose bool) error {
+	file, err := pe.Open(filename)
+	if err != nil {
+		return errors.New("NOT a PE file")
+	}
+	var securityDir pe.DataDirectory
+	optionalHeader := file.OptionalHeader
+	switch oh := optionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		securityDir = oh.DataDirectory[peImageDirectoryEntrySecurity]
+	case *pe.OptionalHeader64:
+		securityDir = oh.DataDirectory[peImageDirectoryEntrySecurity]
+	}
+	file.Close()
+	if securityDir.Size == 0 {
+		return errors.New("No Authenticode signature found")
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	//for the security directory, VirtualAddress is a file offset
+	start, end := uint64(securityDir.VirtualAddress), uint64(securityDir.VirtualAddress)+uint64(securityDir.Size)
+	if end > uint64(len(data)) || securityDir.Size < 8 {
+		return errors.New("Invalid certificate table")
+	}
+	certTable := data[start:end]
+	if binary.LittleEndian.Uint16(certTable[6:]) != peWinCertTypePKCSSignedData {
+		return errors.New("Certificate table does not contain PKCS#7 signed data")
+	}
+	var contentInfo pkcs7ContentInfo
+	_, err = asn1.Unmarshal(certTable[8:], &contentInfo)
+	if err != nil {
+		return err
+	}
+	var signedData pkcs7SignedData
+	_, err = asn1.Unmarshal(contentInfo.Content.Bytes, &signedData)
+	if err != nil {
+		return err
+	}
+	if !signedData.ContentInfo.ContentType.Equal(oidSpcIndirectData) || len(signedData.SignerInfos) != 1 {
+		return errors.New("Not an Authenticode signature")
+	}
+	var indirectDataRaw asn1.RawValue
+	_, err = asn1.Unmarshal(signedData.ContentInfo.Content.Bytes, &indirectDataRaw)
+	if err != nil {
+		return err
+	}
+	var indirectData spcIndirectDataContent
+	_, err = asn1.Unmarshal(indirectDataRaw.FullBytes, &indirectData)
+	if err != nil {
+		return err
+	}
+	digest, err := authenticodeDigest(data, optionalHeader, start)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(digest, indirectData.MessageDigest.Digest) {
+		return errors.New("Authenticode digest does not match the file")
+	}
+	//the messageDigest covers the content of the SpcIndirectDataContent SEQUENCE, without its tag and length
+	err = verifySignerInfo(signedData, sha256.Sum256(indirectDataRaw.Bytes))
+	if err != nil {
+		return err
+	}
+	if isVerbose {
+		log.Printf("File '%s' has a valid Authenticode signature", filename)
+	}
+	return nil
+}
+
+// checks the messageDigest attribute and the signature over the signed attributes
+func verifySignerInfo(signedData pkcs7SignedData, contentDigest [sha256.Size]byte) error {
+	signerInfo := signedData.SignerInfos[0]
+	rest := signerInfo.SignedAttrs.Bytes
+	var messageDigest []byte
+	for len(rest) > 0 {
+		var attr pkcs7Attribute
+		var err error
+		rest, err = asn1.Unmarshal(rest, &attr)
+		if err != nil {
+			return err
+		}
+		if attr.Type.Equal(oidMessageDigest) {
+			_, err = asn1.Unmarshal(attr.Values.Bytes, &messageDigest)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if !bytes.Equal(messageDigest, contentDigest[:]) {
+		return errors.New("Signed messageDigest does not match the signed content")
+	}
+	certs, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	if err != nil {
+		return err
+	}
+	for _, cert := range certs {
+		if bytes.Equal(cert.RawIssuer, signerInfo.IssuerAndSerial.Issuer.FullBytes) && cert.SerialNumber.Cmp(signerInfo.IssuerAndSerial.Serial) == 0 {
+			//the signature covers the attributes re-tagged as a SET
+			signedAttrs := append([]byte{0x31}, signerInfo.SignedAttrs.FullBytes[1:]...)
+			algorithm := x509.SHA256WithRSA
+			if cert.PublicKeyAlgorithm == x509.ECDSA {
+				algorithm = x509.ECDSAWithSHA256
+			}
+			return cert.CheckSignature(algorithm, signedAttrs, signerInfo.Signature)
+		}
+	}
+	return errors.New("Signing certificate not found")
+}
+
+// the digest excludes the checksum, the security directory entry and the certificate table.
+// The offsets come from debug/pe's header layouts (rather than the signer's), so that this checks the signer
+func authenticodeDigest(data []byte, optionalHeader interface{}, certTableOffset uint64) ([]byte, error) {
+	if len(data) < 0x40 {
+		return nil, errors.New("NOT a PE file")
+	}
+	//the optional header follows the 'PE\0\0' signature and the file header
+	optionalHeaderOffset := int(binary.LittleEndian.Uint32(data[0x3c:])) + 4 + binary.Size(pe.FileHeader{})
+	checksumField, dataDirectoryField := binaryFieldOffset(optionalHeader, "CheckSum"), binaryFieldOffset(optionalHeader, "DataDirectory")
+	if checksumField < 0 || dataDirectoryField < 0 {
+		return nil, errors.New("Unknown optional header")
+	}
+	checksum := optionalHeaderOffset + checksumField
+	securityDir := optionalHeaderOffset + dataDirectoryField + peImageDirectoryEntrySecurity*binary.Size(pe.DataDirectory{})
+	if uint64(securityDir+8) > certTableOffset || certTableOffset > uint64(len(data)) {
+		return nil, errors.New("Invalid certificate table offset")
+	}
+	h := sha256.New()
+	h.Write(data[:checksum])
+	h.Write(data[checksum+4 : securityDir])
+	h.Write(data[securityDir+8 : certTableOffset])
+	return h.Sum(nil), nil
+}
+
+// The offset of a field in the encoded struct (which has no padding), or -1
+func binaryFieldOffset(v interface{}, name string) int {
+	t := reflect.Indirect(reflect.ValueOf(v))
+	offset := 0
+	for i := 0; i < t.NumField(); i++ {
+		if t.Type().Field(i).Name == name {
+			return offset
+		}
+		offset += binary.Size(t.Field(i).Interface())
+	}
+	return -1
+}
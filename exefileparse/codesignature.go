@@ -0,0 +1,110 @@
+package exefileparse
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+const (
+	machoLoadCmdCodeSignature = 0x1d
+	csMagicEmbeddedSignature  = 0xfade0cc0
+	csMagicCodeDirectory      = 0xfade0c02
+	csSlotCodeDirectory       = 0
+	csHashTypeSHA256          = 2
+)
+
+// Checks that a Mach-O file has an embedded code signature, and that its CodeDirectory's page hashes match the file's contents.
+// The CMS signature (if any) is not checked against a certificate chain.
+func VerifyMachOSignature(filename string, isVerbose bool) error {
+	file, err := macho.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var dataOff, dataSize uint32
+	for _, load := range file.Loads {
+		raw := load.Raw()
+		if len(raw) >= 16 && file.ByteOrder.Uint32(raw) == machoLoadCmdCodeSignature {
+			dataOff = file.ByteOrder.Uint32(raw[8:])
+			dataSize = file.ByteOrder.Uint32(raw[12:])
+		}
+	}
+	if dataSize == 0 {
+		return errors.New("No code signature found")
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if uint64(dataOff)+uint64(dataSize) > uint64(len(data)) {
+		return errors.New("Code signature extends beyond the end of the file")
+	}
+	sig := data[dataOff : dataOff+dataSize]
+	be := binary.BigEndian
+	if len(sig) < 12 || be.Uint32(sig) != csMagicEmbeddedSignature {
+		return errors.New("Code signature is not an embedded signature SuperBlob")
+	}
+	count := be.Uint32(sig[8:])
+	for i := uint32(0); i < count; i++ {
+		index := 12 + int(i)*8
+		if index+8 > len(sig) {
+			return errors.New("Truncated code signature index")
+		}
+		if be.Uint32(sig[index:]) == csSlotCodeDirectory {
+			offset := be.Uint32(sig[index+4:])
+			if uint64(offset) >= uint64(len(sig)) {
+				return errors.New("Invalid CodeDirectory offset")
+			}
+			err = verifyCodeDirectory(sig[offset:], data)
+			if err != nil {
+				return err
+			}
+			if isVerbose {
+				log.Printf("File '%s' has a valid code signature", filename)
+			}
+			return nil
+		}
+	}
+	return errors.New("Code signature has no CodeDirectory")
+}
+
+func verifyCodeDirectory(cd, data []byte) error {
+	be := binary.BigEndian
+	if len(cd) < 40 || be.Uint32(cd) != csMagicCodeDirectory {
+		return errors.New("Invalid CodeDirectory")
+	}
+	hashOffset := be.Uint32(cd[16:])
+	nCodeSlots := be.Uint32(cd[28:])
+	codeLimit := be.Uint32(cd[32:])
+	hashSize := cd[36]
+	hashType := cd[37]
+	pageSize := 1 << cd[39]
+	if hashType != csHashTypeSHA256 || hashSize != sha256.Size {
+		return fmt.Errorf("Unsupported CodeDirectory hash type %d", hashType)
+	}
+	if uint64(codeLimit) > uint64(len(data)) || uint64(hashOffset)+uint64(nCodeSlots)*sha256.Size > uint64(len(cd)) {
+		return errors.New("Invalid CodeDirectory limits")
+	}
+	for slot := 0; slot < int(nCodeSlots); slot++ {
+		start := slot * pageSize
+		end := start + pageSize
+		if end > int(codeLimit) {
+			end = int(codeLimit)
+		}
+		if start > end {
+			return errors.New("Invalid CodeDirectory slot count")
+		}
+		hash := sha256.Sum256(data[start:end])
+		expected := cd[int(hashOffset)+slot*sha256.Size : int(hashOffset)+(slot+1)*sha256.Size]
+		if !bytes.Equal(hash[:], expected) {
+			return fmt.Errorf("Code signature hash mismatch for page %d", slot)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package exefileparse
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/laher/goxc/platforms"
+)
+
+// name used for the whole file, when the executable format has no parseable sections (e.g. plan9)
+const WHOLE_FILE = "(file)"
+
+// Returns a sha256 digest of each section's contents, keyed by section name.
+// The format is chosen by the target OS, as for Test.
+func SectionDigests(filename, goos string) (map[string][]byte, error) {
+	switch goos {
+	case platforms.WINDOWS:
+		return peSectionDigests(filename)
+	case platforms.DARWIN:
+		return machoSectionDigests(filename)
+	case platforms.PLAN9:
+		return wholeFileDigest(filename)
+	default:
+		return elfSectionDigests(filename)
+	}
+}
+
+// Compares two executables for the same platform, returning the names of any sections which differ (or only exist in one of them).
+// Headers are not compared section-by-section; if the files differ but all sections match, "(headers)" is reported.
+func DiffSections(filenameA, filenameB, goos string) ([]string, error) {
+	digestsA, err := SectionDigests(filenameA, goos)
+	if err != nil {
+		return nil, err
+	}
+	digestsB, err := SectionDigests(filenameB, goos)
+	if err != nil {
+		return nil, err
+	}
+	diffs := []string{}
+	for name, digestA := range digestsA {
+		if digestB, exists := digestsB[name]; !exists || !bytes.Equal(digestA, digestB) {
+			diffs = append(diffs, name)
+		}
+	}
+	for name := range digestsB {
+		if _, exists := digestsA[name]; !exists {
+			diffs = append(diffs, name)
+		}
+	}
+	sort.Strings(diffs)
+	if len(diffs) == 0 {
+		same, err := FilesEqual(filenameA, filenameB)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			diffs = append(diffs, "(headers)")
+		}
+	}
+	return diffs, nil
+}
+
+// Byte-for-byte comparison of two files
+func FilesEqual(filenameA, filenameB string) (bool, error) {
+	a, err := ioutil.ReadFile(filenameA)
+	if err != nil {
+		return false, err
+	}
+	b, err := ioutil.ReadFile(filenameB)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(a, b), nil
+}
+
+func elfSectionDigests(filename string) (map[string][]byte, error) {
+	file, err := elf.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	ret := map[string][]byte{}
+	for _, section := range file.Sections {
+		if section.Type == elf.SHT_NOBITS || section.Type == elf.SHT_NULL {
+			continue
+		}
+		digest, err := digest(section.Open())
+		if err != nil {
+			return nil, err
+		}
+		ret[section.Name] = digest
+	}
+	return ret, nil
+}
+
+func machoSectionDigests(filename string) (map[string][]byte, error) {
+	file, err := macho.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	ret := map[string][]byte{}
+	for _, section := range file.Sections {
+		//zerofill sections have no file contents
+		if section.Offset == 0 {
+			continue
+		}
+		digest, err := digest(section.Open())
+		if err != nil {
+			return nil, err
+		}
+		ret[section.Seg+","+section.Name] = digest
+	}
+	return ret, nil
+}
+
+func peSectionDigests(filename string) (map[string][]byte, error) {
+	file, err := pe.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	ret := map[string][]byte{}
+	for _, section := range file.Sections {
+		digest, err := digest(section.Open())
+		if err != nil {
+			return nil, err
+		}
+		ret[section.Name] = digest
+	}
+	return ret, nil
+}
+
+func wholeFileDigest(filename string) (map[string][]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return map[string][]byte{WHOLE_FILE: sum[:]}, nil
+}
+
+func digest(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	_, err := io.Copy(h, r)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
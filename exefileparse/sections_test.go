@@ -0,0 +1,62 @@
+package exefileparse
+
+import (
+	"debug/elf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/laher/goxc/platforms"
+)
+
+func TestDiffSections(t *testing.T) {
+	if runtime.GOOS != platforms.LINUX {
+		t.Skip("test uses the (ELF) test binary itself")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := DiffSections(exe, exe, runtime.GOOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("unexpected diffs comparing a file with itself: %v", diffs)
+	}
+
+	//flip a byte inside .rodata
+	data, err := ioutil.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := elf.Open(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	section := f.Section(".rodata")
+	f.Close()
+	if section == nil {
+		t.Skip("no .rodata section")
+	}
+	data[section.Offset] ^= 0xff
+	dir, err := ioutil.TempDir("", "goxc-exefileparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	modified := filepath.Join(dir, "modified")
+	err = ioutil.WriteFile(modified, data, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err = DiffSections(exe, modified, runtime.GOOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0] != ".rodata" {
+		t.Fatalf("unexpected diffs: %v", diffs)
+	}
+}
@@ -33,7 +33,11 @@ import (
 	"github.com/laher/goxc/core"
 	"github.com/laher/goxc/platforms"
 	"github.com/laher/goxc/tasks"
+	_ "github.com/laher/goxc/tasks/gitea"
 	_ "github.com/laher/goxc/tasks/github"
+	_ "github.com/laher/goxc/tasks/gitlab"
+	_ "github.com/laher/goxc/tasks/registry"
+	_ "github.com/laher/goxc/tasks/sftp"
 )
 
 const (
@@ -173,6 +177,10 @@ func goXC(call []string) error {
 			log.Printf("Final settings %+v", settings)
 		}
 		destPlatforms := platforms.GetDestPlatforms(settings.Os, settings.Arch)
+		if err := platforms.ValidateBuildConstraints(settings.BuildConstraints); err != nil {
+			log.Printf("Error: %v", err)
+			return err
+		}
 		destPlatforms = platforms.ApplyBuildConstraints(settings.BuildConstraints, destPlatforms)
 		err := tasks.RunTasks(workingDirectory, destPlatforms, &settings, maxProcessors)
 		if err != nil {
@@ -465,7 +473,7 @@ func setupFlags() *flag.FlagSet {
 	flagSet.StringVar(&settings.Arch, "arch", "", "Specify Arch (default is all - \"386 amd64 arm\")")
 
 	//v0.6
-	flagSet.StringVar(&buildConstraints, "bc", "", "Specify build constraints (e.g. 'linux,arm windows')")
+	flagSet.StringVar(&buildConstraints, "bc", "", "Specify build constraints (e.g. 'linux,arm windows' or 'linux && (amd64 || arm64)')")
 
 	flagSet.StringVar(&workingDirectoryFlag, "wd", "", "Specify directory to work on")
 
@@ -482,7 +490,7 @@ func setupFlags() *flag.FlagSet {
 
 	flagSet.StringVar(&settings.OutPath, "o", "", "Output file name for compilation (this string is a template, with default -o=\""+core.OUTFILE_TEMPLATE_DEFAULT+"\")")
 
-	flagSet.StringVar(&codesignId, "codesign", "", "identity to sign darwin binaries with (only applied when host OS is 'darwin')")
+	flagSet.StringVar(&codesignId, "codesign", "", "identity to sign darwin binaries with (only applied when host OS is 'darwin'). Use '-' for an ad-hoc signature, on any host")
 
 	flagSet.StringVar(&settings.ResourcesInclude, "resources-include", "", "Include resources in archives (default="+core.RESOURCES_INCLUDE_DEFAULT+")")
 	//deprecated
@@ -490,6 +498,7 @@ func setupFlags() *flag.FlagSet {
 
 	flagSet.StringVar(&settings.ResourcesExclude, "resources-exclude", "", "Include resources in archives (default="+core.RESOURCES_EXCLUDE_DEFAULT+")")
 	flagSet.StringVar(&settings.MainDirsExclude, "main-dirs-exclude", "", "Exclude given comma-separated directories from 'main' packages (default="+core.MAIN_DIRS_EXCLUDE_DEFAULT+")")
+	flagSet.StringVar(&settings.NestedModulesInclude, "nested-modules-include", "", "Include 'main' packages from nested Go modules matching given comma-separated globs (default excludes nested modules)")
 
 	//0.2.0 Not easy to 'merge' boolean config items. More flexible to translate them to string options anyway
 	flagSet.BoolVar(&isHelp, "h", false, "Help - options")
@@ -0,0 +1,149 @@
+package authenticode
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/laher/goxc/exefileparse"
+	"github.com/laher/goxc/packaging/cms"
+	"github.com/laher/goxc/platforms"
+)
+
+func newSigner(t *testing.T, name string) *cms.Signer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(42), Subject: pkix.Name{CommonName: name}, NotBefore: time.Unix(0, 0), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &cms.Signer{Certificate: cert, Key: key, SigningTime: time.Unix(0, 0)}
+}
+
+// a stand-in Time Stamping Authority, which grants a token for any request
+func newTSA(t *testing.T) *httptest.Server {
+	tsaSigner := newSigner(t, "Test TSA")
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := ioutil.ReadAll(r.Body)
+		if err != nil || r.Header.Get("Content-Type") != "application/timestamp-query" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		//the TSTInfo isn't inspected by these tests, so the request stands in for it
+		token, err := tsaSigner.Sign(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}, req, false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status, _ := asn1.Marshal(struct{ Status int }{0})
+		resp, _ := asn1.Marshal(struct {
+			Status asn1.RawValue
+			Token  asn1.RawValue
+		}{asn1.RawValue{FullBytes: status}, asn1.RawValue{FullBytes: token}})
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(resp)
+	}))
+}
+
+func TestSign(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-authenticode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "main.go")
+	err = ioutil.WriteFile(src, []byte("package main\n\nfunc main() {}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(dir, "hello.exe")
+	cmd := exec.Command("go", "build", "-o", exe, src)
+	cmd.Env = append(os.Environ(), "GOOS=windows", "GOARCH=amd64", "CGO_ENABLED=0", "GO111MODULE=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Skipf("could not build a windows binary: %v\n%s", err, out)
+	}
+	data, err := ioutil.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tsa := newTSA(t)
+	defer tsa.Close()
+	signer := newSigner(t, "Test Signer")
+	signer.Timestamper = cms.RFC3161Timestamper(tsa.URL, cms.OIDRFC3161Countersignature)
+	signed, err := Sign(data, signer, OpusInfo{Description: "Hello", URL: "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(signed, []byte("Test TSA")) {
+		t.Errorf("signature does not contain the timestamp")
+	}
+	err = ioutil.WriteFile(exe, signed, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exefileparse.TestPE(exe, platforms.AMD64, platforms.WINDOWS, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exefileparse.VerifyAuthenticode(exe, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//re-signing replaces the signature
+	signer.Timestamper = nil
+	resigned, err := Sign(signed, signer, OpusInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(resigned, []byte("Test TSA")) {
+		t.Errorf("the old signature was not replaced")
+	}
+	//a certificate table beyond the end of the file is an error, rather than a panic
+	p, err := parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := append([]byte{}, signed...)
+	binary.LittleEndian.PutUint32(corrupt[p.securityDir:], uint32(len(corrupt)+64))
+	if _, err = Sign(corrupt, signer, OpusInfo{}); err == nil {
+		t.Errorf("expected an error for an invalid certificate table offset")
+	}
+	//tampering breaks the signature
+	resigned[len(data)/2] ^= 0xff
+	err = ioutil.WriteFile(exe, resigned, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exefileparse.VerifyAuthenticode(exe, false) == nil {
+		t.Errorf("expected a tampered file to fail verification")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	//the checksum field itself is skipped, and the length is added
+	data := []byte{1, 0, 0xff, 0xff, 0xff, 0xff, 2, 0}
+	if sum := checksum(data, 2); sum != 3+8 {
+		t.Errorf("unexpected checksum %d", sum)
+	}
+}
@@ -0,0 +1,238 @@
+// Authenticode signatures for Windows (PE) executables, written without Microsoft's 'signtool'
+package authenticode
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+
+	"github.com/laher/goxc/packaging/cms"
+)
+
+// PE layout
+const (
+	peOptionalHeaderOffset = 24
+	peMagic32              = 0x10b
+	peMagic64              = 0x20b
+	//offsets within the optional header
+	peChecksumOffset      = 64
+	peSecurityDirOffset32 = 128
+	peSecurityDirOffset64 = 144
+)
+
+// WIN_CERTIFICATE header values
+const (
+	WIN_CERT_REVISION_2_0          = 0x0200
+	WIN_CERT_TYPE_PKCS_SIGNED_DATA = 0x0002
+)
+
+var (
+	oidSpcIndirectData       = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 4}
+	oidSpcPeImageData        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 15}
+	oidSpcSpOpusInfo         = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 12}
+	oidSpcStatementType      = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 11}
+	oidSpcIndividualCodeSign = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 21}
+	oidSHA256                = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+// Shown by Windows when prompting about the program. Both are optional
+type OpusInfo struct {
+	Description string
+	URL         string
+}
+
+// offsets of the fields excluded from the Authenticode digest
+type peFile struct {
+	checksum    int
+	securityDir int
+}
+
+func parse(data []byte) (*peFile, error) {
+	if len(data) < 0x40 || data[0] != 'M' || data[1] != 'Z' {
+		return nil, errors.New("Not a PE file")
+	}
+	peOffset := int(binary.LittleEndian.Uint32(data[0x3c:]))
+	optionalHeader := peOffset + peOptionalHeaderOffset
+	if optionalHeader+peSecurityDirOffset64+8 > len(data) || string(data[peOffset:peOffset+4]) != "PE\x00\x00" {
+		return nil, errors.New("Not a PE file")
+	}
+	p := &peFile{checksum: optionalHeader + peChecksumOffset}
+	switch binary.LittleEndian.Uint16(data[optionalHeader:]) {
+	case peMagic32:
+		p.securityDir = optionalHeader + peSecurityDirOffset32
+	case peMagic64:
+		p.securityDir = optionalHeader + peSecurityDirOffset64
+	default:
+		return nil, errors.New("Unknown PE optional header format")
+	}
+	return p, nil
+}
+
+// The sha256 Authenticode digest: the whole file except the checksum, the security directory entry and the certificate table
+func Digest(data []byte) ([]byte, error) {
+	p, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	end := len(data)
+	if certTable := binary.LittleEndian.Uint32(data[p.securityDir:]); certTable != 0 {
+		end = int(certTable)
+	}
+	if end > len(data) || end < p.securityDir+8 {
+		return nil, errors.New("Invalid certificate table offset")
+	}
+	h := sha256.New()
+	h.Write(data[:p.checksum])
+	h.Write(data[p.checksum+4 : p.securityDir])
+	h.Write(data[p.securityDir+8 : end])
+	return h.Sum(nil), nil
+}
+
+// Signs a PE executable, returning the signed copy. Any existing signature is replaced.
+func Sign(data []byte, signer *cms.Signer, opus OpusInfo) ([]byte, error) {
+	p, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	le := binary.LittleEndian
+	//strip any existing certificate table, which must be at the end of the file
+	if certTable := le.Uint32(data[p.securityDir:]); certTable != 0 {
+		if int(certTable) > len(data) || int(certTable) < p.securityDir+8 {
+			return nil, errors.New("Invalid certificate table offset")
+		}
+		if int(certTable)+int(le.Uint32(data[p.securityDir+4:])) < len(data) {
+			return nil, errors.New("Existing certificate table is not at the end of the file")
+		}
+		data = data[:certTable]
+	}
+	//the certificate table is 8-byte aligned
+	out := make([]byte, (len(data)+7)&^7)
+	copy(out, data)
+	le.PutUint32(out[p.checksum:], 0)
+	le.PutUint64(out[p.securityDir:], 0)
+	digest, err := Digest(out)
+	if err != nil {
+		return nil, err
+	}
+	indirectData := spcIndirectDataContent(digest)
+	//the messageDigest covers the content of the SpcIndirectDataContent SEQUENCE, without its tag and length
+	var raw asn1.RawValue
+	_, err = asn1.Unmarshal(indirectData, &raw)
+	if err != nil {
+		return nil, err
+	}
+	contentDigest := sha256.Sum256(raw.Bytes)
+	signedData, err := signer.SignDigest(oidSpcIndirectData, indirectData, contentDigest[:],
+		cms.Attribute{Type: oidSpcSpOpusInfo, Values: [][]byte{opus.encode()}},
+		cms.Attribute{Type: oidSpcStatementType, Values: [][]byte{mustMarshal([]asn1.ObjectIdentifier{oidSpcIndividualCodeSign})}})
+	if err != nil {
+		return nil, err
+	}
+	//WIN_CERTIFICATE: length, revision, type, then the PKCS#7 SignedData, padded to 8 bytes
+	certLength := (8 + len(signedData) + 7) &^ 7
+	cert := make([]byte, certLength)
+	le.PutUint32(cert, uint32(certLength))
+	le.PutUint16(cert[4:], WIN_CERT_REVISION_2_0)
+	le.PutUint16(cert[6:], WIN_CERT_TYPE_PKCS_SIGNED_DATA)
+	copy(cert[8:], signedData)
+	le.PutUint32(out[p.securityDir:], uint32(len(out)))
+	le.PutUint32(out[p.securityDir+4:], uint32(certLength))
+	out = append(out, cert...)
+	le.PutUint32(out[p.checksum:], checksum(out, p.checksum))
+	return out, nil
+}
+
+func spcIndirectDataContent(digest []byte) []byte {
+	type algorithmIdentifier struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.RawValue
+	}
+	type digestInfo struct {
+		Algorithm algorithmIdentifier
+		Digest    []byte
+	}
+	type spcAttributeTypeAndOptionalValue struct {
+		Type  asn1.ObjectIdentifier
+		Value asn1.RawValue
+	}
+	type spcIndirectDataContent struct {
+		Data          spcAttributeTypeAndOptionalValue
+		MessageDigest digestInfo
+	}
+	//SpcPeImageData: no flags, and the legacy '<<<Obsolete>>>' file link
+	obsolete := mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: bmpString("<<<Obsolete>>>")})
+	fileLink := mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: obsolete})
+	file := mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: fileLink})
+	flags := mustMarshal(asn1.BitString{})
+	peImageData := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: append(flags, file...)}
+	return mustMarshal(spcIndirectDataContent{
+		spcAttributeTypeAndOptionalValue{oidSpcPeImageData, peImageData},
+		digestInfo{algorithmIdentifier{oidSHA256, asn1.NullRawValue}, digest}})
+}
+
+// SpcSpOpusInfo: [0] programName SpcString, [1] moreInfo SpcLink
+func (o OpusInfo) encode() []byte {
+	content := []byte{}
+	if o.Description != "" {
+		name := mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: bmpString(o.Description)})
+		content = append(content, mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: name})...)
+	}
+	if o.URL != "" {
+		url := mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: []byte(o.URL)})
+		content = append(content, mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: url})...)
+	}
+	return mustMarshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: content})
+}
+
+// UTF-16BE
+func bmpString(s string) []byte {
+	ret := []byte{}
+	for _, c := range utf16.Encode([]rune(s)) {
+		ret = append(ret, byte(c>>8), byte(c))
+	}
+	return ret
+}
+
+// The PE checksum, as calculated by imagehlp's CheckSumMappedFile
+func checksum(data []byte, checksumOffset int) uint32 {
+	var sum uint64
+	for i := 0; i+1 < len(data); i += 2 {
+		if i == checksumOffset || i == checksumOffset+2 {
+			continue
+		}
+		sum += uint64(binary.LittleEndian.Uint16(data[i:]))
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	if len(data)%2 == 1 {
+		sum += uint64(data[len(data)-1])
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return uint32(sum) + uint32(len(data))
+}
+
+func mustMarshal(v interface{}) []byte {
+	data, err := asn1.Marshal(v)
+	if err != nil {
+		//only used for types which encoding/asn1 supports
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,262 @@
+// CMS (PKCS#7) SignedData, for embedding in code signatures
+package cms
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+var (
+	OIDData                   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256                 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256        = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// A signed attribute. Values are encoded individually, and gathered into a SET.
+type Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values [][]byte
+}
+
+// A certificate and its private key, along with any intermediate certificates
+type Signer struct {
+	Certificate *x509.Certificate
+	Chain       []*x509.Certificate
+	Key         crypto.Signer
+	//included as a signed attribute, unless zero
+	SigningTime time.Time
+	//if set, called with the signature value to get unsigned attributes, e.g. an RFC 3161 timestamp
+	Timestamper func(signature []byte) ([]Attribute, error)
+}
+
+// Loads the signer from a PKCS#12 ('.p12' / '.pfx') file
+func LoadPKCS12(filename, password string) (*Signer, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return nil, err
+	}
+	signer := &Signer{}
+	certs := []*x509.Certificate{}
+	for _, block := range blocks {
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, cert)
+		case "PRIVATE KEY":
+			signer.Key, err = parsePrivateKey(block)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if signer.Key == nil {
+		return nil, errors.New("No private key found in " + filename)
+	}
+	for _, cert := range certs {
+		if signer.Certificate == nil && publicKeysEqual(cert.PublicKey, signer.Key.Public()) {
+			signer.Certificate = cert
+		} else {
+			signer.Chain = append(signer.Chain, cert)
+		}
+	}
+	if signer.Certificate == nil {
+		return nil, errors.New("No certificate matching the private key found in " + filename)
+	}
+	return signer, nil
+}
+
+// pkcs12.ToPEM converts keys to PKCS#1 (RSA) or SEC 1 (EC)
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("Unsupported private key type")
+	}
+	return signer, nil
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	ak, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && ak.Equal(b)
+}
+
+// Signs content, returning a DER-encoded ContentInfo containing the SignedData.
+// The content is only embedded (as an OCTET STRING) when detached is false. The signed attributes are contentType, messageDigest, signingTime (when set) and attrs.
+func (s *Signer) Sign(contentType asn1.ObjectIdentifier, content []byte, detached bool, attrs ...Attribute) ([]byte, error) {
+	digest := sha256.Sum256(content)
+	var eContent []byte
+	if !detached {
+		eContent = mustMarshal(content)
+	}
+	return s.SignDigest(contentType, eContent, digest[:], attrs...)
+}
+
+// As Sign, with a precomputed sha256 digest and an already-encoded eContent (nil for detached signatures).
+// PKCS#7 formats such as Authenticode embed their content directly, rather than in an OCTET STRING, and digest only part of it.
+func (s *Signer) SignDigest(contentType asn1.ObjectIdentifier, eContent, digest []byte, attrs ...Attribute) ([]byte, error) {
+	signedAttrs := []Attribute{
+		{oidAttributeContentType, [][]byte{mustMarshal(contentType)}},
+		{oidAttributeMessageDigest, [][]byte{mustMarshal(digest)}}}
+	if !s.SigningTime.IsZero() {
+		signedAttrs = append(signedAttrs, Attribute{oidAttributeSigningTime, [][]byte{mustMarshal(s.SigningTime.UTC())}})
+	}
+	signedAttrs = append(signedAttrs, attrs...)
+	//the signature covers the attributes as a SET. They are then embedded with an IMPLICIT [0] tag.
+	attrsSet := tagged(0x31, encodeAttributes(signedAttrs))
+	attrsDigest := sha256.Sum256(attrsSet)
+	var signatureAlgorithm []byte
+	switch s.Key.Public().(type) {
+	case *rsa.PublicKey:
+		signatureAlgorithm = sequence(mustMarshal(oidRSAEncryption), asn1.NullBytes)
+	case *ecdsa.PublicKey:
+		signatureAlgorithm = sequence(mustMarshal(oidECDSAWithSHA256))
+	default:
+		return nil, errors.New("Unsupported key type for signing")
+	}
+	signature, err := s.Key.Sign(rand.Reader, attrsDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	digestAlgorithm := sequence(mustMarshal(oidSHA256), asn1.NullBytes)
+	signerInfoElements := [][]byte{
+		mustMarshal(1),
+		sequence(s.Certificate.RawIssuer, mustMarshal(s.Certificate.SerialNumber)),
+		digestAlgorithm,
+		tagged(0xa0, encodeAttributes(signedAttrs)),
+		signatureAlgorithm,
+		mustMarshal(signature)}
+	if s.Timestamper != nil {
+		unsignedAttrs, err := s.Timestamper(signature)
+		if err != nil {
+			return nil, err
+		}
+		if len(unsignedAttrs) > 0 {
+			signerInfoElements = append(signerInfoElements, tagged(0xa1, encodeAttributes(unsignedAttrs)))
+		}
+	}
+	signerInfo := sequence(signerInfoElements...)
+
+	encapContentInfo := [][]byte{mustMarshal(contentType)}
+	if eContent != nil {
+		encapContentInfo = append(encapContentInfo, tagged(0xa0, eContent))
+	}
+	certs := [][]byte{s.Certificate.Raw}
+	for _, cert := range s.Chain {
+		certs = append(certs, cert.Raw)
+	}
+	signedData := sequence(
+		mustMarshal(1),
+		set(digestAlgorithm),
+		sequence(encapContentInfo...),
+		tagged(0xa0, bytes.Join(certs, nil)),
+		set(signerInfo))
+	return sequence(mustMarshal(oidSignedData), tagged(0xa0, signedData)), nil
+}
+
+// An upper bound for the size of a signature, for formats which reserve space before signing
+func (s *Signer) MaxSize() int {
+	size := len(s.Certificate.Raw)
+	for _, cert := range s.Chain {
+		size += len(cert.Raw)
+	}
+	//signature, attributes, algorithm identifiers, issuer & serial
+	return size + len(s.Certificate.RawIssuer) + 2048
+}
+
+// the (sorted) contents of a SET OF Attribute
+func encodeAttributes(attrs []Attribute) []byte {
+	encoded := [][]byte{}
+	for _, attr := range attrs {
+		encoded = append(encoded, sequence(mustMarshal(attr.Type), set(attr.Values...)))
+	}
+	return sortedConcat(encoded)
+}
+
+func mustMarshal(v interface{}) []byte {
+	data, err := asn1.Marshal(v)
+	if err != nil {
+		//only used for types which encoding/asn1 supports
+		panic(err)
+	}
+	return data
+}
+
+func header(tag byte, length int) []byte {
+	if length < 0x80 {
+		return []byte{tag, byte(length)}
+	}
+	lengthBytes := []byte{}
+	for n := length; n > 0; n >>= 8 {
+		lengthBytes = append([]byte{byte(n)}, lengthBytes...)
+	}
+	return append([]byte{tag, 0x80 | byte(len(lengthBytes))}, lengthBytes...)
+}
+
+func tagged(tag byte, content []byte) []byte {
+	return append(header(tag, len(content)), content...)
+}
+
+func sequence(elements ...[]byte) []byte {
+	return tagged(0x30, bytes.Join(elements, nil))
+}
+
+// DER sorts the elements of a SET OF by their encodings
+func set(elements ...[]byte) []byte {
+	return tagged(0x31, sortedConcat(elements))
+}
+
+func sortedConcat(elements [][]byte) []byte {
+	sorted := append([][]byte{}, elements...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	return bytes.Join(sorted, nil)
+}
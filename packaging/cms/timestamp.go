@@ -0,0 +1,94 @@
+package cms
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// RFC 3161 timestamp tokens, as unsigned attributes
+var (
+	OIDAttributeTimestampToken = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	//Authenticode's equivalent
+	OIDRFC3161Countersignature = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 3, 3, 1}
+)
+
+type timestampResp struct {
+	Status struct {
+		Status       int
+		StatusString asn1.RawValue `asn1:"optional"`
+		FailInfo     asn1.RawValue `asn1:"optional"`
+	}
+	Token asn1.RawValue `asn1:"optional"`
+}
+
+// A Timestamper which requests a timestamp of the signature from the Time Stamping Authority at url, storing the token in an attribute of type attrType
+func RFC3161Timestamper(url string, attrType asn1.ObjectIdentifier) func(signature []byte) ([]Attribute, error) {
+	return func(signature []byte) ([]Attribute, error) {
+		token, err := RequestTimestamp(url, signature)
+		if err != nil {
+			return nil, err
+		}
+		return []Attribute{{attrType, [][]byte{token}}}, nil
+	}
+}
+
+// Requests an RFC 3161 timestamp token for data (using its sha256 digest), returning the token (a ContentInfo)
+func RequestTimestamp(url string, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 63))
+	if err != nil {
+		return nil, err
+	}
+	req := sequence(
+		mustMarshal(1),
+		sequence(sequence(mustMarshal(oidSHA256), asn1.NullBytes), mustMarshal(digest[:])),
+		mustMarshal(nonce),
+		//certReq: include the TSA's certificate in the token
+		mustMarshal(true))
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Post(url, "application/timestamp-query", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Timestamp request to %s failed: %s", url, resp.Status)
+	}
+	var tsResp timestampResp
+	_, err = asn1.Unmarshal(body, &tsResp)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid timestamp response from %s: %v", url, err)
+	}
+	//0 = granted, 1 = granted with modifications
+	if tsResp.Status.Status > 1 || len(tsResp.Token.FullBytes) == 0 {
+		return nil, fmt.Errorf("Timestamp request to %s was rejected (status %d)", url, tsResp.Status.Status)
+	}
+	return tsResp.Token.FullBytes, nil
+}
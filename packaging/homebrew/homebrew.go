@@ -0,0 +1,201 @@
+// Homebrew formulae and casks, for installing released archives with 'brew'
+package homebrew
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Formula (or cask) metadata
+type Formula struct {
+	//formula name, e.g. 'my-app'. The Ruby class name is derived from it
+	Name        string
+	Description string
+	Homepage    string
+	Version     string
+	License     string
+	//executables to install from the archives
+	Binaries []string
+	Archives []Archive
+}
+
+// A released archive for one platform
+type Archive struct {
+	//goxc os and arch
+	Os     string
+	Arch   string
+	URL    string
+	Sha256 string
+	//directory inside the archive which contains the binaries (casks only. Homebrew strips a single top-level directory for formulae)
+	Dir string
+}
+
+const formulaTemplate = `class {{.ClassName}} < Formula
+{{- if .Description}}
+  desc "{{ruby .Description}}"
+{{- end}}
+{{- if .Homepage}}
+  homepage "{{ruby .Homepage}}"
+{{- end}}
+  version "{{ruby .Version}}"
+{{- if .License}}
+  license "{{ruby .License}}"
+{{- end}}
+{{range .Oses}}
+  on_{{.Name}} do
+{{- range .Archives}}
+    if {{cpu .Arch}}
+      url "{{ruby .URL}}"
+      sha256 "{{.Sha256}}"
+    end
+{{- end}}
+  end
+{{end}}
+  def install
+{{- range .Binaries}}
+    bin.install "{{ruby .}}"
+{{- end}}
+  end
+
+  test do
+{{- range .Binaries}}
+    assert_predicate bin/"{{ruby .}}", :executable?
+{{- end}}
+  end
+end
+`
+
+const caskTemplate = `cask "{{ruby .Name}}" do
+  version "{{ruby .Version}}"
+{{range (index .Oses 0).Archives}}
+  on_{{if eq .Arch "arm64"}}arm{{else}}intel{{end}} do
+    url "{{ruby .URL}}"
+    sha256 "{{.Sha256}}"
+{{- $dir := .Dir}}
+{{- range $.Binaries}}
+    binary "{{if $dir}}{{ruby $dir}}/{{end}}{{ruby .}}"
+{{- end}}
+  end
+{{end}}
+  name "{{ruby .Name}}"
+{{- if .Description}}
+  desc "{{ruby .Description}}"
+{{- end}}
+{{- if .Homepage}}
+  homepage "{{ruby .Homepage}}"
+{{- end}}
+end
+`
+
+type osArchives struct {
+	Name     string
+	Archives []Archive
+}
+
+// Homebrew supports 64-bit Intel and ARM, on Mac and Linux
+var homebrewArches = map[string]bool{"amd64": true, "arm64": true}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// The Ruby class name for a formula name, e.g. 'my-app' becomes 'MyApp'
+func ClassName(name string) string {
+	name = strings.Replace(name, "+", "x", -1)
+	name = strings.Replace(name, "@", "AT", -1)
+	className := ""
+	for _, part := range nonAlphanumeric.Split(name, -1) {
+		if part != "" {
+			className += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return className
+}
+
+// The formula's Ruby source. Archives for unsupported platforms are ignored
+func (f Formula) Ruby() ([]byte, error) {
+	oses := f.oses([]string{"macos", "linux"})
+	if len(oses) == 0 {
+		return nil, errors.New("No archives for Homebrew platforms (darwin or linux, amd64 or arm64)")
+	}
+	return f.execute(formulaTemplate, oses)
+}
+
+// The cask's Ruby source. Casks are Mac-only, so only darwin archives are used
+func (f Formula) Cask() ([]byte, error) {
+	oses := f.oses([]string{"macos"})
+	if len(oses) == 0 {
+		return nil, errors.New("No darwin archives (amd64 or arm64) for the cask")
+	}
+	return f.execute(caskTemplate, oses)
+}
+
+func (f Formula) oses(names []string) []osArchives {
+	goos := map[string]string{"macos": "darwin", "linux": "linux"}
+	ret := []osArchives{}
+	for _, name := range names {
+		archives := []Archive{}
+		for _, a := range f.Archives {
+			if a.Os == goos[name] && homebrewArches[a.Arch] {
+				archives = append(archives, a)
+			}
+		}
+		sort.Slice(archives, func(i, j int) bool { return archives[i].Arch < archives[j].Arch })
+		if len(archives) > 0 {
+			ret = append(ret, osArchives{name, archives})
+		}
+	}
+	return ret
+}
+
+func (f Formula) execute(text string, oses []osArchives) ([]byte, error) {
+	if f.Name == "" {
+		return nil, errors.New("Formula name is required")
+	}
+	funcs := template.FuncMap{"ruby": rubyEscape, "cpu": cpu}
+	tmpl, err := template.New("formula").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	params := struct {
+		Formula
+		ClassName string
+		Oses      []osArchives
+	}{f, ClassName(f.Name), oses}
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, params)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func cpu(arch string) string {
+	if arch == "arm64" {
+		return "Hardware::CPU.arm?"
+	}
+	return "Hardware::CPU.intel?"
+}
+
+// escapes a double-quoted Ruby string
+func rubyEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "#{", `\#{`, "\n", `\n`).Replace(s)
+}
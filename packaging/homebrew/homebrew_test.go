@@ -0,0 +1,72 @@
+package homebrew
+
+import (
+	"strings"
+	"testing"
+)
+
+func testFormula() Formula {
+	return Formula{
+		Name:        "my-app",
+		Description: `Says "hello"`,
+		Homepage:    "https://example.com",
+		Version:     "1.2.3",
+		License:     "Apache-2.0",
+		Binaries:    []string{"my-app"},
+		Archives: []Archive{
+			{Os: "darwin", Arch: "arm64", URL: "https://example.com/my-app_darwin_arm64.zip", Sha256: "aa", Dir: "my-app_darwin_arm64"},
+			{Os: "darwin", Arch: "amd64", URL: "https://example.com/my-app_darwin_amd64.zip", Sha256: "bb"},
+			{Os: "linux", Arch: "amd64", URL: "https://example.com/my-app_linux_amd64.tar.gz", Sha256: "cc"},
+			{Os: "linux", Arch: "386", URL: "https://example.com/my-app_linux_386.tar.gz", Sha256: "dd"},
+			{Os: "windows", Arch: "amd64", URL: "https://example.com/my-app_windows_amd64.zip", Sha256: "ee"}}}
+}
+
+func TestClassName(t *testing.T) {
+	for in, expected := range map[string]string{"goxc": "Goxc", "my-app": "MyApp", "my_app2": "MyApp2", "foo@1.2": "FooAT12", "c++": "Cxx"} {
+		if actual := ClassName(in); actual != expected {
+			t.Errorf("ClassName(%q) = %q, expected %q", in, actual, expected)
+		}
+	}
+}
+
+func TestRuby(t *testing.T) {
+	rb, err := testFormula().Ruby()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(rb)
+	for _, expected := range []string{"class MyApp < Formula", `desc "Says \"hello\""`, "on_macos do", "on_linux do", "if Hardware::CPU.arm?", `url "https://example.com/my-app_linux_amd64.tar.gz"`, `sha256 "cc"`, `bin.install "my-app"`} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %s in:\n%s", expected, s)
+		}
+	}
+	for _, unexpected := range []string{"386", "windows"} {
+		if strings.Contains(s, unexpected) {
+			t.Errorf("unexpected %s in:\n%s", unexpected, s)
+		}
+	}
+	if strings.Index(s, "on_macos") > strings.Index(s, "on_linux") {
+		t.Errorf("expected macos before linux:\n%s", s)
+	}
+}
+
+func TestCask(t *testing.T) {
+	rb, err := testFormula().Cask()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(rb)
+	for _, expected := range []string{`cask "my-app" do`, "on_arm do", "on_intel do", `binary "my-app_darwin_arm64/my-app"`, `binary "my-app"`, `sha256 "bb"`} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %s in:\n%s", expected, s)
+		}
+	}
+	if strings.Contains(s, "linux") {
+		t.Errorf("unexpected linux archive in cask:\n%s", s)
+	}
+	f := testFormula()
+	f.Archives = f.Archives[2:]
+	if _, err := f.Cask(); err == nil {
+		t.Errorf("expected an error for a cask without darwin archives")
+	}
+}
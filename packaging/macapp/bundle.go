@@ -0,0 +1,170 @@
+// macOS application bundles ('.app'), with a generated Info.plist and an optional icon
+package macapp
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+const infoPlistTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleDevelopmentRegion</key>
+	<string>en</string>
+	<key>CFBundleExecutable</key>
+	<string>{{xml .Executable}}</string>
+	<key>CFBundleIdentifier</key>
+	<string>{{xml .Identifier}}</string>
+	<key>CFBundleInfoDictionaryVersion</key>
+	<string>6.0</string>
+	<key>CFBundleName</key>
+	<string>{{xml .Name}}</string>
+	<key>CFBundleDisplayName</key>
+	<string>{{xml .Name}}</string>
+	<key>CFBundlePackageType</key>
+	<string>APPL</string>
+	<key>CFBundleSignature</key>
+	<string>????</string>
+	<key>CFBundleShortVersionString</key>
+	<string>{{xml .ShortVersion}}</string>
+	<key>CFBundleVersion</key>
+	<string>{{xml .Version}}</string>
+{{- if .IconFile}}
+	<key>CFBundleIconFile</key>
+	<string>{{xml .IconFile}}</string>
+{{- end}}
+{{- if .MinimumSystemVersion}}
+	<key>LSMinimumSystemVersion</key>
+	<string>{{xml .MinimumSystemVersion}}</string>
+{{- end}}
+{{- if .Copyright}}
+	<key>NSHumanReadableCopyright</key>
+	<string>{{xml .Copyright}}</string>
+{{- end}}
+	<key>NSHighResolutionCapable</key>
+	<{{if .HighResolution}}true{{else}}false{{end}}/>
+</dict>
+</plist>
+`
+
+// Metadata for the bundle's Info.plist
+type Bundle struct {
+	//display name
+	Name string
+	//file name of the binary inside Contents/MacOS
+	Executable string
+	//reverse-DNS identifier, e.g. com.example.myapp
+	Identifier string
+	//build version (CFBundleVersion)
+	Version string
+	//release version (CFBundleShortVersionString)
+	ShortVersion         string
+	MinimumSystemVersion string
+	Copyright            string
+	//file name of the icon inside Contents/Resources. Empty for no icon
+	IconFile       string
+	HighResolution bool
+}
+
+// The Info.plist XML
+func (b Bundle) InfoPlist() ([]byte, error) {
+	if b.Executable == "" {
+		return nil, errors.New("Bundle executable is required")
+	}
+	if b.Identifier == "" {
+		return nil, errors.New("Bundle identifier is required")
+	}
+	tmpl, err := template.New("Info.plist").Funcs(template.FuncMap{"xml": escape}).Parse(infoPlistTemplate)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Writes the bundle to bundleDir (replacing any existing bundle), with the binary at Contents/MacOS/<Executable>.
+// icon is written to Contents/Resources/<IconFile>, if set.
+func (b Bundle) Write(bundleDir, binPath string, icon []byte) error {
+	plist, err := b.InfoPlist()
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(bundleDir)
+	if err != nil {
+		return err
+	}
+	contents := filepath.Join(bundleDir, "Contents")
+	for _, dir := range []string{"MacOS", "Resources"} {
+		err = os.MkdirAll(filepath.Join(contents, dir), 0755)
+		if err != nil {
+			return err
+		}
+	}
+	err = ioutil.WriteFile(filepath.Join(contents, "Info.plist"), plist, 0644)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(contents, "PkgInfo"), []byte("APPL????"), 0644)
+	if err != nil {
+		return err
+	}
+	if b.IconFile != "" {
+		err = ioutil.WriteFile(filepath.Join(contents, "Resources", b.IconFile), icon, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return copyExecutable(binPath, filepath.Join(contents, "MacOS", b.Executable))
+}
+
+func copyExecutable(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func escape(s string) string {
+	buf := &bytes.Buffer{}
+	//writes to a bytes.Buffer never fail
+	_ = xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
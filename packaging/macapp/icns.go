@@ -0,0 +1,124 @@
+package macapp
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// PNG-encoded icon types, by pixel size
+var icnsTypes = []struct {
+	osType string
+	size   int
+}{
+	{"icp4", 16},
+	{"icp5", 32},
+	{"icp6", 64},
+	{"ic07", 128},
+	{"ic08", 256},
+	{"ic09", 512},
+	{"ic10", 1024},
+}
+
+// Converts a square PNG image into the ICNS format, with an entry for each standard size up to the size of the source
+func PngToIcns(r io.Reader) ([]byte, error) {
+	src, err := png.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	if bounds.Dx() != bounds.Dy() {
+		return nil, fmt.Errorf("Icon must be square (it is %dx%d)", bounds.Dx(), bounds.Dy())
+	}
+	entries := &bytes.Buffer{}
+	for _, t := range icnsTypes {
+		if t.size > bounds.Dx() {
+			break
+		}
+		data := &bytes.Buffer{}
+		err = png.Encode(data, scale(src, t.size))
+		if err != nil {
+			return nil, err
+		}
+		entries.WriteString(t.osType)
+		//lengths include the 8 byte type+length header
+		_ = binary.Write(entries, binary.BigEndian, uint32(data.Len()+8))
+		entries.Write(data.Bytes())
+	}
+	if entries.Len() == 0 {
+		return nil, fmt.Errorf("Icon must be at least %dx%d", icnsTypes[0].size, icnsTypes[0].size)
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteString("icns")
+	_ = binary.Write(buf, binary.BigEndian, uint32(entries.Len()+8))
+	buf.Write(entries.Bytes())
+	return buf.Bytes(), nil
+}
+
+// Lists the icon types contained in ICNS data
+func IcnsTypes(data []byte) ([]string, error) {
+	if len(data) < 8 || string(data[:4]) != "icns" || int(binary.BigEndian.Uint32(data[4:8])) != len(data) {
+		return nil, errors.New("Not a valid ICNS file")
+	}
+	ret := []string{}
+	for offset := 8; offset < len(data); {
+		if offset+8 > len(data) {
+			return nil, errors.New("Truncated ICNS entry")
+		}
+		length := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+		if length < 8 || offset+length > len(data) {
+			return nil, errors.New("Invalid ICNS entry length")
+		}
+		ret = append(ret, string(data[offset:offset+4]))
+		offset += length
+	}
+	return ret, nil
+}
+
+// downscales a square image by averaging the source pixels covered by each destination pixel
+func scale(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	srcSize := bounds.Dx()
+	dest := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		sy0, sy1 := y*srcSize/size, (y+1)*srcSize/size
+		for x := 0; x < size; x++ {
+			sx0, sx1 := x*srcSize/size, (x+1)*srcSize/size
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					//premultiplied, so averaging is correct for transparent pixels
+					cr, cg, cb, ca := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dest.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return dest
+}
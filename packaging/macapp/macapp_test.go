@@ -0,0 +1,61 @@
+package macapp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPngToIcns(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x * 4), uint8(y * 4), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	icns, err := PngToIcns(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, err := IcnsTypes(icns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(types, []string{"icp4", "icp5", "icp6"}) {
+		t.Errorf("unexpected icon types %v", types)
+	}
+	//first entry is a 16x16 png
+	entry, err := png.Decode(bytes.NewReader(icns[16:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Bounds().Dx() != 16 {
+		t.Errorf("unexpected size %v", entry.Bounds())
+	}
+}
+
+func TestInfoPlist(t *testing.T) {
+	plist, err := Bundle{Name: "A & B", Executable: "ab", Identifier: "com.example.ab", Version: "1.2.3", ShortVersion: "1.2.3", IconFile: "ab.icns"}.InfoPlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(plist)
+	for _, expected := range []string{"<string>A &amp; B</string>", "<string>com.example.ab</string>", "<key>CFBundleIconFile</key>", "<false/>"} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("Info.plist does not contain %q:\n%s", expected, s)
+		}
+	}
+	_, err = Bundle{Executable: "ab"}.InfoPlist()
+	if err == nil {
+		t.Errorf("expected an error for a missing identifier")
+	}
+}
@@ -0,0 +1,220 @@
+package macpkg
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// Bill of materials layout, as read by lsbom and the macOS installer (see also bomutils' mkbom)
+const (
+	bomHeaderSize = 512
+	//block size of the paths and hard links trees
+	bomPathsBlockSize = 4096
+	//block size of the VIndex and Size64 trees
+	bomSmallBlockSize = 128
+	//entries per leaf of the paths tree
+	bomLeafEntries = 256
+
+	bomTypeFile = 1
+	bomTypeDir  = 2
+)
+
+// a BOM is a set of numbered blocks, plus named variables pointing at some of them
+type bomWriter struct {
+	//block 0 is always null
+	blocks [][]byte
+	vars   []bomVar
+}
+
+type bomVar struct {
+	name  string
+	block uint32
+}
+
+func (b *bomWriter) add(data []byte) uint32 {
+	b.blocks = append(b.blocks, data)
+	return uint32(len(b.blocks) - 1)
+}
+
+func (b *bomWriter) addVar(name string, block uint32) {
+	b.vars = append(b.vars, bomVar{name, block})
+}
+
+// Adds a tree header, pointing at the root node
+func (b *bomWriter) addTree(root uint32, blockSize uint32, pathCount int) uint32 {
+	buf := &bytes.Buffer{}
+	buf.WriteString("tree")
+	write(buf, uint32(1), root, blockSize, uint32(pathCount), uint8(0))
+	return b.add(buf.Bytes())
+}
+
+// Adds a tree node: a leaf, or a branch whose indices point at child nodes
+func treeNode(isLeaf bool, indices [][2]uint32, forward, backward uint32, blockSize int) []byte {
+	buf := &bytes.Buffer{}
+	leaf := uint16(0)
+	if isLeaf {
+		leaf = 1
+	}
+	write(buf, leaf, uint16(len(indices)), forward, backward)
+	for _, index := range indices {
+		write(buf, index[0], index[1])
+	}
+	ret := make([]byte, blockSize)
+	copy(ret, buf.Bytes())
+	return ret
+}
+
+func (b *bomWriter) addEmptyTree(blockSize int) uint32 {
+	return b.addTree(b.add(treeNode(true, nil, 0, 0, blockSize)), uint32(blockSize), 0)
+}
+
+// Builds the BOM for the payload entries
+func bom(entries []payloadEntry, modTime time.Time) []byte {
+	b := &bomWriter{blocks: [][]byte{nil}}
+
+	info := &bytes.Buffer{}
+	write(info, uint32(1), uint32(len(entries)), uint32(0))
+	b.addVar("BomInfo", b.add(info.Bytes()))
+
+	//one index per path, pointing at its info and at its key (parent id + name)
+	indices := [][2]uint32{}
+	for i, entry := range entries {
+		info2 := &bytes.Buffer{}
+		pathType := uint8(bomTypeFile)
+		if entry.isDir() {
+			pathType = bomTypeDir
+		}
+		write(info2, pathType, uint8(1), uint16(3), uint16(entry.mode), uint32(0), uint32(0), uint32(modTime.Unix()), uint32(len(entry.data)), uint8(1))
+		if entry.isDir() {
+			write(info2, uint32(0))
+		} else {
+			write(info2, cksum(entry.data))
+		}
+		//link name length
+		write(info2, uint32(0))
+		info1 := &bytes.Buffer{}
+		write(info1, uint32(i+1), b.add(info2.Bytes()))
+		key := &bytes.Buffer{}
+		write(key, uint32(entry.parent))
+		key.WriteString(entry.name)
+		key.WriteByte(0)
+		indices = append(indices, [2]uint32{b.add(info1.Bytes()), b.add(key.Bytes())})
+	}
+	//leaves are linked to their neighbours. A single leaf is the root; otherwise a branch points at each leaf (keyed by its last path)
+	leaves := []uint32{}
+	chunks := [][][2]uint32{}
+	for start := 0; start < len(indices); start += bomLeafEntries {
+		end := start + bomLeafEntries
+		if end > len(indices) {
+			end = len(indices)
+		}
+		chunks = append(chunks, indices[start:end])
+		leaves = append(leaves, b.add(nil))
+	}
+	branch := [][2]uint32{}
+	for i, chunk := range chunks {
+		var forward, backward uint32
+		if i > 0 {
+			backward = leaves[i-1]
+		}
+		if i < len(leaves)-1 {
+			forward = leaves[i+1]
+		}
+		b.blocks[leaves[i]] = treeNode(true, chunk, forward, backward, bomPathsBlockSize)
+		branch = append(branch, [2]uint32{leaves[i], chunk[len(chunk)-1][1]})
+	}
+	root := leaves[0]
+	if len(leaves) > 1 {
+		root = b.add(treeNode(false, branch, 0, 0, bomPathsBlockSize))
+	}
+	b.addVar("Paths", b.addTree(root, bomPathsBlockSize, len(entries)))
+	b.addVar("HLIndex", b.addEmptyTree(bomPathsBlockSize))
+	vIndex := &bytes.Buffer{}
+	write(vIndex, uint32(1), b.addEmptyTree(bomSmallBlockSize), uint32(0), uint8(0))
+	b.addVar("VIndex", b.add(vIndex.Bytes()))
+	b.addVar("Size64", b.addEmptyTree(bomSmallBlockSize))
+	return b.bytes()
+}
+
+// header, then the blocks, then the block table and the variables
+func (b *bomWriter) bytes() []byte {
+	data := &bytes.Buffer{}
+	addresses := make([][2]uint32, len(b.blocks))
+	for i, block := range b.blocks {
+		if block == nil {
+			continue
+		}
+		addresses[i] = [2]uint32{uint32(bomHeaderSize + data.Len()), uint32(len(block))}
+		data.Write(block)
+	}
+	index := &bytes.Buffer{}
+	write(index, uint32(len(addresses)))
+	for _, address := range addresses {
+		write(index, address[0], address[1])
+	}
+	//empty free list
+	write(index, uint32(0))
+	vars := &bytes.Buffer{}
+	write(vars, uint32(len(b.vars)))
+	for _, v := range b.vars {
+		write(vars, v.block, uint8(len(v.name)))
+		vars.WriteString(v.name)
+	}
+	indexOffset := bomHeaderSize + data.Len()
+	varsOffset := indexOffset + index.Len()
+
+	header := &bytes.Buffer{}
+	header.WriteString("BOMStore")
+	write(header, uint32(1), uint32(len(b.blocks)-1), uint32(indexOffset), uint32(index.Len()), uint32(varsOffset), uint32(vars.Len()))
+	ret := make([]byte, bomHeaderSize, varsOffset+vars.Len())
+	copy(ret, header.Bytes())
+	ret = append(ret, data.Bytes()...)
+	ret = append(ret, index.Bytes()...)
+	return append(ret, vars.Bytes()...)
+}
+
+// POSIX 'cksum' CRC, as used for file checksums in BOMs
+func cksum(data []byte) uint32 {
+	var crc uint32
+	update := func(b byte) {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	for _, b := range data {
+		update(b)
+	}
+	for n := len(data); n > 0; n >>= 8 {
+		update(byte(n))
+	}
+	return ^crc
+}
+
+func write(buf *bytes.Buffer, values ...interface{}) {
+	for _, v := range values {
+		//writes to a bytes.Buffer never fail
+		_ = binary.Write(buf, binary.BigEndian, v)
+	}
+}
@@ -0,0 +1,91 @@
+package macpkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCksum(t *testing.T) {
+	//values from POSIX 'cksum'
+	if sum := cksum([]byte("hello world")); sum != 1135714720 {
+		t.Errorf("unexpected checksum %d", sum)
+	}
+	if sum := cksum(nil); sum != 4294967295 {
+		t.Errorf("unexpected checksum %d", sum)
+	}
+}
+
+func TestPayloadEntries(t *testing.T) {
+	entries := payloadEntries(map[string]file{"/b/c": {0755, []byte("c")}, "/a": {0644, []byte("a")}})
+	paths := []string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.path)
+	}
+	if strings.Join(paths, " ") != ". ./a ./b ./b/c" {
+		t.Errorf("unexpected entries %v", paths)
+	}
+	if entries[3].parent != 3 || !entries[2].isDir() || entries[1].isDir() {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	p := &Package{Identifier: "com.example.app", Version: "1.0", Title: "A & B", InstallLocation: "/usr/local/bin"}
+	p.AddFile("app", []byte("binary"), 0755)
+	buf := &bytes.Buffer{}
+	err := p.Write(buf, time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if string(data[:4]) != "xar!" {
+		t.Fatalf("unexpected magic %q", data[:4])
+	}
+	tocLength := binary.BigEndian.Uint64(data[8:16])
+	zr, err := zlib.NewReader(bytes.NewReader(data[xarHeaderSize : xarHeaderSize+tocLength]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	toc, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"<name>Distribution</name>", "<name>com.example.app.pkg</name>", "<name>Bom</name>", "<name>PackageInfo</name>", "<name>Payload</name>"} {
+		if !strings.Contains(string(toc), expected) {
+			t.Errorf("toc does not contain %q:\n%s", expected, toc)
+		}
+	}
+	//the payload is a gzipped cpio archive
+	entries := payloadEntries(p.files)
+	payloadData, err := payload(entries, time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(payloadData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpio, err := ioutil.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(cpio, []byte("070707")) || !bytes.Contains(cpio, []byte("./app\x00binary")) || !bytes.Contains(cpio, []byte("TRAILER!!!")) {
+		t.Errorf("unexpected payload %q", cpio)
+	}
+	if !bytes.Contains(data, payloadData) {
+		t.Errorf("package does not contain the payload")
+	}
+	if !bytes.HasPrefix(bom(entries, time.Unix(0, 0)), []byte("BOMStore")) {
+		t.Errorf("unexpected bom")
+	}
+	err = (&Package{Identifier: "com.example.app", InstallLocation: "bin"}).Write(buf, time.Unix(0, 0))
+	if err == nil {
+		t.Errorf("expected an error for a relative install location")
+	}
+}
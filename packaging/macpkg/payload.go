@@ -0,0 +1,126 @@
+package macpkg
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	modeDir  = 0040000
+	modeFile = 0100000
+)
+
+// an entry of the payload (and bom). IDs are 1-based positions in the list, with the root '.' first.
+type payloadEntry struct {
+	name   string
+	path   string
+	parent int
+	mode   uint32
+	data   []byte
+}
+
+func (e payloadEntry) isDir() bool {
+	return e.mode&modeDir != 0
+}
+
+// Lists the files along with all their parent directories, breadth-first and sorted by name within each directory.
+// This orders entries by (parent, name) - the ordering of keys in the bom's paths tree.
+func payloadEntries(files map[string]file) []payloadEntry {
+	children := map[string][]string{}
+	var addDir func(dir string)
+	addDir = func(dir string) {
+		if dir == "." {
+			return
+		}
+		parent := path.Dir(dir)
+		for _, existing := range children[parent] {
+			if existing == dir {
+				return
+			}
+		}
+		children[parent] = append(children[parent], dir)
+		addDir(parent)
+	}
+	for p := range files {
+		rel := strings.TrimPrefix(path.Clean("/"+p), "/")
+		addDir(path.Dir(rel))
+		children[path.Dir(rel)] = append(children[path.Dir(rel)], rel)
+	}
+	entries := []payloadEntry{{name: ".", path: ".", mode: modeDir | 0755}}
+	dirs := []string{"."}
+	for i := 0; i < len(entries); i++ {
+		if !entries[i].isDir() {
+			continue
+		}
+		kids := children[dirs[i]]
+		sort.Strings(kids)
+		for _, kid := range kids {
+			entry := payloadEntry{name: path.Base(kid), path: "./" + kid, parent: i + 1, mode: modeDir | 0755}
+			if f, isFile := files["/"+kid]; isFile {
+				entry.mode = modeFile | uint32(f.mode.Perm())
+				entry.data = f.data
+			}
+			entries = append(entries, entry)
+			dirs = append(dirs, kid)
+		}
+	}
+	return entries
+}
+
+// The Payload: a gzipped cpio archive (portable 'odc' format)
+func payload(entries []payloadEntry, modTime time.Time) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	for i, entry := range entries {
+		nlink := 1
+		if entry.isDir() {
+			nlink = 2
+		}
+		err := writeCpioEntry(gw, i+1, entry.mode, nlink, modTime.Unix(), entry.path, entry.data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := writeCpioEntry(gw, 0, 0, 1, 0, "TRAILER!!!", nil)
+	if err != nil {
+		return nil, err
+	}
+	err = gw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCpioEntry(w io.Writer, ino int, mode uint32, nlink int, mtime int64, name string, data []byte) error {
+	//dev, ino, mode, uid, gid, nlink, rdev, mtime, namesize, filesize
+	_, err := fmt.Fprintf(w, "070707%06o%06o%06o%06o%06o%06o%06o%011o%06o%011o%s\x00",
+		0, ino, mode, 0, 0, nlink, 0, mtime, len(name)+1, len(data), name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
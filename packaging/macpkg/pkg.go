@@ -0,0 +1,128 @@
+package macpkg
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"text/template"
+	"time"
+)
+
+const packageInfoTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<pkg-info format-version="2" identifier="{{xml .Identifier}}" version="{{xml .Version}}" install-location="{{xml .InstallLocation}}" auth="root">
+	<payload numberOfFiles="{{.NumberOfFiles}}" installKBytes="{{.InstallKBytes}}"/>
+	<bundle-version/>
+</pkg-info>
+`
+
+const distributionTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<installer-gui-script minSpecVersion="2">
+	<title>{{xml .Title}}</title>
+	<options customize="never" require-scripts="false"/>
+	<choices-outline>
+		<line choice="default">
+			<line choice="{{xml .Identifier}}"/>
+		</line>
+	</choices-outline>
+	<choice id="default"/>
+	<choice id="{{xml .Identifier}}" visible="false">
+		<pkg-ref id="{{xml .Identifier}}"/>
+	</choice>
+	<pkg-ref id="{{xml .Identifier}}" version="{{xml .Version}}" onConclusion="none" installKBytes="{{.InstallKBytes}}">#{{xml .ComponentName}}</pkg-ref>
+</installer-gui-script>
+`
+
+// a file to be installed
+type file struct {
+	mode os.FileMode
+	data []byte
+}
+
+// An unsigned flat installer package, containing a single component
+type Package struct {
+	//reverse-DNS identifier, e.g. com.example.myapp
+	Identifier string
+	Version    string
+	//shown by the installer
+	Title string
+	//absolute directory into which the files are installed, e.g. /usr/local/bin
+	InstallLocation string
+	files           map[string]file
+}
+
+// Adds a file, at a path relative to the InstallLocation
+func (p *Package) AddFile(name string, data []byte, mode os.FileMode) {
+	if p.files == nil {
+		p.files = map[string]file{}
+	}
+	p.files[path.Clean("/"+name)] = file{mode, data}
+}
+
+// Writes the '.pkg'. modTime is used for all timestamps, so that output is reproducible
+func (p *Package) Write(w io.Writer, modTime time.Time) error {
+	if p.Identifier == "" {
+		return errors.New("Package identifier is required")
+	}
+	if !path.IsAbs(p.InstallLocation) {
+		return errors.New("Package install location must be an absolute path")
+	}
+	entries := payloadEntries(p.files)
+	payloadData, err := payload(entries, modTime)
+	if err != nil {
+		return err
+	}
+	size := 0
+	for _, f := range p.files {
+		size += len(f.data)
+	}
+	params := struct {
+		Package
+		ComponentName string
+		NumberOfFiles int
+		InstallKBytes int
+	}{*p, p.Identifier + ".pkg", len(entries), (size + 1023) / 1024}
+	packageInfo, err := execute("PackageInfo", packageInfoTemplate, params)
+	if err != nil {
+		return err
+	}
+	distribution, err := execute("Distribution", distributionTemplate, params)
+	if err != nil {
+		return err
+	}
+	component := &xarEntry{name: params.ComponentName, isDir: true, children: []*xarEntry{
+		{name: "Bom", data: bom(entries, modTime)},
+		{name: "PackageInfo", data: packageInfo},
+		{name: "Payload", data: payloadData}}}
+	return writeXar(w, []*xarEntry{{name: "Distribution", data: distribution}, component}, modTime)
+}
+
+func execute(name, text string, data interface{}) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"xml": escape}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
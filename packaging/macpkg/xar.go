@@ -0,0 +1,116 @@
+// macOS flat installer packages ('.pkg'): a xar archive containing a Distribution, plus a component package with a PackageInfo, Bom and Payload
+package macpkg
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	xarMagic      = 0x78617221
+	xarHeaderSize = 28
+	xarCksumSha1  = 1
+)
+
+// a file or directory within a xar archive
+type xarEntry struct {
+	name     string
+	data     []byte
+	isDir    bool
+	children []*xarEntry
+}
+
+// Writes a xar archive. The table of contents is zlib-compressed; file data is stored as-is.
+func writeXar(w io.Writer, entries []*xarEntry, modTime time.Time) error {
+	heap := &bytes.Buffer{}
+	//the heap starts with the checksum of the compressed toc
+	heap.Write(make([]byte, sha1.Size))
+	toc := &bytes.Buffer{}
+	toc.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<xar>\n<toc>\n")
+	fmt.Fprintf(toc, "<checksum style=\"sha1\"><offset>0</offset><size>%d</size></checksum>\n", sha1.Size)
+	fmt.Fprintf(toc, "<creation-time>%s</creation-time>\n", modTime.UTC().Format("2006-01-02T15:04:05"))
+	id := 0
+	writeXarEntries(toc, heap, entries, &id, modTime)
+	toc.WriteString("</toc>\n</xar>\n")
+
+	compressed := &bytes.Buffer{}
+	zw := zlib.NewWriter(compressed)
+	_, err := zw.Write(toc.Bytes())
+	if err != nil {
+		return err
+	}
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+	sum := sha1.Sum(compressed.Bytes())
+	heapBytes := heap.Bytes()
+	copy(heapBytes, sum[:])
+
+	header := &bytes.Buffer{}
+	for _, v := range []interface{}{uint32(xarMagic), uint16(xarHeaderSize), uint16(1), uint64(compressed.Len()), uint64(toc.Len()), uint32(xarCksumSha1)} {
+		//writes to a bytes.Buffer never fail
+		_ = binary.Write(header, binary.BigEndian, v)
+	}
+	for _, b := range [][]byte{header.Bytes(), compressed.Bytes(), heapBytes} {
+		_, err = w.Write(b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeXarEntries(toc, heap *bytes.Buffer, entries []*xarEntry, id *int, modTime time.Time) {
+	timestamp := modTime.UTC().Format("2006-01-02T15:04:05Z")
+	for _, entry := range entries {
+		*id++
+		fmt.Fprintf(toc, "<file id=\"%d\">\n", *id)
+		if !entry.isDir {
+			sum := fmt.Sprintf("%x", sha1.Sum(entry.data))
+			fmt.Fprintf(toc, "<data><length>%d</length><offset>%d</offset><size>%d</size>", len(entry.data), heap.Len(), len(entry.data))
+			toc.WriteString(`<encoding style="application/octet-stream"/>`)
+			fmt.Fprintf(toc, "<extracted-checksum style=\"sha1\">%s</extracted-checksum><archived-checksum style=\"sha1\">%s</archived-checksum></data>\n", sum, sum)
+			heap.Write(entry.data)
+		}
+		fmt.Fprintf(toc, "<ctime>%s</ctime><mtime>%s</mtime><atime>%s</atime>\n", timestamp, timestamp, timestamp)
+		toc.WriteString("<group>wheel</group><gid>0</gid><user>root</user><uid>0</uid>\n")
+		if entry.isDir {
+			toc.WriteString("<mode>0755</mode><type>directory</type>")
+		} else {
+			toc.WriteString("<mode>0644</mode><type>file</type>")
+		}
+		fmt.Fprintf(toc, "<name>%s</name>\n", escape(entry.name))
+		writeXarEntries(toc, heap, entry.children, id, modTime)
+		toc.WriteString("</file>\n")
+	}
+}
+
+func escape(s string) string {
+	buf := &bytes.Buffer{}
+	//writes to a bytes.Buffer never fail
+	_ = xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
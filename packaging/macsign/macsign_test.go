@@ -0,0 +1,124 @@
+package macsign
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"debug/macho"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/laher/goxc/exefileparse"
+	"github.com/laher/goxc/packaging/cms"
+)
+
+// builds a trivial darwin binary. The linker signs arm64 binaries ad-hoc, but not amd64 ones
+func buildDarwin(t *testing.T, dir, arch string) string {
+	src := filepath.Join(dir, "main.go")
+	err := ioutil.WriteFile(src, []byte("package main\n\nfunc main() {}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(dir, "hello_"+arch)
+	cmd := exec.Command("go", "build", "-o", exe, src)
+	cmd.Env = append(os.Environ(), "GOOS=darwin", "GOARCH="+arch, "CGO_ENABLED=0", "GO111MODULE=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Skipf("could not build a darwin binary: %v\n%s", err, out)
+	}
+	return exe
+}
+
+func TestSign(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-macsign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, arch := range []string{"amd64", "arm64"} {
+		exe := buildDarwin(t, dir, arch)
+		data, err := ioutil.ReadFile(exe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signed, err := Sign(data, "hello", nil)
+		if err != nil {
+			t.Fatalf("%s: %v", arch, err)
+		}
+		err = ioutil.WriteFile(exe, signed, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = exefileparse.VerifyMachOSignature(exe, false)
+		if err != nil {
+			t.Errorf("%s: %v", arch, err)
+		}
+		f, err := macho.Open(exe)
+		if err != nil {
+			t.Fatalf("%s: signed file is not valid Mach-O: %v", arch, err)
+		}
+		f.Close()
+		//re-signing replaces the signature
+		resigned, err := Sign(signed, "hello", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resigned) != len(signed) {
+			t.Errorf("%s: re-signing changed the size from %d to %d", arch, len(signed), len(resigned))
+		}
+		//tampering breaks the signature
+		signed[100] ^= 0xff
+		err = ioutil.WriteFile(exe, signed, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exefileparse.VerifyMachOSignature(exe, false) == nil {
+			t.Errorf("%s: expected a tampered file to fail verification", arch)
+		}
+	}
+}
+
+func TestSignWithCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-macsign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := buildDarwin(t, dir, "amd64")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "Test"}, NotBefore: time.Unix(0, 0), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := Sign(data, "hello", &cms.Signer{Certificate: cert, Key: key, SigningTime: time.Unix(0, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(exe, signed, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exefileparse.VerifyMachOSignature(exe, false)
+	if err != nil {
+		t.Error(err)
+	}
+}
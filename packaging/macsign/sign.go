@@ -0,0 +1,264 @@
+// Embedded code signatures for Mach-O executables, written without Apple's 'codesign' tool
+package macsign
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/laher/goxc/packaging/cms"
+)
+
+// Mach-O layout
+const (
+	machoMagic64      = 0xfeedfacf
+	machoHeaderSize64 = 32
+	lcSegment64       = 0x19
+	lcCodeSignature   = 0x1d
+	//size of a segment_64 command, excluding its sections
+	segmentCommandSize = 72
+	sectionSize64      = 80
+	//__LINKEDIT's vmsize is rounded up to the largest page size (arm64)
+	segmentAlignment = 0x4000
+)
+
+// Code signature blobs (see xnu's osfmk/kern/cs_blobs.h). Note that these are big-endian.
+const (
+	CSMAGIC_EMBEDDED_SIGNATURE = 0xfade0cc0
+	CSMAGIC_CODEDIRECTORY      = 0xfade0c02
+	CSMAGIC_REQUIREMENTS       = 0xfade0c01
+	CSMAGIC_BLOBWRAPPER        = 0xfade0b01
+
+	CSSLOT_CODEDIRECTORY = 0
+	CSSLOT_REQUIREMENTS  = 2
+	CSSLOT_SIGNATURESLOT = 0x10000
+
+	CS_ADHOC               = 0x2
+	CS_EXECSEG_MAIN_BINARY = 0x1
+	CS_HASHTYPE_SHA256     = 2
+)
+
+const (
+	codeDirectoryVersion    = 0x20400
+	codeDirectoryHeaderSize = 88
+	pageSizeBits            = 12
+	pageSize                = 1 << pageSizeBits
+	//requirements and Info.plist
+	specialSlots             = 2
+	superBlobHeaderSize      = 12
+	blobIndexSize            = 8
+	emptyRequirementsBlobLen = 12
+)
+
+// a parsed 64-bit Mach-O header, with the offsets of the load commands we update
+type machoFile struct {
+	//end of the load commands
+	commandsEnd int
+	//smallest file offset of any section, bounding the space for load commands
+	firstSectionOffset uint32
+	linkEdit           int
+	text               int
+	codeSignature      int
+}
+
+// Signs a (thin, 64-bit) Mach-O executable, returning the signed copy. Any existing signature is replaced.
+// A nil signer creates an ad-hoc signature, which is sufficient for running on Apple Silicon.
+func Sign(data []byte, identifier string, signer *cms.Signer) ([]byte, error) {
+	m, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if m.linkEdit < 0 || m.text < 0 {
+		return nil, errors.New("Mach-O file has no __TEXT or __LINKEDIT segment")
+	}
+	le := binary.LittleEndian
+	var sigOffset int
+	if m.codeSignature >= 0 {
+		sigOffset = int(le.Uint32(data[m.codeSignature+8:]))
+	} else {
+		fileOff := le.Uint64(data[m.linkEdit+40:])
+		fileSize := le.Uint64(data[m.linkEdit+48:])
+		if fileOff+fileSize != uint64(len(data)) {
+			return nil, errors.New("__LINKEDIT is not at the end of the file")
+		}
+		if m.commandsEnd+16 > int(m.firstSectionOffset) {
+			return nil, errors.New("Not enough space to add a code signature load command")
+		}
+		sigOffset = (len(data) + 15) &^ 15
+	}
+	maxCMSSize := 8
+	if signer != nil {
+		maxCMSSize += signer.MaxSize()
+	}
+	nCodeSlots := (sigOffset + pageSize - 1) / pageSize
+	cdSize := codeDirectoryHeaderSize + len(identifier) + 1 + (specialSlots+nCodeSlots)*sha256.Size
+	sigSize := superBlobHeaderSize + 3*blobIndexSize + cdSize + emptyRequirementsBlobLen + maxCMSSize
+	sigSize = (sigSize + 15) &^ 15
+
+	out := make([]byte, sigOffset, sigOffset+sigSize)
+	copy(out, data)
+	codeSignature := m.codeSignature
+	if codeSignature < 0 {
+		codeSignature = m.commandsEnd
+		le.PutUint32(out[16:], le.Uint32(out[16:])+1)
+		le.PutUint32(out[20:], le.Uint32(out[20:])+16)
+		le.PutUint32(out[codeSignature:], lcCodeSignature)
+		le.PutUint32(out[codeSignature+4:], 16)
+	}
+	le.PutUint32(out[codeSignature+8:], uint32(sigOffset))
+	le.PutUint32(out[codeSignature+12:], uint32(sigSize))
+	linkEditFileSize := uint64(sigOffset+sigSize) - le.Uint64(out[m.linkEdit+40:])
+	le.PutUint64(out[m.linkEdit+48:], linkEditFileSize)
+	le.PutUint64(out[m.linkEdit+32:], (linkEditFileSize+segmentAlignment-1)&^(segmentAlignment-1))
+
+	requirements := blob(CSMAGIC_REQUIREMENTS, be32(0))
+	flags := uint32(0)
+	if signer == nil {
+		flags = CS_ADHOC
+	}
+	cd := codeDirectory(out, identifier, flags, requirements, le.Uint64(out[m.text+40:]), le.Uint64(out[m.text+48:]))
+	signature := blob(CSMAGIC_BLOBWRAPPER, nil)
+	if signer != nil {
+		cmsData, err := signer.Sign(cms.OIDData, cd, true)
+		if err != nil {
+			return nil, err
+		}
+		if len(cmsData) > maxCMSSize-8 {
+			return nil, errors.New("Signature is larger than the space reserved for it")
+		}
+		signature = blob(CSMAGIC_BLOBWRAPPER, cmsData)
+	}
+	superBlob := superBlob(map[uint32][]byte{CSSLOT_CODEDIRECTORY: cd, CSSLOT_REQUIREMENTS: requirements, CSSLOT_SIGNATURESLOT: signature})
+	out = append(out, superBlob...)
+	return append(out, make([]byte, sigSize-len(superBlob))...), nil
+}
+
+// Whether the data is a thin, 64-bit Mach-O file, which Sign can sign. (Older 32-bit binaries can't be signed)
+func IsSignable(data []byte) bool {
+	return len(data) >= machoHeaderSize64 && binary.LittleEndian.Uint32(data) == machoMagic64
+}
+
+func parse(data []byte) (*machoFile, error) {
+	le := binary.LittleEndian
+	if !IsSignable(data) {
+		return nil, errors.New("Not a thin 64-bit Mach-O file")
+	}
+	m := &machoFile{linkEdit: -1, text: -1, codeSignature: -1, firstSectionOffset: uint32(len(data))}
+	ncmds := le.Uint32(data[16:])
+	offset := machoHeaderSize64
+	for i := uint32(0); i < ncmds; i++ {
+		if offset+8 > len(data) {
+			return nil, errors.New("Truncated Mach-O load commands")
+		}
+		cmd := le.Uint32(data[offset:])
+		size := int(le.Uint32(data[offset+4:]))
+		if size < 8 || offset+size > len(data) {
+			return nil, errors.New("Invalid Mach-O load command size")
+		}
+		switch cmd {
+		case lcSegment64:
+			name := string(bytes.TrimRight(data[offset+8:offset+24], "\x00"))
+			switch name {
+			case "__LINKEDIT":
+				m.linkEdit = offset
+			case "__TEXT":
+				m.text = offset
+			}
+			nsects := int(le.Uint32(data[offset+64:]))
+			for s := 0; s < nsects; s++ {
+				section := offset + segmentCommandSize + s*sectionSize64
+				if section+sectionSize64 > offset+size {
+					return nil, errors.New("Invalid Mach-O section count")
+				}
+				//zerofill sections have no file offset
+				if sectionOffset := le.Uint32(data[section+48:]); sectionOffset != 0 && sectionOffset < m.firstSectionOffset {
+					m.firstSectionOffset = sectionOffset
+				}
+			}
+		case lcCodeSignature:
+			m.codeSignature = offset
+		}
+		offset += size
+	}
+	m.commandsEnd = offset
+	return m, nil
+}
+
+// The CodeDirectory: hashes of each page up to the signature, plus the 'special slots' (here, just the requirements)
+func codeDirectory(data []byte, identifier string, flags uint32, requirements []byte, execSegBase, execSegLimit uint64) []byte {
+	nCodeSlots := (len(data) + pageSize - 1) / pageSize
+	identOffset := codeDirectoryHeaderSize
+	hashOffset := identOffset + len(identifier) + 1 + specialSlots*sha256.Size
+	buf := &bytes.Buffer{}
+	for _, v := range []interface{}{
+		uint32(CSMAGIC_CODEDIRECTORY), uint32(hashOffset + nCodeSlots*sha256.Size), uint32(codeDirectoryVersion), flags,
+		uint32(hashOffset), uint32(identOffset), uint32(specialSlots), uint32(nCodeSlots), uint32(len(data)),
+		uint8(sha256.Size), uint8(CS_HASHTYPE_SHA256), uint8(0), uint8(pageSizeBits), uint32(0),
+		//scatterOffset, teamOffset, spare3, codeLimit64
+		uint32(0), uint32(0), uint32(0), uint64(0),
+		execSegBase, execSegLimit, uint64(CS_EXECSEG_MAIN_BINARY)} {
+		//writes to a bytes.Buffer never fail
+		_ = binary.Write(buf, binary.BigEndian, v)
+	}
+	buf.WriteString(identifier)
+	buf.WriteByte(0)
+	//special slots are stored in reverse order, before the code slots. Slot 1 (Info.plist) is empty
+	requirementsHash := sha256.Sum256(requirements)
+	buf.Write(requirementsHash[:])
+	buf.Write(make([]byte, sha256.Size))
+	for start := 0; start < len(data); start += pageSize {
+		end := start + pageSize
+		if end > len(data) {
+			end = len(data)
+		}
+		hash := sha256.Sum256(data[start:end])
+		buf.Write(hash[:])
+	}
+	return buf.Bytes()
+}
+
+// The SuperBlob: an index of blobs by slot, followed by the blobs themselves
+func superBlob(blobs map[uint32][]byte) []byte {
+	slots := []uint32{CSSLOT_CODEDIRECTORY, CSSLOT_REQUIREMENTS, CSSLOT_SIGNATURESLOT}
+	offset := superBlobHeaderSize + len(slots)*blobIndexSize
+	index := &bytes.Buffer{}
+	contents := &bytes.Buffer{}
+	for _, slot := range slots {
+		index.Write(be32(slot))
+		index.Write(be32(uint32(offset + contents.Len())))
+		contents.Write(blobs[slot])
+	}
+	ret := append(be32(CSMAGIC_EMBEDDED_SIGNATURE), be32(uint32(offset+contents.Len()))...)
+	ret = append(ret, be32(uint32(len(slots)))...)
+	ret = append(ret, index.Bytes()...)
+	return append(ret, contents.Bytes()...)
+}
+
+// a generic blob: magic, length and data
+func blob(magic uint32, data []byte) []byte {
+	ret := append(be32(magic), be32(uint32(8+len(data)))...)
+	return append(ret, data...)
+}
+
+func be32(v uint32) []byte {
+	ret := make([]byte, 4)
+	binary.BigEndian.PutUint32(ret, v)
+	return ret
+}
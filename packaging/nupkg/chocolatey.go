@@ -0,0 +1,122 @@
+package nupkg
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"text/template"
+)
+
+// A zip for 'chocolateyinstall.ps1' to extract into the package's tools directory. Chocolatey then shims the executables in it
+type ChocolateyZip struct {
+	//filename inside 'tools', for an embedded zip
+	Embedded string
+	//download URL, for a referenced zip
+	URL    string
+	Sha256 string
+}
+
+// The 32-bit and 64-bit zips. Either may be nil, but not both
+type ChocolateyInstall struct {
+	Zip32 *ChocolateyZip
+	Zip64 *ChocolateyZip
+}
+
+const chocolateyEmbeddedTemplate = `$ErrorActionPreference = 'Stop'
+$toolsDir = "$(Split-Path -Parent $MyInvocation.MyCommand.Definition)"
+
+$packageArgs = @{
+  PackageName    = $env:ChocolateyPackageName
+  Destination    = $toolsDir
+{{- if .Zip32}}
+  FileFullPath   = Join-Path $toolsDir '{{ps .Zip32.Embedded}}'
+{{- end}}
+{{- if .Zip64}}
+  FileFullPath64 = Join-Path $toolsDir '{{ps .Zip64.Embedded}}'
+{{- end}}
+}
+Get-ChocolateyUnzip @packageArgs
+{{- if .Zip32}}
+Remove-Item -Force (Join-Path $toolsDir '{{ps .Zip32.Embedded}}')
+{{- end}}
+{{- if .Zip64}}
+Remove-Item -Force (Join-Path $toolsDir '{{ps .Zip64.Embedded}}')
+{{- end}}
+`
+
+const chocolateyReferencedTemplate = `$ErrorActionPreference = 'Stop'
+$toolsDir = "$(Split-Path -Parent $MyInvocation.MyCommand.Definition)"
+
+$packageArgs = @{
+  PackageName    = $env:ChocolateyPackageName
+  UnzipLocation  = $toolsDir
+{{- if .Zip32}}
+  Url            = '{{ps .Zip32.URL}}'
+  Checksum       = '{{ps .Zip32.Sha256}}'
+  ChecksumType   = 'sha256'
+{{- end}}
+{{- if .Zip64}}
+  Url64bit       = '{{ps .Zip64.URL}}'
+  Checksum64     = '{{ps .Zip64.Sha256}}'
+  ChecksumType64 = 'sha256'
+{{- end}}
+}
+Install-ChocolateyZipPackage @packageArgs
+`
+
+// The 'tools/chocolateyinstall.ps1' script, which extracts embedded zips or downloads referenced ones
+func (c ChocolateyInstall) Script() ([]byte, error) {
+	zips := []*ChocolateyZip{}
+	for _, z := range []*ChocolateyZip{c.Zip32, c.Zip64} {
+		if z != nil {
+			zips = append(zips, z)
+		}
+	}
+	if len(zips) == 0 {
+		return nil, errors.New("No zips for the Chocolatey install script")
+	}
+	embedded := zips[0].Embedded != ""
+	for _, z := range zips {
+		if (z.Embedded != "") != embedded {
+			return nil, errors.New("Chocolatey zips must be either all embedded or all referenced")
+		}
+		if !embedded && (z.URL == "" || z.Sha256 == "") {
+			return nil, errors.New("Referenced Chocolatey zips require a URL and a checksum")
+		}
+	}
+	text := chocolateyReferencedTemplate
+	if embedded {
+		text = chocolateyEmbeddedTemplate
+	}
+	tmpl, err := template.New("chocolateyinstall.ps1").Funcs(template.FuncMap{"ps": powershellEscape}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, c)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// escapes a single-quoted PowerShell string
+func powershellEscape(s string) string {
+	return strings.Replace(s, "'", "''", -1)
+}
@@ -0,0 +1,268 @@
+// NuGet packages ('.nupkg'), as used by Chocolatey. A '.nupkg' is an OPC (Open Packaging Conventions) zip containing a '.nuspec'
+package nupkg
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/archive"
+)
+
+// '.nuspec' metadata
+type Nuspec struct {
+	//lowercase for Chocolatey, e.g. 'my-app'
+	ID               string
+	Version          string
+	Title            string
+	Authors          string
+	Owners           string
+	Description      string
+	Summary          string
+	ProjectURL       string
+	LicenseURL       string
+	IconURL          string
+	PackageSourceURL string
+	Copyright        string
+	ReleaseNotes     string
+	//space-separated
+	Tags string
+}
+
+// A NuGet package: metadata plus content files (e.g. 'tools/chocolateyinstall.ps1')
+type Package struct {
+	Nuspec
+	Files []archive.ArchiveItem
+	//timestamp for generated entries (and Files without a ModTime)
+	Modified time.Time
+}
+
+// NuGet versions: 1 to 4 numeric parts, then optional SemVer 2 prerelease & build metadata
+var versionPattern = regexp.MustCompile(`^\d+(\.\d+){0,3}(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+const nuspecTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://schemas.microsoft.com/packaging/2015/06/nuspec.xsd">
+  <metadata>
+    <id>{{xml .ID}}</id>
+    <version>{{xml .Version}}</version>
+{{- if .Title}}
+    <title>{{xml .Title}}</title>
+{{- end}}
+    <authors>{{xml .Authors}}</authors>
+{{- if .Owners}}
+    <owners>{{xml .Owners}}</owners>
+{{- end}}
+{{- if .ProjectURL}}
+    <projectUrl>{{xml .ProjectURL}}</projectUrl>
+{{- end}}
+{{- if .LicenseURL}}
+    <licenseUrl>{{xml .LicenseURL}}</licenseUrl>
+{{- end}}
+{{- if .IconURL}}
+    <iconUrl>{{xml .IconURL}}</iconUrl>
+{{- end}}
+{{- if .PackageSourceURL}}
+    <packageSourceUrl>{{xml .PackageSourceURL}}</packageSourceUrl>
+{{- end}}
+    <requireLicenseAcceptance>false</requireLicenseAcceptance>
+    <description>{{xml .Description}}</description>
+{{- if .Summary}}
+    <summary>{{xml .Summary}}</summary>
+{{- end}}
+{{- if .ReleaseNotes}}
+    <releaseNotes>{{xml .ReleaseNotes}}</releaseNotes>
+{{- end}}
+{{- if .Copyright}}
+    <copyright>{{xml .Copyright}}</copyright>
+{{- end}}
+{{- if .Tags}}
+    <tags>{{xml .Tags}}</tags>
+{{- end}}
+  </metadata>
+</package>
+`
+
+const relsTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Type="http://schemas.microsoft.com/packaging/2010/07/manifest" Target="/{{xml .NuspecName}}" Id="{{.NuspecRelID}}"/>
+  <Relationship Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="/{{.CorePropertiesName}}" Id="{{.CorePropertiesRelID}}"/>
+</Relationships>
+`
+
+const contentTypesTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+  <Default Extension="psmdcp" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>
+{{- range .Extensions}}
+  <Default Extension="{{xml .}}" ContentType="application/octet"/>
+{{- end}}
+{{- range .Overrides}}
+  <Override PartName="/{{xml .}}" ContentType="application/octet"/>
+{{- end}}
+</Types>
+`
+
+const corePropertiesTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<coreProperties xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns="http://schemas.openxmlformats.org/package/2006/metadata/core-properties">
+  <dc:creator>{{xml .Authors}}</dc:creator>
+  <dc:description>{{xml .Description}}</dc:description>
+  <dc:identifier>{{xml .ID}}</dc:identifier>
+  <version>{{xml .Version}}</version>
+  <keywords>{{xml .Tags}}</keywords>
+  <dc:title>{{xml .Title}}</dc:title>
+  <lastModifiedBy>goxc</lastModifiedBy>
+</coreProperties>
+`
+
+// OPC reserves these names for itself
+var reservedNames = map[string]bool{"[Content_Types].xml": true, "_rels": true, "package": true}
+
+// All of the package's zip entries: content files, the '.nuspec' and the OPC parts
+func (p Package) Items() ([]archive.ArchiveItem, error) {
+	err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+	nuspecName := p.ID + ".nuspec"
+	//deterministic names, for reproducible packages
+	hash := fmt.Sprintf("%x", sha1.Sum([]byte(p.ID+"/"+p.Version)))
+	params := struct {
+		Nuspec
+		NuspecName          string
+		NuspecRelID         string
+		CorePropertiesName  string
+		CorePropertiesRelID string
+		Extensions          []string
+		Overrides           []string
+	}{Nuspec: p.Nuspec,
+		NuspecName:          nuspecName,
+		NuspecRelID:         "R" + hash[:16],
+		CorePropertiesName:  "package/services/metadata/core-properties/" + hash[:32] + ".psmdcp",
+		CorePropertiesRelID: "R" + hash[16:32]}
+	extensions := map[string]bool{"nuspec": true}
+	for _, f := range p.Files {
+		name := strings.Replace(f.ArchivePath, "\\", "/", -1)
+		ext := strings.TrimPrefix(path.Ext(name), ".")
+		if ext == "" {
+			params.Overrides = append(params.Overrides, name)
+		} else {
+			extensions[strings.ToLower(ext)] = true
+		}
+	}
+	for ext := range extensions {
+		if ext != "rels" && ext != "psmdcp" {
+			params.Extensions = append(params.Extensions, ext)
+		}
+	}
+	sort.Strings(params.Extensions)
+	items := []archive.ArchiveItem{}
+	for _, f := range p.Files {
+		if f.ModTime.IsZero() {
+			f.ModTime = p.Modified
+		}
+		items = append(items, f)
+	}
+	for _, part := range []struct{ Name, Template string }{
+		{nuspecName, nuspecTemplate},
+		{"_rels/.rels", relsTemplate},
+		{params.CorePropertiesName, corePropertiesTemplate},
+		{"[Content_Types].xml", contentTypesTemplate}} {
+		data, err := execute(part.Name, part.Template, params)
+		if err != nil {
+			return nil, err
+		}
+		item := archive.ArchiveItemFromBytes(data, part.Name)
+		item.ModTime = p.Modified
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Writes the '.nupkg', using goxc's zip archiver
+func (p Package) Write(filename string) error {
+	items, err := p.Items()
+	if err != nil {
+		return err
+	}
+	return archive.Zip(filename, items)
+}
+
+// The conventional filename, e.g. 'my-app.1.0.0.nupkg'
+func (p Package) Filename() string {
+	return p.ID + "." + p.Version + ".nupkg"
+}
+
+func (p Package) validate() error {
+	missing := []string{}
+	if p.ID == "" {
+		missing = append(missing, "id")
+	}
+	if p.Version == "" {
+		missing = append(missing, "version")
+	}
+	if p.Authors == "" {
+		missing = append(missing, "authors")
+	}
+	if p.Description == "" {
+		missing = append(missing, "description")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("nuspec fields missing (%v)", missing)
+	}
+	if !versionPattern.MatchString(p.Version) {
+		return fmt.Errorf("Invalid nuspec version '%s'. NuGet requires a numeric version such as '1.2.3' or '1.2.3-beta1'. Please set the PackageVersion", p.Version)
+	}
+	for _, f := range p.Files {
+		top := strings.SplitN(strings.Replace(f.ArchivePath, "\\", "/", -1), "/", 2)[0]
+		if reservedNames[top] || strings.HasSuffix(f.ArchivePath, ".nuspec") {
+			return errors.New("Reserved nupkg path: " + f.ArchivePath)
+		}
+	}
+	return nil
+}
+
+func execute(name, text string, data interface{}) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"xml": escapeXml}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func escapeXml(s string) string {
+	buf := &bytes.Buffer{}
+	//writes to a bytes.Buffer never fail
+	_ = xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,164 @@
+package nupkg
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/laher/goxc/archive"
+)
+
+func testPackage() Package {
+	return Package{
+		Nuspec: Nuspec{ID: "my-app", Version: "1.2.3", Title: "My App", Authors: "Me & Co", Description: "Says hello", Tags: "hello cli"},
+		Files: []archive.ArchiveItem{
+			archive.ArchiveItemFromBytes([]byte("Write-Host hi"), "tools/chocolateyinstall.ps1"),
+			archive.ArchiveItemFromBytes([]byte("PK"), "tools/my-app_windows_amd64.zip"),
+			archive.ArchiveItemFromBytes([]byte("MIT"), "tools/LICENSE")}}
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-nupkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := testPackage()
+	filename := filepath.Join(dir, p.Filename())
+	err = p.Write(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(filename) != "my-app.1.2.3.nupkg" {
+		t.Errorf("unexpected filename %s", filename)
+	}
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	contents := map[string]string{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[f.Name] = string(data)
+	}
+	for _, name := range []string{"my-app.nuspec", "_rels/.rels", "[Content_Types].xml", "tools/chocolateyinstall.ps1", "tools/LICENSE"} {
+		if _, exists := contents[name]; !exists {
+			t.Errorf("missing %s in %v", name, contents)
+		}
+	}
+	for name, content := range contents {
+		if strings.HasSuffix(name, ".nuspec") || strings.HasSuffix(name, ".rels") || strings.HasSuffix(name, ".psmdcp") || strings.HasSuffix(name, ".xml") {
+			if err := xml.Unmarshal([]byte(content), new(interface{})); err != nil {
+				t.Errorf("invalid XML in %s: %v\n%s", name, err, content)
+			}
+		}
+	}
+	var nuspec struct {
+		Metadata struct {
+			ID      string `xml:"id"`
+			Version string `xml:"version"`
+			Authors string `xml:"authors"`
+		} `xml:"metadata"`
+	}
+	err = xml.Unmarshal([]byte(contents["my-app.nuspec"]), &nuspec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nuspec.Metadata.ID != "my-app" || nuspec.Metadata.Version != "1.2.3" || nuspec.Metadata.Authors != "Me & Co" {
+		t.Errorf("unexpected nuspec %+v", nuspec)
+	}
+	types := contents["[Content_Types].xml"]
+	for _, expected := range []string{`Extension="ps1"`, `Extension="zip"`, `Extension="nuspec"`, `PartName="/tools/LICENSE"`} {
+		if !strings.Contains(types, expected) {
+			t.Errorf("expected %s in:\n%s", expected, types)
+		}
+	}
+	//the core properties part is referenced by the relationships
+	for name := range contents {
+		if strings.HasSuffix(name, ".psmdcp") && !strings.Contains(contents["_rels/.rels"], name) {
+			t.Errorf("%s is not referenced in:\n%s", name, contents["_rels/.rels"])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	p := testPackage()
+	p.Description = ""
+	if _, err := p.Items(); err == nil || !strings.Contains(err.Error(), "description") {
+		t.Errorf("expected an error for a missing description, got %v", err)
+	}
+	p = testPackage()
+	p.Files = append(p.Files, archive.ArchiveItemFromBytes([]byte{}, "_rels/x.rels"))
+	if _, err := p.Items(); err == nil {
+		t.Errorf("expected an error for a reserved path")
+	}
+	for version, valid := range map[string]bool{"1.2.3": true, "1.2.3.4-beta.1+abc": true, "snapshot": false, "1.2-": false, "1.2.3.4.5": false} {
+		p = testPackage()
+		p.Version = version
+		if _, err := p.Items(); (err == nil) != valid {
+			t.Errorf("version %s: expected valid=%v, got %v", version, valid, err)
+		}
+	}
+}
+
+func TestModified(t *testing.T) {
+	p := testPackage()
+	p.Modified = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	items, err := p.Items()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if !item.ModTime.Equal(p.Modified) {
+			t.Errorf("%s: expected %v, got %v", item.ArchivePath, p.Modified, item.ModTime)
+		}
+	}
+}
+
+func TestChocolateyScript(t *testing.T) {
+	embedded, err := ChocolateyInstall{Zip64: &ChocolateyZip{Embedded: "app's_windows_amd64.zip"}}.Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(embedded)
+	for _, expected := range []string{"Get-ChocolateyUnzip @packageArgs", "FileFullPath64 = Join-Path $toolsDir 'app''s_windows_amd64.zip'"} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %s in:\n%s", expected, s)
+		}
+	}
+	if strings.Contains(s, "FileFullPath   =") {
+		t.Errorf("unexpected 32-bit zip in:\n%s", s)
+	}
+	referenced, err := ChocolateyInstall{
+		Zip32: &ChocolateyZip{URL: "https://example.com/app_386.zip", Sha256: "aa"},
+		Zip64: &ChocolateyZip{URL: "https://example.com/app_amd64.zip", Sha256: "bb"}}.Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = string(referenced)
+	for _, expected := range []string{"Install-ChocolateyZipPackage @packageArgs", "Url            = 'https://example.com/app_386.zip'", "Checksum64     = 'bb'"} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %s in:\n%s", expected, s)
+		}
+	}
+	if _, err := (ChocolateyInstall{Zip32: &ChocolateyZip{Embedded: "a.zip"}, Zip64: &ChocolateyZip{URL: "u", Sha256: "s"}}).Script(); err == nil {
+		t.Errorf("expected an error for mixed embedded & referenced zips")
+	}
+	if _, err := (ChocolateyInstall{}).Script(); err == nil {
+		t.Errorf("expected an error without zips")
+	}
+}
@@ -0,0 +1,361 @@
+// OCI container images, written as an image layout without a container runtime
+package oci
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	MEDIA_TYPE_INDEX    = "application/vnd.oci.image.index.v1+json"
+	MEDIA_TYPE_MANIFEST = "application/vnd.oci.image.manifest.v1+json"
+	MEDIA_TYPE_CONFIG   = "application/vnd.oci.image.config.v1+json"
+	MEDIA_TYPE_LAYER    = "application/vnd.oci.image.layer.v1.tar+gzip"
+	//annotation for the tag of an image in a layout
+	ANNOTATION_REF_NAME = "org.opencontainers.image.ref.name"
+)
+
+// A file in the image's filesystem
+type File struct {
+	//absolute path inside the image, e.g. '/usr/local/bin/app'
+	Path string
+	//path on the build host. Ignored if Data is set
+	Source string
+	Data   []byte
+	Mode   int64
+}
+
+// The platform an image runs on, e.g. linux/arm/v7
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// Runtime configuration
+type Config struct {
+	User       string            `json:"User,omitempty"`
+	Env        []string          `json:"Env,omitempty"`
+	Entrypoint []string          `json:"Entrypoint,omitempty"`
+	Cmd        []string          `json:"Cmd,omitempty"`
+	WorkingDir string            `json:"WorkingDir,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty"`
+}
+
+// A single-layer image for one platform
+type Image struct {
+	Platform Platform
+	Config   Config
+	Files    []File
+	//timestamps for the config and the layer's files, for reproducible images
+	Created time.Time
+}
+
+// A content descriptor, referencing a blob by digest
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *Platform         `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type imageConfig struct {
+	Created      string   `json:"created"`
+	Architecture string   `json:"architecture"`
+	OS           string   `json:"os"`
+	Variant      string   `json:"variant,omitempty"`
+	Config       Config   `json:"config"`
+	RootFS       rootFS   `json:"rootfs"`
+	History      []record `json:"history"`
+}
+
+type rootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+type record struct {
+	Created   string `json:"created"`
+	CreatedBy string `json:"created_by"`
+}
+
+type manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	Config        *Descriptor       `json:"config,omitempty"`
+	Layers        []Descriptor      `json:"layers,omitempty"`
+	Manifests     []Descriptor      `json:"manifests,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// An OCI image layout: content-addressed blobs, plus a multi-platform index of the images added to it
+type Layout struct {
+	blobs  map[string][]byte
+	images []Descriptor
+	//for the layout's own files
+	modTime time.Time
+}
+
+func NewLayout(modTime time.Time) *Layout {
+	return &Layout{blobs: map[string][]byte{}, modTime: modTime}
+}
+
+// Stores a blob, returning its descriptor
+func (l *Layout) addBlob(mediaType string, data []byte) Descriptor {
+	digest := Digest(data)
+	l.blobs[digest] = data
+	return Descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(data))}
+}
+
+// Adds an image (its layer, config and manifest) to the layout's index
+func (l *Layout) AddImage(img Image) (Descriptor, error) {
+	for _, existing := range l.images {
+		if *existing.Platform == img.Platform {
+			return Descriptor{}, fmt.Errorf("Duplicate image platform %s/%s", img.Platform.OS, img.Platform.Architecture)
+		}
+	}
+	layerTar, err := img.layer()
+	if err != nil {
+		return Descriptor{}, err
+	}
+	layerGz, err := gzipped(layerTar)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	created := img.Created.UTC().Format(time.RFC3339)
+	config := imageConfig{
+		Created:      created,
+		Architecture: img.Platform.Architecture,
+		OS:           img.Platform.OS,
+		Variant:      img.Platform.Variant,
+		Config:       img.Config,
+		RootFS:       rootFS{"layers", []string{Digest(layerTar)}},
+		History:      []record{{created, "goxc"}}}
+	configJson, err := json.Marshal(config)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	configDesc := l.addBlob(MEDIA_TYPE_CONFIG, configJson)
+	m := manifest{
+		SchemaVersion: 2,
+		MediaType:     MEDIA_TYPE_MANIFEST,
+		Config:        &configDesc,
+		Layers:        []Descriptor{l.addBlob(MEDIA_TYPE_LAYER, layerGz)}}
+	manifestJson, err := json.Marshal(m)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	desc := l.addBlob(MEDIA_TYPE_MANIFEST, manifestJson)
+	platform := img.Platform
+	desc.Platform = &platform
+	l.images = append(l.images, desc)
+	return desc, nil
+}
+
+// The layout's files: 'oci-layout', 'index.json' (referencing the multi-platform index, tagged 'tag') and 'blobs/'.
+func (l *Layout) Files(tag string) (map[string][]byte, error) {
+	if len(l.images) == 0 {
+		return nil, errors.New("No images in the OCI layout")
+	}
+	images := append([]Descriptor{}, l.images...)
+	sort.Slice(images, func(i, j int) bool {
+		return platformString(*images[i].Platform) < platformString(*images[j].Platform)
+	})
+	indexJson, err := json.Marshal(manifest{SchemaVersion: 2, MediaType: MEDIA_TYPE_INDEX, Manifests: images})
+	if err != nil {
+		return nil, err
+	}
+	indexDesc := l.addBlob(MEDIA_TYPE_INDEX, indexJson)
+	if tag != "" {
+		indexDesc.Annotations = map[string]string{ANNOTATION_REF_NAME: tag}
+	}
+	topJson, err := json.Marshal(manifest{SchemaVersion: 2, MediaType: MEDIA_TYPE_INDEX, Manifests: []Descriptor{indexDesc}})
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{
+		"oci-layout": []byte(`{"imageLayoutVersion":"1.0.0"}`),
+		"index.json": topJson}
+	for digest, data := range l.blobs {
+		files["blobs/sha256/"+strings.TrimPrefix(digest, "sha256:")] = data
+	}
+	return files, nil
+}
+
+// Writes the layout into a directory
+func (l *Layout) WriteDir(dir, tag string) error {
+	files, err := l.Files(tag)
+	if err != nil {
+		return err
+	}
+	for name, data := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(filename), 0755)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filename, data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes the layout as a tarball (an 'oci-archive')
+func (l *Layout) WriteTar(w io.Writer, tag string) error {
+	files, err := l.Files(tag)
+	if err != nil {
+		return err
+	}
+	names := []string{"blobs/", "blobs/sha256/"}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		h := &tar.Header{Name: name, ModTime: l.modTime, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(name, "/") {
+			h.Mode = 0755
+			h.Typeflag = tar.TypeDir
+		}
+		err = tw.WriteHeader(h)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(files[name])
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// The layer, as an uncompressed tar. Parent directories are added, and entries are sorted
+func (img Image) layer() ([]byte, error) {
+	files := map[string]File{}
+	dirs := map[string]bool{}
+	for _, f := range img.Files {
+		p := path.Clean("/" + f.Path)
+		if p == "/" {
+			return nil, errors.New("Invalid image file path: " + f.Path)
+		}
+		if _, exists := files[p]; exists {
+			return nil, errors.New("Duplicate image file path: " + p)
+		}
+		files[p] = f
+		for d := path.Dir(p); d != "/"; d = path.Dir(d) {
+			dirs[d] = true
+		}
+	}
+	names := []string{}
+	for p := range files {
+		names = append(names, p)
+	}
+	for d := range dirs {
+		if _, exists := files[d]; exists {
+			return nil, errors.New("Image file path is also a directory: " + d)
+		}
+		names = append(names, d)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, p := range names {
+		//tar paths are relative to the root
+		h := &tar.Header{Name: strings.TrimPrefix(p, "/"), ModTime: img.Created, Uname: "root", Gname: "root"}
+		f, isFile := files[p]
+		if !isFile {
+			h.Name += "/"
+			h.Typeflag = tar.TypeDir
+			h.Mode = 0755
+			err := tw.WriteHeader(h)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		data := f.Data
+		if data == nil {
+			var err error
+			data, err = ioutil.ReadFile(f.Source)
+			if err != nil {
+				return nil, err
+			}
+		}
+		h.Typeflag = tar.TypeReg
+		h.Mode = f.Mode
+		if h.Mode == 0 {
+			h.Mode = 0644
+		}
+		h.Size = int64(len(data))
+		err := tw.WriteHeader(h)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tw.Write(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := tw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gzip without a timestamp or name, for reproducible layers
+func gzipped(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	_, err := gw.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = gw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// The 'sha256:...' digest of a blob
+func Digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+func platformString(p Platform) string {
+	return p.OS + "/" + p.Architecture + "/" + p.Variant
+}
@@ -0,0 +1,195 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLayout(t *testing.T) *Layout {
+	l := NewLayout(time.Unix(0, 0))
+	for _, arch := range []string{"arm64", "amd64"} {
+		_, err := l.AddImage(Image{
+			Platform: Platform{Architecture: arch, OS: "linux"},
+			Config:   Config{Entrypoint: []string{"/usr/local/bin/app"}, Labels: map[string]string{"org.opencontainers.image.version": "1.0"}},
+			Files: []File{
+				{Path: "/usr/local/bin/app", Data: []byte("binary-" + arch), Mode: 0755},
+				{Path: "etc/ssl/certs/ca-certificates.crt", Data: []byte("certs")}},
+			Created: time.Unix(1, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l
+}
+
+func readTar(t *testing.T, data []byte) map[string][]byte {
+	files := map[string][]byte{}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[h.Name] = content
+	}
+	return files
+}
+
+func blob(t *testing.T, files map[string][]byte, d Descriptor) []byte {
+	data, exists := files["blobs/sha256/"+strings.TrimPrefix(d.Digest, "sha256:")]
+	if !exists {
+		t.Fatalf("missing blob %s", d.Digest)
+	}
+	if Digest(data) != d.Digest || int64(len(data)) != d.Size {
+		t.Fatalf("blob %s does not match its descriptor", d.Digest)
+	}
+	return data
+}
+
+func TestWriteTar(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := testLayout(t).WriteTar(buf, "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readTar(t, buf.Bytes())
+	if string(files["oci-layout"]) != `{"imageLayoutVersion":"1.0.0"}` {
+		t.Errorf("unexpected oci-layout %s", files["oci-layout"])
+	}
+	var top manifest
+	err = json.Unmarshal(files["index.json"], &top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top.Manifests) != 1 || top.Manifests[0].MediaType != MEDIA_TYPE_INDEX || top.Manifests[0].Annotations[ANNOTATION_REF_NAME] != "1.0" {
+		t.Fatalf("unexpected index.json %s", files["index.json"])
+	}
+	var index manifest
+	err = json.Unmarshal(blob(t, files, top.Manifests[0]), &index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 2 || index.Manifests[0].Platform.Architecture != "amd64" || index.Manifests[1].Platform.Architecture != "arm64" {
+		t.Fatalf("unexpected image index %+v", index)
+	}
+	var m manifest
+	err = json.Unmarshal(blob(t, files, index.Manifests[1]), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config imageConfig
+	err = json.Unmarshal(blob(t, files, *m.Config), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Architecture != "arm64" || config.OS != "linux" || config.Config.Entrypoint[0] != "/usr/local/bin/app" || config.Created != "1970-01-01T00:00:01Z" {
+		t.Errorf("unexpected config %+v", config)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(blob(t, files, m.Layers[0])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layerTar, err := ioutil.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.RootFS.DiffIDs[0] != Digest(layerTar) {
+		t.Errorf("diff_id does not match the uncompressed layer")
+	}
+	layer := readTar(t, layerTar)
+	if string(layer["usr/local/bin/app"]) != "binary-arm64" || string(layer["etc/ssl/certs/ca-certificates.crt"]) != "certs" {
+		t.Errorf("unexpected layer files %v", layer)
+	}
+	for _, dir := range []string{"usr/", "usr/local/", "usr/local/bin/", "etc/", "etc/ssl/", "etc/ssl/certs/"} {
+		if _, exists := layer[dir]; !exists {
+			t.Errorf("missing directory %s in layer", dir)
+		}
+	}
+	//reproducible
+	buf2 := &bytes.Buffer{}
+	err = testLayout(t).WriteTar(buf2, "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Errorf("layout is not reproducible")
+	}
+}
+
+func TestAddImageErrors(t *testing.T) {
+	l := testLayout(t)
+	if _, err := l.AddImage(Image{Platform: Platform{Architecture: "amd64", OS: "linux"}}); err == nil {
+		t.Errorf("expected an error for a duplicate platform")
+	}
+	files := []File{{Path: "/a", Data: []byte{}}, {Path: "/a/b", Data: []byte{}}}
+	if _, err := l.AddImage(Image{Platform: Platform{Architecture: "386", OS: "linux"}, Files: files}); err == nil {
+		t.Errorf("expected an error for a file which is also a directory")
+	}
+	if _, err := NewLayout(time.Unix(0, 0)).Files(""); err == nil {
+		t.Errorf("expected an error for an empty layout")
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-oci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l := testLayout(t)
+	err = l.WriteDir(filepath.Join(dir, "layout"), "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "layout.tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.WriteTar(f, "1.0")
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := l.Files("1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"layout", "layout.tar"} {
+		files, err := ReadFiles(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("%s: unexpected files %v", name, files)
+		}
+		index, err := Index(files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := Blob(files, index[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs, err := References(data)
+		if err != nil || len(refs) != 2 || refs[0].MediaType != MEDIA_TYPE_MANIFEST {
+			t.Errorf("unexpected references %v (%v)", refs, err)
+		}
+	}
+}
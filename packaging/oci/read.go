@@ -0,0 +1,114 @@
+package oci
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reads a layout written by WriteTar (a file) or WriteDir (a directory), as returned by Files
+func ReadFiles(layoutPath string) (map[string][]byte, error) {
+	fi, err := os.Stat(layoutPath)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	if fi.IsDir() {
+		err = filepath.Walk(layoutPath, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(layoutPath, p)
+			if err != nil {
+				return err
+			}
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(rel)] = data
+			return nil
+		})
+		return files, err
+	}
+	f, err := os.Open(layoutPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[strings.TrimPrefix(h.Name, "./")] = data
+	}
+	return files, nil
+}
+
+// The descriptors in a layout's 'index.json'
+func Index(files map[string][]byte) ([]Descriptor, error) {
+	data, exists := files["index.json"]
+	if !exists {
+		return nil, fmt.Errorf("Not an OCI layout: missing index.json")
+	}
+	return References(data)
+}
+
+// The content of a descriptor's blob, verified against its digest
+func Blob(files map[string][]byte, d Descriptor) ([]byte, error) {
+	data, exists := files["blobs/sha256/"+strings.TrimPrefix(d.Digest, "sha256:")]
+	if !exists {
+		return nil, fmt.Errorf("Missing blob %s in OCI layout", d.Digest)
+	}
+	if Digest(data) != d.Digest || int64(len(data)) != d.Size {
+		return nil, fmt.Errorf("Blob %s does not match its descriptor", d.Digest)
+	}
+	return data, nil
+}
+
+// The blobs referenced by an image index (its manifests) or a manifest (its config and layers)
+func References(data []byte) ([]Descriptor, error) {
+	var m manifest
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+	refs := append([]Descriptor{}, m.Manifests...)
+	if m.Config != nil {
+		refs = append(refs, *m.Config)
+	}
+	return append(refs, m.Layers...), nil
+}
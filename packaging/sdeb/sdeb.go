@@ -31,7 +31,19 @@ import (
 )
 
 //TODO: unfinished: need to discover root dir to determine which dirs to pre-make.
+// For Go modules, sources are laid out relative to the module root (including go.mod & go.sum), rather than the GOPATH element.
 func SdebGetSourcesAsArchiveItems(codeDir, prefix string) ([]archive.ArchiveItem, error) {
+	if moduleRoot := core.FindModuleRoot(codeDir); moduleRoot != "" {
+		log.Printf("Code dir %s (using module root %s)", codeDir, moduleRoot)
+		sources := []archive.ArchiveItem{}
+		for _, modFile := range []string{core.GOMOD_FILENAME, "go.sum"} {
+			if exists, _ := core.FileExists(filepath.Join(moduleRoot, modFile)); exists {
+				sources = append(sources, archive.ArchiveItemFromFileSystem(filepath.Join(moduleRoot, modFile), filepath.Join(prefix, modFile)))
+			}
+		}
+		items, err := sdebGetSourcesAsArchiveItems(moduleRoot, codeDir, prefix)
+		return append(sources, items...), err
+	}
 	goPathRoot := core.GetGoPathElement(codeDir)
 	goPathRootResolved, err := filepath.EvalSymlinks(goPathRoot)
 	if err != nil {
@@ -65,6 +77,10 @@ func sdebGetSourcesAsArchiveItems(goPathRoot, codeDir, prefix string) ([]archive
 	fis, err := ioutil.ReadDir(codeDir)
 	for _, fi := range fis {
 		if fi.IsDir() && fi.Name() != DIRNAME_TEMP {
+			if isNested, _ := core.FileExists(filepath.Join(codeDir, fi.Name(), core.GOMOD_FILENAME)); isNested {
+				//nested modules are separate source packages
+				continue
+			}
 			additionalItems, err := sdebGetSourcesAsArchiveItems(goPathRoot, filepath.Join(codeDir, fi.Name()), prefix)
 			sources = append(sources, additionalItems...)
 			if err != nil {
@@ -0,0 +1,162 @@
+// Windows installers: WiX sources (for building an '.msi') and NSIS scripts (for building a setup '.exe')
+package wininstaller
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// namespace for the name-based GUIDs generated by goxc
+var guidNamespace = []byte{0x6b, 0x1d, 0x3f, 0x2e, 0x8a, 0x54, 0x4c, 0x1b, 0x9e, 0x07, 0x5d, 0x2f, 0x61, 0xa3, 0xc4, 0x90}
+
+// Installer metadata and contents
+type Installer struct {
+	//product name, also used for the install directory and the Start menu folder
+	Name         string
+	Version      string
+	Manufacturer string
+	Description  string
+	//goxc arch, i.e. 386, amd64 or arm64
+	Arch string
+	//identifies the product across versions. Defaults to a GUID derived from Name
+	UpgradeCode string
+	AddToPath   bool
+	//executable (a file Target) to create a Start menu shortcut for. Empty for no shortcut
+	Shortcut string
+	Files    []File
+}
+
+// A file to install
+type File struct {
+	//path on the build host
+	Source string
+	//slash-separated path, relative to the install directory
+	Target string
+}
+
+// A name-based (version 5) GUID, so that the same name always gives the same GUID
+func GUID(name string) string {
+	h := sha1.New()
+	h.Write(guidNamespace)
+	h.Write([]byte(name))
+	b := h.Sum(nil)[:16]
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
+
+var msiVersionRegexp = regexp.MustCompile(`^\d+(\.\d+){0,2}`)
+
+// MSI versions are numeric: major.minor.build. Anything else (e.g. 'snapshot', or a '-rc1' suffix) is dropped.
+func MsiVersion(version string) string {
+	v := msiVersionRegexp.FindString(strings.TrimPrefix(version, "v"))
+	if v == "" {
+		return "0.0.0"
+	}
+	return v
+}
+
+func (i Installer) validate() error {
+	if i.Name == "" {
+		return errors.New("Installer name is required")
+	}
+	if len(i.Files) == 0 {
+		return errors.New("Installer has no files")
+	}
+	if !IsSupportedArch(i.Arch) {
+		return fmt.Errorf("Unsupported installer arch '%s'", i.Arch)
+	}
+	return nil
+}
+
+// Whether installers can be built for a Windows arch
+func IsSupportedArch(arch string) bool {
+	switch arch {
+	case "386", "amd64", "arm64":
+		return true
+	}
+	return false
+}
+
+func (i Installer) upgradeCode() string {
+	if i.UpgradeCode != "" {
+		return strings.ToUpper(i.UpgradeCode)
+	}
+	return GUID(i.Name)
+}
+
+// the install directory's files and subdirectories
+type dir struct {
+	Name  string
+	Path  string
+	Files []File
+	Dirs  []*dir
+}
+
+func (i Installer) tree() *dir {
+	root := &dir{Path: "."}
+	dirs := map[string]*dir{".": root}
+	var getDir func(p string) *dir
+	getDir = func(p string) *dir {
+		if d, exists := dirs[p]; exists {
+			return d
+		}
+		d := &dir{Name: path.Base(p), Path: p}
+		parent := getDir(path.Dir(p))
+		parent.Dirs = append(parent.Dirs, d)
+		dirs[p] = d
+		return d
+	}
+	files := append([]File{}, i.Files...)
+	sort.Slice(files, func(a, b int) bool { return files[a].Target < files[b].Target })
+	for _, f := range files {
+		f.Target = path.Clean(f.Target)
+		d := getDir(path.Dir(f.Target))
+		d.Files = append(d.Files, f)
+	}
+	return root
+}
+
+func execute(name, text string, funcs template.FuncMap, data interface{}) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func escapeXml(s string) string {
+	buf := &bytes.Buffer{}
+	//writes to a bytes.Buffer never fail
+	_ = xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,141 @@
+package wininstaller
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"path"
+	"strings"
+	"text/template"
+)
+
+const nsiTemplate = `Unicode true
+!include "WinMessages.nsh"
+!include "StrFunc.nsh"
+${UnStrRep}
+
+!define ENVIRONMENT_KEY "SYSTEM\CurrentControlSet\Control\Session Manager\Environment"
+!define UNINSTALL_KEY "Software\Microsoft\Windows\CurrentVersion\Uninstall\{{"{"}}{{.UpgradeCode}}{{"}"}}"
+
+Name "{{nsis .Name}}"
+OutFile "{{nsis .OutFile}}"
+InstallDir "{{.ProgramFiles}}\{{nsis .Name}}"
+RequestExecutionLevel admin
+VIProductVersion "{{.ProductVersion}}"
+VIAddVersionKey "ProductName" "{{nsis .Name}}"
+VIAddVersionKey "ProductVersion" "{{nsis .Version}}"
+VIAddVersionKey "FileVersion" "{{nsis .Version}}"
+VIAddVersionKey "FileDescription" "{{nsis .Description}}"
+VIAddVersionKey "CompanyName" "{{nsis .Manufacturer}}"
+
+Page directory
+Page instfiles
+UninstPage uninstConfirm
+UninstPage instfiles
+
+Section "Install"
+{{- range .Dirs}}
+	SetOutPath "{{.}}"
+{{- end}}
+{{- range .Files}}
+	File "/oname={{nsis (windowsPath .Target)}}" "{{nsis .Source}}"
+{{- end}}
+	WriteUninstaller "$INSTDIR\uninstall.exe"
+{{- if .Shortcut}}
+	CreateDirectory "$SMPROGRAMS\{{nsis .Name}}"
+	CreateShortcut "$SMPROGRAMS\{{nsis .Name}}\{{nsis .Name}}.lnk" "$INSTDIR\{{nsis (windowsPath .Shortcut)}}"
+{{- end}}
+{{- if .AddToPath}}
+	ReadRegStr $0 HKLM "${ENVIRONMENT_KEY}" "Path"
+	WriteRegExpandStr HKLM "${ENVIRONMENT_KEY}" "Path" "$0;$INSTDIR"
+	SendMessage ${HWND_BROADCAST} ${WM_WININICHANGE} 0 "STR:Environment" /TIMEOUT=5000
+{{- end}}
+	WriteRegStr HKLM "${UNINSTALL_KEY}" "DisplayName" "{{nsis .Name}}"
+	WriteRegStr HKLM "${UNINSTALL_KEY}" "DisplayVersion" "{{nsis .Version}}"
+	WriteRegStr HKLM "${UNINSTALL_KEY}" "Publisher" "{{nsis .Manufacturer}}"
+	WriteRegStr HKLM "${UNINSTALL_KEY}" "UninstallString" '"$INSTDIR\uninstall.exe"'
+SectionEnd
+
+Section "Uninstall"
+{{- range .Files}}
+	Delete "$INSTDIR\{{nsis (windowsPath .Target)}}"
+{{- end}}
+	Delete "$INSTDIR\uninstall.exe"
+{{- range .ReverseDirs}}
+	RMDir "{{.}}"
+{{- end}}
+{{- if .Shortcut}}
+	Delete "$SMPROGRAMS\{{nsis .Name}}\{{nsis .Name}}.lnk"
+	RMDir "$SMPROGRAMS\{{nsis .Name}}"
+{{- end}}
+{{- if .AddToPath}}
+	ReadRegStr $0 HKLM "${ENVIRONMENT_KEY}" "Path"
+	${UnStrRep} $0 $0 ";$INSTDIR" ""
+	WriteRegExpandStr HKLM "${ENVIRONMENT_KEY}" "Path" "$0"
+	SendMessage ${HWND_BROADCAST} ${WM_WININICHANGE} 0 "STR:Environment" /TIMEOUT=5000
+{{- end}}
+	DeleteRegKey HKLM "${UNINSTALL_KEY}"
+SectionEnd
+`
+
+// The NSIS script, which 'makensis' compiles into a setup program written to outFile.
+// Files are installed using '/oname', so SetOutPath is only used to create their directories.
+func (i Installer) Nsi(outFile string) ([]byte, error) {
+	err := i.validate()
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{}
+	var addDirs func(d *dir)
+	addDirs = func(d *dir) {
+		dirs = append(dirs, strings.TrimSuffix(`$INSTDIR\`+nsisEscape(windowsPath(d.Path)), `\.`))
+		for _, sub := range d.Dirs {
+			addDirs(sub)
+		}
+	}
+	addDirs(i.tree())
+	reverseDirs := []string{}
+	for j := len(dirs) - 1; j >= 0; j-- {
+		reverseDirs = append(reverseDirs, dirs[j])
+	}
+	//the root is last, so that files go there by default
+	dirs = append(dirs[1:], dirs[0])
+	files := append([]File{}, i.Files...)
+	for j := range files {
+		files[j].Target = path.Clean(files[j].Target)
+	}
+	programFiles := "$PROGRAMFILES64"
+	if i.Arch == "386" {
+		programFiles = "$PROGRAMFILES"
+	}
+	params := struct {
+		Installer
+		OutFile        string
+		UpgradeCode    string
+		ProductVersion string
+		ProgramFiles   string
+		Dirs           []string
+		ReverseDirs    []string
+	}{i, outFile, i.upgradeCode(), MsiVersion(i.Version) + strings.Repeat(".0", 3-strings.Count(MsiVersion(i.Version), ".")), programFiles, dirs, reverseDirs}
+	params.Files = files
+	funcs := template.FuncMap{"nsis": nsisEscape, "windowsPath": windowsPath}
+	return execute("nsi", nsiTemplate, funcs, params)
+}
+
+// NSIS strings escape quotes, newlines and '$' (which starts a variable) with '$'
+func nsisEscape(s string) string {
+	return strings.NewReplacer(`$`, `$$`, `"`, `$\"`, "\n", `$\n`).Replace(s)
+}
@@ -0,0 +1,108 @@
+package wininstaller
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func testInstaller() Installer {
+	return Installer{
+		Name:         "Hello & Co",
+		Version:      "1.2.3-rc1",
+		Manufacturer: "Example",
+		Description:  "Says hello",
+		Arch:         "amd64",
+		AddToPath:    true,
+		Shortcut:     "hello.exe",
+		Files: []File{
+			{Source: "/build/hello.exe", Target: "hello.exe"},
+			{Source: "/src/docs/guide.txt", Target: "docs/guide.txt"},
+			{Source: "/src/README.md", Target: "README.md"}}}
+}
+
+func TestGUID(t *testing.T) {
+	g := GUID("hello")
+	if g != GUID("hello") {
+		t.Errorf("GUID is not deterministic")
+	}
+	if g == GUID("goodbye") {
+		t.Errorf("different names gave the same GUID")
+	}
+	if !regexp.MustCompile(`^[0-9A-F]{8}-[0-9A-F]{4}-5[0-9A-F]{3}-[89AB][0-9A-F]{3}-[0-9A-F]{12}$`).MatchString(g) {
+		t.Errorf("unexpected GUID format %s", g)
+	}
+}
+
+func TestMsiVersion(t *testing.T) {
+	for in, expected := range map[string]string{"1.2.3": "1.2.3", "v1.2": "1.2", "1.2.3-rc1": "1.2.3", "1.2.3.4": "1.2.3", "snapshot": "0.0.0"} {
+		if actual := MsiVersion(in); actual != expected {
+			t.Errorf("MsiVersion(%q) = %q, expected %q", in, actual, expected)
+		}
+	}
+}
+
+func TestWxs(t *testing.T) {
+	wxs, err := testInstaller().Wxs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	elements := map[string]int{}
+	decoder := xml.NewDecoder(bytes.NewReader(wxs))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, wxs)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			elements[start.Name.Local]++
+		}
+	}
+	//the 3 files, plus PATH and the shortcut
+	if elements["Component"] != 5 || elements["ComponentRef"] != 5 || elements["File"] != 3 {
+		t.Errorf("unexpected components: %v\n%s", elements, wxs)
+	}
+	for _, expected := range []string{`Name="Hello &amp; Co"`, `Version="1.2.3"`, `UpgradeCode="` + GUID("Hello & Co") + `"`, `Platform="x64"`, `Name="docs"`, `Target="[INSTALLDIR]hello.exe"`, `Part="last"`} {
+		if !bytes.Contains(wxs, []byte(expected)) {
+			t.Errorf("expected %s in:\n%s", expected, wxs)
+		}
+	}
+}
+
+func TestNsi(t *testing.T) {
+	i := testInstaller()
+	i.Arch = "386"
+	nsi, err := i.Nsi("hello-setup.exe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(nsi)
+	for _, expected := range []string{`OutFile "hello-setup.exe"`, `InstallDir "$PROGRAMFILES\Hello & Co"`, `File "/oname=docs\guide.txt" "/src/docs/guide.txt"`, `RMDir "$INSTDIR\docs"`, `VIProductVersion "1.2.3.0"`, `${UnStrRep} $0 $0 ";$INSTDIR" ""`, GUID("Hello & Co")} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %s in:\n%s", expected, s)
+		}
+	}
+	//files default to the install dir, so it's the last SetOutPath
+	if strings.LastIndex(s, `SetOutPath "$INSTDIR"`) < strings.LastIndex(s, `SetOutPath "$INSTDIR\docs"`) {
+		t.Errorf("unexpected SetOutPath order:\n%s", s)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	i := testInstaller()
+	i.Arch = "arm"
+	if _, err := i.Wxs(); err == nil {
+		t.Errorf("expected an error for an unsupported arch")
+	}
+	i = testInstaller()
+	i.Files = nil
+	if _, err := i.Nsi("x.exe"); err == nil {
+		t.Errorf("expected an error for an installer without files")
+	}
+}
@@ -0,0 +1,153 @@
+package wininstaller
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// WiX v3 source, kept to the subset which msitools' 'wixl' supports
+const wxsTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<Wix xmlns="http://schemas.microsoft.com/wix/2006/wi">
+	<Product Id="{{.ProductCode}}" Name="{{xml .Name}}" Language="1033" Version="{{.MsiVersion}}" Manufacturer="{{xml .Manufacturer}}" UpgradeCode="{{.UpgradeCode}}">
+		<Package InstallerVersion="500" Compressed="yes" InstallScope="perMachine" Platform="{{.Platform}}" Description="{{xml .Description}}" Manufacturer="{{xml .Manufacturer}}"/>
+		<MajorUpgrade DowngradeErrorMessage="A newer version of [ProductName] is already installed."/>
+		<Media Id="1" Cabinet="product.cab" EmbedCab="yes"/>
+		<Directory Id="TARGETDIR" Name="SourceDir">
+			<Directory Id="{{.ProgramFilesFolder}}">
+				<Directory Id="INSTALLDIR" Name="{{xml .Name}}">
+{{- template "dir" .Tree}}
+{{- if .AddToPath}}
+					<Component Id="EnvironmentPath" Guid="{{guid "PATH"}}"{{if .Win64}} Win64="yes"{{end}}>
+						<CreateFolder/>
+						<Environment Id="PATH" Name="PATH" Value="[INSTALLDIR]" Permanent="no" Part="last" Action="set" System="yes"/>
+					</Component>
+{{- end}}
+				</Directory>
+			</Directory>
+{{- if .Shortcut}}
+			<Directory Id="ProgramMenuFolder">
+				<Directory Id="ProgramMenuDir" Name="{{xml .Name}}">
+					<Component Id="StartMenuShortcut" Guid="{{guid "Shortcut"}}">
+						<Shortcut Id="ApplicationShortcut" Name="{{xml .Name}}" Target="[INSTALLDIR]{{xml (windowsPath .Shortcut)}}" WorkingDirectory="INSTALLDIR"/>
+						<RemoveFolder Id="ProgramMenuDir" On="uninstall"/>
+						<RegistryValue Root="HKCU" Key="Software\{{xml .Name}}" Name="installed" Type="integer" Value="1" KeyPath="yes"/>
+					</Component>
+				</Directory>
+			</Directory>
+{{- end}}
+		</Directory>
+		<Feature Id="Complete" Level="1">
+{{- range .Components}}
+			<ComponentRef Id="{{.}}"/>
+{{- end}}
+		</Feature>
+	</Product>
+</Wix>
+{{define "dir"}}
+{{- range .Files}}
+					<Component Id="{{id "c" .Target}}" Guid="{{guid .Target}}"{{if $.Win64}} Win64="yes"{{end}}>
+						<File Id="{{id "f" .Target}}" Name="{{xml (base .Target)}}" Source="{{xml .Source}}" KeyPath="yes"/>
+					</Component>
+{{- end}}
+{{- range .Dirs}}
+					<Directory Id="{{id "d" .Path}}" Name="{{xml .Name}}">
+{{- template "dir" .}}
+					</Directory>
+{{- end}}
+{{- end}}
+`
+
+// wraps the directory tree, so that the recursive template can see Win64
+type wxsDir struct {
+	*dir
+	Win64 bool
+}
+
+func (d wxsDir) Dirs() []wxsDir {
+	ret := []wxsDir{}
+	for _, sub := range d.dir.Dirs {
+		ret = append(ret, wxsDir{sub, d.Win64})
+	}
+	return ret
+}
+
+// The WiX source, which 'wixl' (or WiX's candle & light) compiles into an '.msi'
+func (i Installer) Wxs() ([]byte, error) {
+	err := i.validate()
+	if err != nil {
+		return nil, err
+	}
+	guid := func(s string) string { return GUID(i.Name + "/" + i.Arch + "/" + s) }
+	id := func(prefix, s string) string { return fmt.Sprintf("%s%x", prefix, sha1.Sum([]byte(s))) }
+	tree := i.tree()
+	components := []string{}
+	var addComponents func(d *dir)
+	addComponents = func(d *dir) {
+		for _, f := range d.Files {
+			components = append(components, id("c", f.Target))
+		}
+		for _, sub := range d.Dirs {
+			addComponents(sub)
+		}
+	}
+	addComponents(tree)
+	if i.AddToPath {
+		components = append(components, "EnvironmentPath")
+	}
+	if i.Shortcut != "" {
+		components = append(components, "StartMenuShortcut")
+	}
+	params := struct {
+		Installer
+		ProductCode        string
+		UpgradeCode        string
+		MsiVersion         string
+		Platform           string
+		ProgramFilesFolder string
+		Win64              bool
+		Tree               wxsDir
+		Components         []string
+	}{Installer: i,
+		//a new product code for each version, as required for major upgrades
+		ProductCode: guid("Product/" + i.Version),
+		UpgradeCode: i.upgradeCode(),
+		MsiVersion:  MsiVersion(i.Version),
+		Platform:    i.WixlArch(),
+		Win64:       i.Arch != "386",
+		Components:  components}
+	params.ProgramFilesFolder = "ProgramFilesFolder"
+	if params.Win64 {
+		params.ProgramFilesFolder = "ProgramFiles64Folder"
+	}
+	params.Tree = wxsDir{tree, params.Win64}
+	funcs := template.FuncMap{"xml": escapeXml, "guid": guid, "id": id, "base": path.Base, "windowsPath": windowsPath}
+	return execute("wxs", wxsTemplate, funcs, params)
+}
+
+// The WiX platform name, as also used for 'wixl -a'
+func (i Installer) WixlArch() string {
+	return map[string]string{"386": "x86", "amd64": "x64", "arm64": "arm64"}[i.Arch]
+}
+
+func windowsPath(p string) string {
+	return strings.Replace(path.Clean(p), "/", `\`, -1)
+}
@@ -0,0 +1,141 @@
+// Scoop and winget manifests, for installing released Windows zips with a package manager
+package winmanifest
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+)
+
+// Package metadata, shared by the Scoop and winget manifests
+type Manifest struct {
+	Name        string
+	Version     string
+	Description string
+	Homepage    string
+	License     string
+	//executables inside the zips, e.g. 'app.exe'
+	Binaries []string
+	Archives []Archive
+}
+
+// A released zip for one arch
+type Archive struct {
+	//goxc arch
+	Arch   string
+	URL    string
+	Sha256 string
+	//top-level directory inside the zip. Empty if there's none
+	Dir string
+}
+
+// Scoop's 'checkver' and 'autoupdate'. Scoop only autoupdates when checkver is set
+type ScoopUpdate struct {
+	//e.g. "github", a regex matched against the homepage, or {"github": "https://github.com/owner/repo"}
+	Checkver interface{}
+	//adds an 'autoupdate' stanza, with the version in URLs replaced by '$version'
+	Autoupdate bool
+}
+
+type scoopArch struct {
+	URL        string `json:"url"`
+	Hash       string `json:"hash,omitempty"`
+	ExtractDir string `json:"extract_dir,omitempty"`
+}
+
+type scoopAutoupdate struct {
+	Architecture map[string]scoopArch `json:"architecture"`
+}
+
+type scoopManifest struct {
+	Version      string               `json:"version"`
+	Description  string               `json:"description,omitempty"`
+	Homepage     string               `json:"homepage,omitempty"`
+	License      string               `json:"license,omitempty"`
+	Architecture map[string]scoopArch `json:"architecture"`
+	Bin          []string             `json:"bin"`
+	Checkver     interface{}          `json:"checkver,omitempty"`
+	Autoupdate   *scoopAutoupdate     `json:"autoupdate,omitempty"`
+}
+
+var scoopArches = map[string]string{"386": "32bit", "amd64": "64bit", "arm64": "arm64"}
+
+// The Scoop app manifest (JSON)
+func (m Manifest) Scoop(update ScoopUpdate) ([]byte, error) {
+	err := m.validate()
+	if err != nil {
+		return nil, err
+	}
+	sm := scoopManifest{
+		Version:      m.Version,
+		Description:  m.Description,
+		Homepage:     m.Homepage,
+		License:      m.License,
+		Architecture: map[string]scoopArch{},
+		Bin:          m.Binaries,
+		Checkver:     update.Checkver}
+	if update.Autoupdate && update.Checkver != nil {
+		sm.Autoupdate = &scoopAutoupdate{map[string]scoopArch{}}
+	}
+	for _, a := range m.Archives {
+		scoopArchName, supported := scoopArches[a.Arch]
+		if !supported {
+			continue
+		}
+		sm.Architecture[scoopArchName] = scoopArch{URL: a.URL, Hash: a.Sha256, ExtractDir: a.Dir}
+		if sm.Autoupdate != nil {
+			sm.Autoupdate.Architecture[scoopArchName] = scoopArch{
+				URL:        strings.Replace(a.URL, m.Version, "$version", -1),
+				ExtractDir: strings.Replace(a.Dir, m.Version, "$version", -1)}
+		}
+	}
+	if len(sm.Architecture) == 0 {
+		return nil, errors.New("No windows archives for Scoop (386, amd64 or arm64)")
+	}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	//keep '&' in URLs readable
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	err = enc.Encode(sm)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m Manifest) validate() error {
+	if m.Name == "" {
+		return errors.New("Manifest name is required")
+	}
+	if m.Version == "" {
+		return errors.New("Manifest version is required")
+	}
+	if len(m.Binaries) == 0 {
+		return errors.New("Manifest has no binaries")
+	}
+	for _, bin := range m.Binaries {
+		if path.IsAbs(bin) {
+			return errors.New("Manifest binaries must be relative: " + bin)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package winmanifest
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const WINGET_MANIFEST_VERSION = "1.6.0"
+
+// winget-specific metadata
+type Winget struct {
+	//e.g. 'Publisher.AppName'
+	PackageIdentifier string
+	Publisher         string
+	Locale            string
+}
+
+const wingetVersionTemplate = `# yaml-language-server: $schema=https://aka.ms/winget-manifest.version.{{.ManifestVersion}}.schema.json
+PackageIdentifier: {{yaml .PackageIdentifier}}
+PackageVersion: {{yaml .Version}}
+DefaultLocale: {{yaml .Locale}}
+ManifestType: version
+ManifestVersion: {{.ManifestVersion}}
+`
+
+const wingetInstallerTemplate = `# yaml-language-server: $schema=https://aka.ms/winget-manifest.installer.{{.ManifestVersion}}.schema.json
+PackageIdentifier: {{yaml .PackageIdentifier}}
+PackageVersion: {{yaml .Version}}
+InstallerType: zip
+NestedInstallerType: portable
+Installers:
+{{- range .Installers}}
+- Architecture: {{.Architecture}}
+  InstallerUrl: {{yaml .URL}}
+  InstallerSha256: {{.Sha256}}
+  NestedInstallerFiles:
+{{- range .Files}}
+  - RelativeFilePath: {{yaml .RelativeFilePath}}
+    PortableCommandAlias: {{yaml .PortableCommandAlias}}
+{{- end}}
+{{- end}}
+ManifestType: installer
+ManifestVersion: {{.ManifestVersion}}
+`
+
+const wingetLocaleTemplate = `# yaml-language-server: $schema=https://aka.ms/winget-manifest.defaultLocale.{{.ManifestVersion}}.schema.json
+PackageIdentifier: {{yaml .PackageIdentifier}}
+PackageVersion: {{yaml .Version}}
+PackageLocale: {{yaml .Locale}}
+Publisher: {{yaml .Publisher}}
+PackageName: {{yaml .Name}}
+{{- if .Homepage}}
+PackageUrl: {{yaml .Homepage}}
+{{- end}}
+License: {{yaml .License}}
+ShortDescription: {{yaml .Description}}
+ManifestType: defaultLocale
+ManifestVersion: {{.ManifestVersion}}
+`
+
+var wingetArches = map[string]string{"386": "x86", "amd64": "x64", "arm": "arm", "arm64": "arm64"}
+
+type wingetFile struct {
+	RelativeFilePath     string
+	PortableCommandAlias string
+}
+
+type wingetInstaller struct {
+	Architecture string
+	URL          string
+	Sha256       string
+	Files        []wingetFile
+}
+
+// The manifests' directory in the winget-pkgs repository, e.g. 'manifests/p/Publisher/AppName/1.0.0'
+func (w Winget) Dir(version string) string {
+	return path.Join(append([]string{"manifests", strings.ToLower(w.PackageIdentifier[:1])}, append(strings.Split(w.PackageIdentifier, "."), version)...)...)
+}
+
+// The winget multi-file manifest (version, installer and default locale), keyed by filename
+func (m Manifest) Winget(w Winget) (map[string][]byte, error) {
+	err := m.validate()
+	if err != nil {
+		return nil, err
+	}
+	missing := []string{}
+	if w.PackageIdentifier == "" {
+		missing = append(missing, "package-identifier")
+	}
+	if w.Publisher == "" {
+		missing = append(missing, "publisher")
+	}
+	if m.License == "" {
+		missing = append(missing, "license")
+	}
+	if m.Description == "" {
+		missing = append(missing, "description")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("winget manifest fields missing (%v)", missing)
+	}
+	if w.Locale == "" {
+		w.Locale = "en-US"
+	}
+	installers := []wingetInstaller{}
+	for _, a := range m.Archives {
+		arch, supported := wingetArches[a.Arch]
+		if !supported {
+			continue
+		}
+		installer := wingetInstaller{Architecture: arch, URL: a.URL, Sha256: strings.ToUpper(a.Sha256)}
+		for _, bin := range m.Binaries {
+			relativePath := bin
+			if a.Dir != "" {
+				relativePath = a.Dir + "/" + bin
+			}
+			installer.Files = append(installer.Files, wingetFile{strings.Replace(relativePath, "/", `\`, -1), strings.TrimSuffix(path.Base(bin), ".exe")})
+		}
+		installers = append(installers, installer)
+	}
+	if len(installers) == 0 {
+		return nil, errors.New("No windows archives for winget")
+	}
+	sort.Slice(installers, func(i, j int) bool { return installers[i].Architecture < installers[j].Architecture })
+	params := struct {
+		Manifest
+		Winget
+		ManifestVersion string
+		Installers      []wingetInstaller
+	}{m, w, WINGET_MANIFEST_VERSION, installers}
+	files := map[string][]byte{}
+	for suffix, text := range map[string]string{
+		".yaml":                         wingetVersionTemplate,
+		".installer.yaml":               wingetInstallerTemplate,
+		".locale." + w.Locale + ".yaml": wingetLocaleTemplate} {
+		tmpl, err := template.New(suffix).Funcs(template.FuncMap{"yaml": yamlString}).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		err = tmpl.Execute(buf, params)
+		if err != nil {
+			return nil, err
+		}
+		files[w.PackageIdentifier+suffix] = buf.Bytes()
+	}
+	return files, nil
+}
+
+// a double-quoted YAML scalar. JSON strings are valid YAML
+func yamlString(s string) string {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	//encoding a string never fails
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,98 @@
+package winmanifest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testManifest() Manifest {
+	return Manifest{
+		Name:        "app",
+		Version:     "1.2.3",
+		Description: "Says hello",
+		Homepage:    "https://example.com",
+		License:     "MIT",
+		Binaries:    []string{"app.exe"},
+		Archives: []Archive{
+			{Arch: "amd64", URL: "https://example.com/v1.2.3/app_1.2.3_windows_amd64.zip?a=1&b=2", Sha256: "abcd", Dir: "app_1.2.3_windows_amd64"},
+			{Arch: "386", URL: "https://example.com/v1.2.3/app_1.2.3_windows_386.zip", Sha256: "ef01"}}}
+}
+
+func TestScoop(t *testing.T) {
+	b, err := testManifest().Scoop(ScoopUpdate{Checkver: map[string]string{"github": "https://github.com/me/app"}, Autoupdate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), `\u0026`) {
+		t.Errorf("unexpected escaping in:\n%s", b)
+	}
+	var parsed struct {
+		Version      string
+		Bin          []string
+		Architecture map[string]map[string]string
+		Checkver     map[string]string
+		Autoupdate   struct{ Architecture map[string]map[string]string }
+	}
+	err = json.Unmarshal(b, &parsed)
+	if err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, b)
+	}
+	if parsed.Version != "1.2.3" || parsed.Bin[0] != "app.exe" || parsed.Checkver["github"] == "" {
+		t.Errorf("unexpected manifest:\n%s", b)
+	}
+	if a := parsed.Architecture["64bit"]; a["hash"] != "abcd" || a["extract_dir"] != "app_1.2.3_windows_amd64" {
+		t.Errorf("unexpected 64bit architecture %v", a)
+	}
+	if a := parsed.Autoupdate.Architecture["64bit"]; a["url"] != "https://example.com/v$version/app_$version_windows_amd64.zip?a=1&b=2" || a["extract_dir"] != "app_$version_windows_amd64" {
+		t.Errorf("unexpected autoupdate %v", a)
+	}
+	if _, exists := parsed.Architecture["32bit"]; !exists {
+		t.Errorf("missing 32bit architecture:\n%s", b)
+	}
+	//no checkver, no autoupdate
+	b, err = testManifest().Scoop(ScoopUpdate{Autoupdate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "autoupdate") {
+		t.Errorf("unexpected autoupdate without checkver:\n%s", b)
+	}
+}
+
+func TestWinget(t *testing.T) {
+	w := Winget{PackageIdentifier: "Example.App", Publisher: "Example"}
+	files, err := testManifest().Winget(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+	installer := string(files["Example.App.installer.yaml"])
+	for _, expected := range []string{"- Architecture: x64", `InstallerUrl: "https://example.com/v1.2.3/app_1.2.3_windows_amd64.zip?a=1&b=2"`, "InstallerSha256: ABCD", `RelativeFilePath: "app_1.2.3_windows_amd64\\app.exe"`, `PortableCommandAlias: "app"`, "- Architecture: x86"} {
+		if !strings.Contains(installer, expected) {
+			t.Errorf("expected %s in:\n%s", expected, installer)
+		}
+	}
+	if strings.Index(installer, "x64") > strings.Index(installer, "x86") {
+		t.Errorf("unexpected installer order:\n%s", installer)
+	}
+	locale := string(files["Example.App.locale.en-US.yaml"])
+	for _, expected := range []string{`Publisher: "Example"`, `License: "MIT"`, "ManifestType: defaultLocale"} {
+		if !strings.Contains(locale, expected) {
+			t.Errorf("expected %s in:\n%s", expected, locale)
+		}
+	}
+	if !strings.Contains(string(files["Example.App.yaml"]), `DefaultLocale: "en-US"`) {
+		t.Errorf("unexpected version manifest:\n%s", files["Example.App.yaml"])
+	}
+	if dir := w.Dir("1.2.3"); dir != "manifests/e/Example/App/1.2.3" {
+		t.Errorf("unexpected dir %s", dir)
+	}
+	m := testManifest()
+	m.License = ""
+	if _, err := m.Winget(Winget{}); err == nil || !strings.Contains(err.Error(), "publisher") || !strings.Contains(err.Error(), "license") {
+		t.Errorf("expected an error listing the missing fields, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+// Windows resources (version info, icons, manifests), written as a COFF object ('.syso') for linking by 'go build'
+package winres
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+// Resource types
+const (
+	RT_ICON       = 3
+	RT_GROUP_ICON = 14
+	RT_VERSION    = 16
+	RT_MANIFEST   = 24
+)
+
+// en-US
+const LANG_EN_US = 0x0409
+
+const (
+	imageFileMachineI386  = 0x14c
+	imageFileMachineAmd64 = 0x8664
+	imageFileMachineArmNT = 0x1c4
+	imageFileMachineArm64 = 0xaa64
+
+	imageFileLineNumsStripped = 0x0004
+	imageFile32BitMachine     = 0x0100
+
+	imageScnCntInitializedData = 0x00000040
+	imageScnMemRead            = 0x40000000
+
+	imageSymClassStatic = 3
+
+	//offsets within the .rsrc section need an 'address relative to image base' relocation
+	imageRelI386Dir32NB   = 0x0007
+	imageRelAmd64Addr32NB = 0x0003
+	imageRelArmAddr32NB   = 0x0002
+	imageRelArm64Addr32NB = 0x0002
+
+	fileHeaderSize     = 20
+	sectionHeaderSize  = 40
+	relocationSize     = 10
+	directorySize      = 16
+	directoryEntrySize = 8
+	dataEntrySize      = 16
+
+	subdirectoryFlag = 0x80000000
+)
+
+type machine struct {
+	id              uint16
+	relocationType  uint16
+	characteristics uint16
+}
+
+var machines = map[string]machine{
+	"386":   {imageFileMachineI386, imageRelI386Dir32NB, imageFileLineNumsStripped | imageFile32BitMachine},
+	"amd64": {imageFileMachineAmd64, imageRelAmd64Addr32NB, imageFileLineNumsStripped},
+	"arm":   {imageFileMachineArmNT, imageRelArmAddr32NB, imageFileLineNumsStripped | imageFile32BitMachine},
+	"arm64": {imageFileMachineArm64, imageRelArm64Addr32NB, imageFileLineNumsStripped},
+}
+
+// Whether a .syso can be generated for the given GOARCH
+func IsSupportedArch(arch string) bool {
+	_, ok := machines[arch]
+	return ok
+}
+
+type resource struct {
+	typeID uint16
+	id     uint16
+	lang   uint16
+	data   []byte
+}
+
+// A set of resources, identified by type and ID
+type Resources struct {
+	resources []resource
+}
+
+// Adds (or replaces) a resource
+func (r *Resources) Add(typeID, id, lang uint16, data []byte) {
+	for i, existing := range r.resources {
+		if existing.typeID == typeID && existing.id == id && existing.lang == lang {
+			r.resources[i].data = data
+			return
+		}
+	}
+	r.resources = append(r.resources, resource{typeID, id, lang, data})
+}
+
+// Writes the resources as a COFF object file for the given GOARCH, containing a single '.rsrc' section
+func (r *Resources) WriteSyso(w io.Writer, arch string) error {
+	m, ok := machines[arch]
+	if !ok {
+		return errors.New("Windows resources are not supported for arch '" + arch + "'")
+	}
+	section, relocations := r.buildSection()
+	sectionOffset := fileHeaderSize + sectionHeaderSize
+	relocationsOffset := sectionOffset + len(section)
+	symbolsOffset := relocationsOffset + len(relocations)*relocationSize
+
+	buf := &bytes.Buffer{}
+	//file header
+	write(buf, m.id)
+	write(buf, uint16(1)) //sections
+	write(buf, uint32(0)) //timestamp, for reproducibility
+	write(buf, uint32(symbolsOffset))
+	write(buf, uint32(1)) //symbols
+	write(buf, uint16(0)) //optional header size
+	write(buf, m.characteristics)
+	//section header
+	buf.WriteString(".rsrc\x00\x00\x00")
+	write(buf, uint32(0)) //virtual size
+	write(buf, uint32(0)) //virtual address
+	write(buf, uint32(len(section)))
+	write(buf, uint32(sectionOffset))
+	write(buf, uint32(relocationsOffset))
+	write(buf, uint32(0)) //line numbers
+	write(buf, uint16(len(relocations)))
+	write(buf, uint16(0)) //line numbers
+	write(buf, uint32(imageScnCntInitializedData|imageScnMemRead))
+	buf.Write(section)
+	for _, offset := range relocations {
+		write(buf, offset)
+		write(buf, uint32(0)) //symbol index
+		write(buf, m.relocationType)
+	}
+	//symbol for the start of the section, which the relocations refer to
+	buf.WriteString("$R000000")
+	write(buf, uint32(0)) //value
+	write(buf, uint16(1)) //section number
+	write(buf, uint16(0)) //type
+	write(buf, uint8(imageSymClassStatic))
+	write(buf, uint8(0)) //aux symbols
+	//empty string table
+	write(buf, uint32(4))
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Lays out the resource directory tree (type/id/language), data entries and then the data itself.
+// Returns the section contents and the offsets needing relocations.
+func (r *Resources) buildSection() ([]byte, []uint32) {
+	sorted := make([]resource, len(r.resources))
+	copy(sorted, r.resources)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.typeID != b.typeID {
+			return a.typeID < b.typeID
+		}
+		if a.id != b.id {
+			return a.id < b.id
+		}
+		return a.lang < b.lang
+	})
+	types := []uint16{}
+	idsByType := map[uint16][]uint16{}
+	for _, res := range sorted {
+		ids := idsByType[res.typeID]
+		if len(ids) == 0 {
+			types = append(types, res.typeID)
+		}
+		if len(ids) == 0 || ids[len(ids)-1] != res.id {
+			idsByType[res.typeID] = append(ids, res.id)
+		}
+	}
+	//sizes of each level of the tree
+	idDirCount := 0
+	for _, t := range types {
+		idDirCount += len(idsByType[t])
+	}
+	rootSize := directorySize + len(types)*directoryEntrySize
+	typeDirsSize := len(types)*directorySize + idDirCount*directoryEntrySize
+	langDirsSize := idDirCount*directorySize + len(sorted)*directoryEntrySize
+	dataEntriesOffset := rootSize + typeDirsSize + langDirsSize
+	dataOffset := align(dataEntriesOffset+len(sorted)*dataEntrySize, 8)
+
+	buf := &bytes.Buffer{}
+	relocations := []uint32{}
+	//root: one entry per type
+	writeDirectory(buf, len(types))
+	typeDirOffset := rootSize
+	for _, t := range types {
+		write(buf, uint32(t))
+		write(buf, uint32(typeDirOffset|subdirectoryFlag))
+		typeDirOffset += directorySize + len(idsByType[t])*directoryEntrySize
+	}
+	//per type: one entry per id
+	langDirOffset := rootSize + typeDirsSize
+	resIndex := 0
+	langCounts := []int{}
+	for _, t := range types {
+		writeDirectory(buf, len(idsByType[t]))
+		for _, id := range idsByType[t] {
+			write(buf, uint32(id))
+			write(buf, uint32(langDirOffset|subdirectoryFlag))
+			count := 0
+			for resIndex+count < len(sorted) && sorted[resIndex+count].typeID == t && sorted[resIndex+count].id == id {
+				count++
+			}
+			langCounts = append(langCounts, count)
+			resIndex += count
+			langDirOffset += directorySize + count*directoryEntrySize
+		}
+	}
+	//per id: one entry per language, pointing at a data entry
+	resIndex = 0
+	for _, count := range langCounts {
+		writeDirectory(buf, count)
+		for i := 0; i < count; i++ {
+			write(buf, uint32(sorted[resIndex].lang))
+			write(buf, uint32(dataEntriesOffset+resIndex*dataEntrySize))
+			resIndex++
+		}
+	}
+	//data entries
+	offset := dataOffset
+	for _, res := range sorted {
+		relocations = append(relocations, uint32(buf.Len()))
+		write(buf, uint32(offset))
+		write(buf, uint32(len(res.data)))
+		write(buf, uint32(0)) //code page
+		write(buf, uint32(0)) //reserved
+		offset = align(offset+len(res.data), 8)
+	}
+	//data
+	for _, res := range sorted {
+		pad(buf, 8)
+		buf.Write(res.data)
+	}
+	pad(buf, 8)
+	return buf.Bytes(), relocations
+}
+
+func writeDirectory(buf *bytes.Buffer, idEntries int) {
+	write(buf, uint32(0)) //characteristics
+	write(buf, uint32(0)) //timestamp
+	write(buf, uint16(0)) //major version
+	write(buf, uint16(0)) //minor version
+	write(buf, uint16(0)) //named entries
+	write(buf, uint16(idEntries))
+}
+
+func write(buf *bytes.Buffer, v interface{}) {
+	//writes to a bytes.Buffer never fail
+	_ = binary.Write(buf, binary.LittleEndian, v)
+}
+
+func align(n, to int) int {
+	return (n + to - 1) / to * to
+}
+
+func pad(buf *bytes.Buffer, to int) {
+	for buf.Len()%to != 0 {
+		buf.WriteByte(0)
+	}
+}
@@ -0,0 +1,63 @@
+package winres
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	iconDirSize      = 6
+	iconDirEntrySize = 16
+)
+
+// Adds the images of an .ico file as RT_ICON resources (with IDs from firstIconID), plus an RT_GROUP_ICON resource referencing them.
+func (r *Resources) AddIcon(groupID, firstIconID uint16, ico []byte) error {
+	if len(ico) < iconDirSize {
+		return errors.New("Icon file is too short")
+	}
+	reserved := binary.LittleEndian.Uint16(ico[0:])
+	iconType := binary.LittleEndian.Uint16(ico[2:])
+	count := int(binary.LittleEndian.Uint16(ico[4:]))
+	if reserved != 0 || iconType != 1 || count == 0 {
+		return errors.New("Not a valid .ico file")
+	}
+	if len(ico) < iconDirSize+count*iconDirEntrySize {
+		return errors.New("Icon directory is truncated")
+	}
+	group := &bytes.Buffer{}
+	write(group, uint16(0))
+	write(group, uint16(1))
+	write(group, uint16(count))
+	for i := 0; i < count; i++ {
+		entry := ico[iconDirSize+i*iconDirEntrySize:]
+		size := binary.LittleEndian.Uint32(entry[8:])
+		offset := binary.LittleEndian.Uint32(entry[12:])
+		if uint64(offset)+uint64(size) > uint64(len(ico)) {
+			return errors.New("Icon image data is truncated")
+		}
+		id := firstIconID + uint16(i)
+		r.Add(RT_ICON, id, LANG_EN_US, ico[offset:offset+size])
+		//group entries are icon dir entries, with the ID in place of the offset
+		group.Write(entry[:12])
+		write(group, id)
+	}
+	r.Add(RT_GROUP_ICON, groupID, LANG_EN_US, group.Bytes())
+	return nil
+}
@@ -0,0 +1,96 @@
+package winres
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"text/template"
+)
+
+const manifestTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="{{.Name}}" version="{{.Version}}" processorArchitecture="*"/>
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="{{.ExecutionLevel}}" uiAccess="false"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+{{- if .DpiAware}}
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">{{.DpiAware}}</dpiAware>
+{{- if .DpiAwareness}}
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">{{.DpiAwareness}}</dpiAwareness>
+{{- end}}
+    </windowsSettings>
+  </application>
+{{- end}}
+</assembly>
+`
+
+// Application manifest settings
+type Manifest struct {
+	Name    string
+	Version [4]uint16
+	//asInvoker, highestAvailable or requireAdministrator
+	ExecutionLevel string
+	//empty (unaware), system, per-monitor or per-monitor-v2
+	DpiAwareness string
+}
+
+var dpiSettings = map[string][2]string{
+	"":               {"", ""},
+	"unaware":        {"", ""},
+	"system":         {"true", ""},
+	"per-monitor":    {"true/pm", "PerMonitor"},
+	"per-monitor-v2": {"true/pm", "PerMonitorV2, PerMonitor"},
+}
+
+// The manifest XML, for use as an RT_MANIFEST resource
+func (m Manifest) Bytes() ([]byte, error) {
+	executionLevel := m.ExecutionLevel
+	if executionLevel == "" {
+		executionLevel = "asInvoker"
+	}
+	if executionLevel != "asInvoker" && executionLevel != "highestAvailable" && executionLevel != "requireAdministrator" {
+		return nil, errors.New("Invalid execution level '" + executionLevel + "'. Use asInvoker, highestAvailable or requireAdministrator")
+	}
+	dpi, ok := dpiSettings[m.DpiAwareness]
+	if !ok {
+		return nil, errors.New("Invalid DPI awareness '" + m.DpiAwareness + "'. Use system, per-monitor or per-monitor-v2")
+	}
+	tmpl, err := template.New("manifest").Parse(manifestTemplate)
+	if err != nil {
+		return nil, err
+	}
+	vars := struct {
+		Name, Version, ExecutionLevel, DpiAware, DpiAwareness string
+	}{
+		m.Name,
+		fmt.Sprintf("%d.%d.%d.%d", m.Version[0], m.Version[1], m.Version[2], m.Version[3]),
+		executionLevel,
+		dpi[0],
+		dpi[1],
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, vars)
+	return buf.Bytes(), err
+}
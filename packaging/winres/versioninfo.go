@@ -0,0 +1,146 @@
+package winres
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	vsFixedFileInfoSignature = 0xFEEF04BD
+	vsFileFlagsMask          = 0x3F
+	vosNtWindows32           = 0x00040004
+	vftApp                   = 0x00000001
+	//unicode
+	codePageUtf16 = 0x04B0
+)
+
+// VERSIONINFO resource contents. Strings are keyed by the standard names (CompanyName, FileDescription, LegalCopyright, etc)
+type VersionInfo struct {
+	FileVersion    [4]uint16
+	ProductVersion [4]uint16
+	Strings        map[string]string
+}
+
+// Parses up to 4 numeric components from a version string (e.g. '1.2.3-rc1' => 1,2,3,0)
+func ParseVersion(version string) [4]uint16 {
+	ret := [4]uint16{}
+	parts := strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '-' || r == '+' })
+	for i := 0; i < len(parts) && i < 4; i++ {
+		n, err := strconv.ParseUint(parts[i], 10, 16)
+		if err != nil {
+			break
+		}
+		ret[i] = uint16(n)
+	}
+	return ret
+}
+
+// The VS_VERSIONINFO structure, for use as an RT_VERSION resource
+func (vi VersionInfo) Bytes() []byte {
+	fixed := &bytes.Buffer{}
+	for _, v := range []uint32{
+		vsFixedFileInfoSignature,
+		0x00010000, //struct version
+		uint32(vi.FileVersion[0])<<16 | uint32(vi.FileVersion[1]),
+		uint32(vi.FileVersion[2])<<16 | uint32(vi.FileVersion[3]),
+		uint32(vi.ProductVersion[0])<<16 | uint32(vi.ProductVersion[1]),
+		uint32(vi.ProductVersion[2])<<16 | uint32(vi.ProductVersion[3]),
+		vsFileFlagsMask,
+		0, //flags
+		vosNtWindows32,
+		vftApp,
+		0, //subtype
+		0, //date (MS)
+		0, //date (LS)
+	} {
+		write(fixed, v)
+	}
+
+	keys := []string{}
+	for k, v := range vi.Strings {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	strs := []viNode{}
+	for _, k := range keys {
+		strs = append(strs, viNode{key: k, text: vi.Strings[k], isText: true})
+	}
+	stringTable := viNode{key: fmt.Sprintf("%04X%04X", LANG_EN_US, codePageUtf16), isText: true, children: strs}
+	stringFileInfo := viNode{key: "StringFileInfo", isText: true, children: []viNode{stringTable}}
+	translation := &bytes.Buffer{}
+	write(translation, uint16(LANG_EN_US))
+	write(translation, uint16(codePageUtf16))
+	varFileInfo := viNode{key: "VarFileInfo", isText: true, children: []viNode{{key: "Translation", value: translation.Bytes()}}}
+	root := viNode{key: "VS_VERSION_INFO", value: fixed.Bytes(), children: []viNode{stringFileInfo, varFileInfo}}
+	return root.bytes()
+}
+
+// a node of the version info tree: each has a key, an optional value, and children
+type viNode struct {
+	key      string
+	value    []byte
+	text     string
+	isText   bool
+	children []viNode
+}
+
+func (n viNode) bytes() []byte {
+	buf := &bytes.Buffer{}
+	value := n.value
+	valueLength := len(value)
+	if n.text != "" {
+		value = utf16z(n.text)
+		//text lengths are in WORDs, including the terminator
+		valueLength = len(value) / 2
+	}
+	write(buf, uint16(0)) //length, filled in below
+	write(buf, uint16(valueLength))
+	if n.isText {
+		write(buf, uint16(1))
+	} else {
+		write(buf, uint16(0))
+	}
+	buf.Write(utf16z(n.key))
+	pad(buf, 4)
+	buf.Write(value)
+	for _, child := range n.children {
+		pad(buf, 4)
+		buf.Write(child.bytes())
+	}
+	ret := buf.Bytes()
+	binary.LittleEndian.PutUint16(ret, uint16(len(ret)))
+	return ret
+}
+
+// null-terminated UTF-16LE
+func utf16z(s string) []byte {
+	buf := &bytes.Buffer{}
+	for _, c := range utf16.Encode([]rune(s)) {
+		write(buf, c)
+	}
+	write(buf, uint16(0))
+	return buf.Bytes()
+}
@@ -0,0 +1,60 @@
+package winres
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"testing"
+)
+
+func TestWriteSyso(t *testing.T) {
+	r := &Resources{}
+	v := ParseVersion("1.2.3-rc1")
+	if v != [4]uint16{1, 2, 3, 0} {
+		t.Fatalf("unexpected version %v", v)
+	}
+	r.Add(RT_VERSION, 1, LANG_EN_US, VersionInfo{v, v, map[string]string{"CompanyName": "Acme"}}.Bytes())
+	manifest, err := Manifest{Name: "app", Version: v}.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Add(RT_MANIFEST, 1, LANG_EN_US, manifest)
+	var buf bytes.Buffer
+	err = r.WriteSyso(&buf, "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := pe.NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.FileHeader.Machine != pe.IMAGE_FILE_MACHINE_AMD64 || len(f.Sections) != 1 || f.Sections[0].Name != ".rsrc" {
+		t.Fatalf("unexpected COFF headers: %+v", f.FileHeader)
+	}
+	section := f.Sections[0]
+	if len(section.Relocs) != 2 {
+		t.Fatalf("expected a relocation per resource, got %d", len(section.Relocs))
+	}
+	data, err := section.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//root directory: 2 types (version, manifest), in ascending order
+	if binary.LittleEndian.Uint16(data[14:]) != 2 || binary.LittleEndian.Uint32(data[16:]) != RT_VERSION || binary.LittleEndian.Uint32(data[24:]) != RT_MANIFEST {
+		t.Fatalf("unexpected root directory: %v", data[:32])
+	}
+	//manifest data is the last resource: its data entry points at the XML
+	entry := section.Relocs[1].VirtualAddress
+	offset := binary.LittleEndian.Uint32(data[entry:])
+	size := binary.LittleEndian.Uint32(data[entry+4:])
+	if !bytes.Equal(data[offset:offset+size], manifest) {
+		t.Fatalf("manifest data not found at offset %d", offset)
+	}
+}
+
+func TestAddIconInvalid(t *testing.T) {
+	r := &Resources{}
+	if err := r.AddIcon(1, 1, []byte("not an icon")); err == nil {
+		t.Fatalf("expected an error for an invalid icon")
+	}
+}
@@ -17,7 +17,11 @@ package platforms
 */
 
 import (
+	"fmt"
+	"go/build/constraint"
 	"log"
+	"regexp"
+	"runtime"
 	"strings"
 
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
@@ -25,8 +29,24 @@ import (
 	"github.com/laher/goxc/typeutils"
 )
 
+// OSes satisfying the 'unix' build tag (as in go/build)
+var UNIX_OSES = []string{"aix", "android", DARWIN, DRAGONFLY, FREEBSD, "hurd", "illumos", "ios", LINUX, NETBSD, OPENBSD, SOLARIS}
+
+// release tags, e.g. 'go1.21'
+var goReleaseTag = regexp.MustCompile(`^go1\.\d+$`)
+
 // parse and filter list of platforms
+// Both the original space/comma/'!' syntax (e.g. 'linux,!arm windows') and
+// //go:build-style boolean expressions (e.g. 'linux && (amd64 || arm64)') are accepted.
 func ApplyBuildConstraints(buildConstraints string, unfilteredPlatforms []Platform) []Platform {
+	if isBuildExpression(buildConstraints) {
+		expr, err := constraint.Parse("//go:build " + buildConstraints)
+		if err != nil {
+			log.Printf("Invalid build constraint expression '%s': %v", buildConstraints, err)
+			return []Platform{}
+		}
+		return applyBuildExpression(expr, unfilteredPlatforms)
+	}
 	ret := []Platform{}
 	items := strings.FieldsFunc(buildConstraints, func(r rune) bool { return r == ' ' })
 	if len(items) == 0 {
@@ -38,9 +58,21 @@ func ApplyBuildConstraints(buildConstraints string, unfilteredPlatforms []Platfo
 		itemNegOs := []string{}
 		itemArch := []string{}
 		itemNegArch := []string{}
+		//nil unless 'cgo' or '!cgo' is present
+		var itemCgo *bool
 		for _, part := range parts {
 			isNeg, modulus := isNegative(part)
-			if IsOs(modulus) {
+			if modulus == "unix" {
+				for _, unixOs := range UNIX_OSES {
+					if IsOs(unixOs) {
+						if isNeg {
+							itemNegOs = append(itemNegOs, unixOs)
+						} else {
+							itemOs = append(itemOs, unixOs)
+						}
+					}
+				}
+			} else if IsOs(modulus) {
 				if isNeg {
 					itemNegOs = append(itemNegOs, modulus)
 				} else {
@@ -53,15 +85,153 @@ func ApplyBuildConstraints(buildConstraints string, unfilteredPlatforms []Platfo
 					itemArch = append(itemArch, modulus)
 				}
 
-			} else {
-				log.Printf("Unrecognised build constraint! Ignoring '%s'", part)
+			} else if modulus == "cgo" {
+				cgo := !isNeg
+				itemCgo = &cgo
+			} else if !isGoTag(modulus) {
+				log.Printf("Unrecognised build constraint! Ignoring '%s'%s", part, didYouMean(modulus))
+			}
+		}
+		for _, p := range resolveItem(itemOs, itemNegOs, itemArch, itemNegArch, unfilteredPlatforms) {
+			if itemCgo == nil || *itemCgo == isCgoDefault(p) {
+				ret = append(ret, p)
+			}
+		}
+	}
+	return ret
+}
+
+// ValidateBuildConstraints checks that every OS or architecture named in the build constraints is known to goxc
+// (or is another tag known to the go tool, such as 'unix', 'cgo' or 'go1.21').
+// Typos are reported along with the closest known name, rather than silently matching the wrong platforms.
+func ValidateBuildConstraints(buildConstraints string) error {
+	tags, err := buildConstraintTags(buildConstraints)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if !IsOs(tag) && !IsArch(tag) && tag != "unix" && !isGoTag(tag) {
+			return fmt.Errorf("Unrecognised build constraint '%s'%s", tag, didYouMean(tag))
+		}
+	}
+	return nil
+}
+
+// tags known to the go tool which don't name a platform. They don't narrow the old-style constraints
+func isGoTag(tag string) bool {
+	return tag == "cgo" || goReleaseTag.MatchString(tag)
+}
+
+// the go tool only enables cgo by default for native builds
+func isCgoDefault(p Platform) bool {
+	return p.Os == runtime.GOOS && p.Arch == runtime.GOARCH
+}
+
+// 'old' constraints are space-separated lists of comma-separated terms. Anything using operators or parentheses is an expression.
+func isBuildExpression(buildConstraints string) bool {
+	return strings.ContainsAny(buildConstraints, "&|()")
+}
+
+func applyBuildExpression(expr constraint.Expr, unfilteredPlatforms []Platform) []Platform {
+	ret := []Platform{}
+	for _, p := range unfilteredPlatforms {
+		matches := expr.Eval(func(tag string) bool {
+			switch {
+			case tag == "unix":
+				return typeutils.StringSliceContains(UNIX_OSES, p.Os)
+			case tag == "cgo":
+				return isCgoDefault(p)
+			case goReleaseTag.MatchString(tag):
+				return true
 			}
+			return tag == p.Os || tag == p.Arch
+		})
+		if matches {
+			ret = append(ret, p)
 		}
-		ret = append(ret, resolveItem(itemOs, itemNegOs, itemArch, itemNegArch, unfilteredPlatforms)...)
 	}
 	return ret
 }
 
+// list the OS/arch names referred to by the build constraints (in either syntax)
+func buildConstraintTags(buildConstraints string) ([]string, error) {
+	tags := []string{}
+	if isBuildExpression(buildConstraints) {
+		expr, err := constraint.Parse("//go:build " + buildConstraints)
+		if err != nil {
+			return tags, fmt.Errorf("Invalid build constraint expression '%s': %v", buildConstraints, err)
+		}
+		return appendExprTags(tags, expr), nil
+	}
+	parts := strings.FieldsFunc(buildConstraints, func(r rune) bool { return r == ' ' || r == ',' })
+	for _, part := range parts {
+		_, modulus := isNegative(part)
+		tags = append(tags, modulus)
+	}
+	return tags, nil
+}
+
+func appendExprTags(tags []string, expr constraint.Expr) []string {
+	switch x := expr.(type) {
+	case *constraint.TagExpr:
+		return append(tags, x.Tag)
+	case *constraint.NotExpr:
+		return appendExprTags(tags, x.X)
+	case *constraint.AndExpr:
+		return appendExprTags(appendExprTags(tags, x.X), x.Y)
+	case *constraint.OrExpr:
+		return appendExprTags(appendExprTags(tags, x.X), x.Y)
+	}
+	return tags
+}
+
+// suggest the closest known OS or arch name, if there's one within a couple of typos
+func didYouMean(unknown string) string {
+	best := ""
+	bestDistance := 3
+	for _, known := range append(append([]string{}, OSES...), ARCHS...) {
+		d := levenshtein(unknown, known)
+		if d < bestDistance {
+			best = known
+			bestDistance = d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean '%s'?)", best)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
 // check if a string is a valid architecture name
 func IsArch(part string) bool {
 	return typeutils.StringSlicePos(ARCHS, part) > -1
@@ -2,12 +2,14 @@ package platforms
 
 import (
 	"fmt"
+	"sort"
 	"testing"
 )
 
 func Test1(t *testing.T) {
 	testBCs := map[string]string{
 		"freebsd linux,!arm": "[{freebsd 386} {freebsd amd64} {linux 386} {linux amd64}]",
+		"unix,!arm,!386":     "[{darwin amd64} {freebsd amd64} {linux amd64} {openbsd amd64}]",
 		"windows":            "[{windows 386} {windows amd64}]",
 		"windows,!386":       "[{windows amd64}]",
 		"!windows":           "[{darwin 386} {darwin amd64} {linux 386} {linux amd64} {linux arm} {freebsd 386} {freebsd amd64} {openbsd 386} {openbsd amd64}]",
@@ -23,3 +25,70 @@ func Test1(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildExpressions(t *testing.T) {
+	testBCs := map[string]string{
+		"linux && (386 || arm)":         "[{linux 386} {linux arm}]",
+		"!windows && !darwin && !linux": "[{freebsd 386} {freebsd amd64} {openbsd 386} {openbsd amd64}]",
+		"(darwin || windows) && !386":   "[{darwin amd64} {windows amd64}]",
+		"linux && !(amd64 || 386)":      "[{linux arm}]",
+		"unix && !linux && go1.21":      "[{darwin 386} {darwin amd64} {freebsd 386} {freebsd amd64} {openbsd 386} {openbsd amd64}]",
+	}
+	for buildConstraints, expectedPlatforms := range testBCs {
+		targets := ApplyBuildConstraints(buildConstraints, SUPPORTED_PLATFORMS_1_0)
+		t.Logf("build: %s. targets: %v", buildConstraints, targets)
+		targetsAsString := fmt.Sprintf("%v", targets)
+		if targetsAsString != expectedPlatforms {
+			t.Fatalf("unexpected result %v != %v", expectedPlatforms, targets)
+		}
+	}
+}
+
+func sortedPlatforms(platforms []Platform) string {
+	names := []string{}
+	for _, p := range platforms {
+		names = append(names, p.Os+"/"+p.Arch)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("%v", names)
+}
+
+// both syntaxes select the same platforms
+func TestBuildConstraintSyntaxesAgree(t *testing.T) {
+	for old, expr := range map[string]string{
+		"linux,cgo":      "linux && cgo",
+		"linux,!cgo":     "linux && !cgo",
+		"cgo":            "cgo || cgo",
+		"unix,!arm":      "unix && !arm",
+		"go1.21,windows": "go1.21 && windows",
+	} {
+		oldTargets := sortedPlatforms(ApplyBuildConstraints(old, SUPPORTED_PLATFORMS_1_0))
+		exprTargets := sortedPlatforms(ApplyBuildConstraints(expr, SUPPORTED_PLATFORMS_1_0))
+		if oldTargets != exprTargets {
+			t.Errorf("'%s' gives %s, but '%s' gives %s", old, oldTargets, expr, exprTargets)
+		}
+	}
+}
+
+func TestValidateBuildConstraints(t *testing.T) {
+	for _, bc := range []string{"", "linux,!arm windows", "linux && (amd64 || arm64)", "!windows && !plan9", "unix && !cgo", "go1.21 linux,cgo"} {
+		if err := ValidateBuildConstraints(bc); err != nil {
+			t.Fatalf("unexpected error for '%s': %v", bc, err)
+		}
+	}
+	for bc, expected := range map[string]string{
+		"linx,arm":           "Unrecognised build constraint 'linx' (did you mean 'linux'?)",
+		"windows && (amd46)": "Unrecognised build constraint 'amd46' (did you mean 'amd64'?)",
+		"linux && (amd64 ||": "Invalid build constraint expression 'linux && (amd64 ||': missing close paren",
+		"!beos":              "Unrecognised build constraint 'beos'",
+		"unix && go1.x":      "Unrecognised build constraint 'go1.x'",
+	} {
+		err := ValidateBuildConstraints(bc)
+		if err == nil {
+			t.Fatalf("expected error for '%s'", bc)
+		}
+		if err.Error() != expected {
+			t.Fatalf("unexpected error for '%s': '%v' != '%s'", bc, err, expected)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package source
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/platforms"
+)
+
+// A Go module: its path (from go.mod) and the directory containing its go.mod
+type Module struct {
+	Path string
+	Dir  string
+}
+
+// A 'main' package within a module. ImportPath is derived from the module path, so that it can be built by import path.
+type MainPackage struct {
+	Dir        string
+	ImportPath string
+	Module     Module
+}
+
+// Lists the modules making up the project at root, as the go tool does ('go list -m'): the 'use' directives of a go.work file, or else the module containing root.
+// Returns an empty slice for GOPATH-style projects.
+func FindModules(root, goRoot string) ([]Module, error) {
+	modules := []Module{}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return modules, err
+	}
+	if core.FindWorkFile(root) == "" && core.FindModuleRoot(root) == "" {
+		return modules, nil
+	}
+	out, err := goList(root, goRoot, nil, "-m", "-mod=readonly", "-json")
+	if err != nil {
+		return modules, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var module Module
+		err = decoder.Decode(&module)
+		if err == io.EOF {
+			return modules, nil
+		}
+		if err != nil {
+			return modules, err
+		}
+		modules = append(modules, module)
+	}
+}
+
+func loadModule(dir string) (Module, error) {
+	modulePath, err := core.GetModulePath(filepath.Join(dir, core.GOMOD_FILENAME))
+	if err != nil {
+		return Module{}, err
+	}
+	return Module{modulePath, dir}, nil
+}
+
+// A package, as listed by 'go list -json'
+type listedPackage struct {
+	Dir        string
+	ImportPath string
+	Name       string
+}
+
+// Finds 'main' packages within the given modules, as listed by the go tool ('go list ./...'), so vendor, testdata and '_' directories are skipped.
+// Build constraints apply, but a package only built for another platform (e.g. with just 'main_windows.go') counts too.
+// Nested modules (subdirectories with their own go.mod) are skipped, unless they match one of includingNestedGlobs.
+// Exclusion globs are relative to root, as for FindMainDirs.
+func FindMainPackages(root, goRoot string, modules []Module, excludingGlobs, includingNestedGlobs []string, isVerbose bool) ([]MainPackage, error) {
+	mainPackages := []MainPackage{}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return mainPackages, err
+	}
+	modules, err = appendNestedModules(root, modules, includingNestedGlobs, isVerbose)
+	if err != nil {
+		return mainPackages, err
+	}
+	for _, module := range modules {
+		listRoot := module.Dir
+		if strings.HasPrefix(root, module.Dir+string(filepath.Separator)) {
+			//only look inside the working directory
+			listRoot = root
+		}
+		packages, err := listPackages(listRoot, goRoot, isVerbose)
+		if err != nil {
+			return mainPackages, err
+		}
+		for _, pkg := range packages {
+			if pkg.Name != "main" || isExcludedDir(root, pkg.Dir, excludingGlobs, isVerbose) {
+				continue
+			}
+			if isVerbose {
+				log.Printf("Found main package '%s' in %s", pkg.ImportPath, pkg.Dir)
+			}
+			mainPackages = append(mainPackages, MainPackage{pkg.Dir, pkg.ImportPath, module})
+		}
+	}
+	return mainPackages, nil
+}
+
+// Adds nested modules matching includingNestedGlobs (relative to root)
+func appendNestedModules(root string, modules []Module, includingNestedGlobs []string, isVerbose bool) ([]Module, error) {
+	for _, glob := range includingNestedGlobs {
+		matches, err := filepath.Glob(filepath.Join(root, glob, core.GOMOD_FILENAME))
+		if err != nil {
+			log.Printf("Glob error: %s: %s", glob, err)
+			continue
+		}
+		for _, match := range matches {
+			dir := filepath.Dir(match)
+			if containsModuleDir(modules, dir) {
+				continue
+			}
+			nested, err := loadModule(dir)
+			if err != nil {
+				return modules, err
+			}
+			if isVerbose {
+				log.Printf("Including nested module '%s'", dir)
+			}
+			modules = append(modules, nested)
+		}
+	}
+	return modules, nil
+}
+
+// Lists the packages in dir and below, sorted by import path. './...' only matches packages with files for the target platform,
+// so this lists for each OS (and for each arch, on linux). A package which is 'main' for any of them is 'main'
+func listPackages(dir, goRoot string, isVerbose bool) ([]listedPackage, error) {
+	targets := []platforms.Platform{}
+	for _, goos := range platforms.OSES {
+		targets = append(targets, platforms.Platform{Os: goos, Arch: platforms.AMD64})
+	}
+	for _, goarch := range platforms.ARCHS {
+		if goarch != platforms.AMD64 {
+			targets = append(targets, platforms.Platform{Os: platforms.LINUX, Arch: goarch})
+		}
+	}
+	byImportPath := map[string]listedPackage{}
+	var firstErr error
+	listed := false
+	for _, target := range targets {
+		//each module on its own (nested modules aren't in the workspace). '-find' skips resolving dependencies
+		out, err := goList(dir, goRoot, []string{"GOWORK=off", "GOOS=" + target.Os, "GOARCH=" + target.Arch}, "-find", "-e", "-mod=readonly", "-json", "./...")
+		if err != nil {
+			//e.g. a platform this toolchain doesn't support
+			if isVerbose {
+				log.Printf("Could not list packages for %s/%s: %v", target.Os, target.Arch, err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		listed = true
+		decoder := json.NewDecoder(bytes.NewReader(out))
+		for {
+			var pkg listedPackage
+			err = decoder.Decode(&pkg)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if existing, exists := byImportPath[pkg.ImportPath]; !exists || existing.Name != "main" {
+				byImportPath[pkg.ImportPath] = pkg
+			}
+		}
+	}
+	if !listed {
+		return nil, firstErr
+	}
+	packages := []listedPackage{}
+	for _, pkg := range byImportPath {
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].ImportPath < packages[j].ImportPath })
+	return packages, nil
+}
+
+// Runs 'go list', returning its output
+func goList(dir, goRoot string, env []string, args ...string) ([]byte, error) {
+	goCmd := "go"
+	if goRoot != "" {
+		goCmd = filepath.Join(goRoot, "bin", "go")
+	}
+	cmd := exec.Command(goCmd, append([]string{"list"}, args...)...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(cmd.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		return nil, fmt.Errorf("go list: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, err
+}
+
+func containsModuleDir(modules []Module, dir string) bool {
+	for _, module := range modules {
+		if module.Dir == dir {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+func TestFindMainPackagesWorkspace(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "goxc_test_modules")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpDir, _ = filepath.EvalSymlinks(tmpDir)
+	writeTestFile(t, filepath.Join(tmpDir, "go.work"), "go 1.21\n\nuse (\n\t./app // the app\n\t./lib\n)\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "go.mod"), "module example.com/app/v2\n\ngo 1.21\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "cmd", "tool", "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "testdata", "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "vendor", "example.com", "dep", "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "_examples", "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "gen", "gen.go"), "//go:build ignore\n\npackage main\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "gen", "doc.go"), "package gen\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "cmd", "winsvc", "main_windows.go"), "package main\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "internal", "x.go"), "package internal\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "nested", "go.mod"), "module example.com/nested\n")
+	writeTestFile(t, filepath.Join(tmpDir, "app", "nested", "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(tmpDir, "lib", "go.mod"), "module \"example.com/lib\"\n")
+	writeTestFile(t, filepath.Join(tmpDir, "lib", "lib.go"), "package lib\n")
+
+	modules, err := FindModules(tmpDir, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(modules) != 2 || modules[0].Path != "example.com/app/v2" || modules[1].Path != "example.com/lib" {
+		t.Fatalf("unexpected modules %+v", modules)
+	}
+	mainPackages, err := FindMainPackages(tmpDir, "", modules, []string{}, []string{}, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	importPaths := []string{}
+	for _, mp := range mainPackages {
+		importPaths = append(importPaths, mp.ImportPath)
+	}
+	if len(importPaths) != 3 || importPaths[0] != "example.com/app/v2" || importPaths[1] != "example.com/app/v2/cmd/tool" || importPaths[2] != "example.com/app/v2/cmd/winsvc" {
+		t.Fatalf("unexpected main packages %v", importPaths)
+	}
+
+	mainPackages, err = FindMainPackages(tmpDir, "", modules, []string{}, []string{"app/nested"}, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(mainPackages) != 4 || mainPackages[3].ImportPath != "example.com/nested" || mainPackages[3].Module.Dir != filepath.Join(tmpDir, "app", "nested") {
+		t.Fatalf("unexpected main packages with nested module %+v", mainPackages)
+	}
+}
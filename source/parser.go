@@ -86,34 +86,7 @@ func FindSourceDirs(root string, packageNameFilter string, excludingGlobs []stri
 			if err != nil {
 				log.Printf("Abs error: %s: %v", filepath.Dir(name), err)
 			} else {
-				excluded := false
-				for _, exclGlob := range excludingGlobs {
-					//log.Printf("Glob testing: %s matches %s", filepath.Join(root, exclGlob), mainDir)
-					matches, err := filepath.Match(filepath.Join(root, exclGlob), mainDir)
-					if err != nil {
-						//ignore this exclusion glob
-						log.Printf("Glob error: %s: %s", exclGlob, err)
-					} else if matches {
-						if isVerbose {
-							log.Printf("Main dir '%s' excluded by glob '%s'", mainDir, exclGlob)
-						}
-						excluded = true
-					} else {
-						absExcl, err := filepath.Abs(filepath.Join(root, exclGlob))
-						if err != nil {
-							//ignore
-							log.Printf("Abs error: %s: %v", filepath.Join(root, exclGlob), err)
-						} else if strings.HasPrefix(mainDir, absExcl) {
-							if isVerbose {
-								log.Printf("Main dir '%s' excluded because it is in '%s'", mainDir, absExcl)
-							}
-							excluded = true
-						} else {
-							//log.Printf("Main dir '%s' is NOT in '%s'", mainDir, absExcl)
-						}
-					}
-
-				}
+				excluded := isExcludedDir(root, mainDir, excludingGlobs, isVerbose)
 				if !excluded {
 					alreadyThere := false
 					for _, v := range mainDirs {
@@ -131,6 +104,38 @@ func FindSourceDirs(root string, packageNameFilter string, excludingGlobs []stri
 	return mainDirs, err
 }
 
+// checks a directory against exclusion globs (relative to the root dir). Directories inside an excluded directory are also excluded.
+func isExcludedDir(root, dir string, excludingGlobs []string, isVerbose bool) bool {
+	excluded := false
+	for _, exclGlob := range excludingGlobs {
+		//log.Printf("Glob testing: %s matches %s", filepath.Join(root, exclGlob), dir)
+		matches, err := filepath.Match(filepath.Join(root, exclGlob), dir)
+		if err != nil {
+			//ignore this exclusion glob
+			log.Printf("Glob error: %s: %s", exclGlob, err)
+		} else if matches {
+			if isVerbose {
+				log.Printf("Main dir '%s' excluded by glob '%s'", dir, exclGlob)
+			}
+			excluded = true
+		} else {
+			absExcl, err := filepath.Abs(filepath.Join(root, exclGlob))
+			if err != nil {
+				//ignore
+				log.Printf("Abs error: %s: %v", filepath.Join(root, exclGlob), err)
+			} else if strings.HasPrefix(dir, absExcl) {
+				if isVerbose {
+					log.Printf("Main dir '%s' excluded because it is in '%s'", dir, absExcl)
+				}
+				excluded = true
+			} else {
+				//log.Printf("Main dir '%s' is NOT in '%s'", dir, absExcl)
+			}
+		}
+	}
+	return excluded
+}
+
 func LoadFilesMap(filenames []string) (map[string]*ast.File, error) {
 	filesMap := map[string]*ast.File{}
 	fset := token.NewFileSet() // positions are relative to fset
@@ -61,6 +61,9 @@ func setupTarGz(tp TaskParams) ([]platforms.Platform, error) {
 		return []platforms.Platform{}, errors.New("Option 'os' is no longer supported! Please use 'platforms' instead, specified as a 'build contraint'. e.g. 'linux,386'")
 	}
 	bc := tp.Settings.GetTaskSettingString(TASK_TARGZ, "platforms")
+	if err := platforms.ValidateBuildConstraints(bc); err != nil {
+		return []platforms.Platform{}, err
+	}
 	destPlatforms := platforms.ApplyBuildConstraints(bc, tp.DestPlatforms)
 	return destPlatforms, nil
 }
@@ -71,6 +74,9 @@ func setupZip(tp TaskParams) ([]platforms.Platform, error) {
 		return []platforms.Platform{}, errors.New("Option 'os' is no longer supported! Please use 'platforms' instead, specified as a 'build contraint'. e.g. 'linux,386'")
 	}
 	bc := tp.Settings.GetTaskSettingString(TASK_ZIP, "platforms")
+	if err := platforms.ValidateBuildConstraints(bc); err != nil {
+		return []platforms.Platform{}, err
+	}
 	destPlatforms := platforms.ApplyBuildConstraints(bc, tp.DestPlatforms)
 	return destPlatforms, nil
 }
@@ -111,11 +117,21 @@ func archivePlat(goos, arch string, mainDirs []string, workingDirectory, outDest
 		} else {
 			exeName = filepath.Base(mainDir)
 		}
-		binPath, err := core.GetAbsoluteBin(goos, arch, settings.AppName, exeName, workingDirectory, settings.GetFullVersionName(), settings.OutPath, settings.ArtifactsDest)
+		binPath, err := settings.GetAbsoluteBin(goos, arch, exeName, workingDirectory)
 
 		if err != nil {
 			return err
 		}
+		if goos == platforms.DARWIN {
+			//package the app bundle instead, if 'darwin-app' made one
+			bundleDir := getDarwinAppBundle(binPath, exeName)
+			if exists, _ := core.FileExists(bundleDir); exists {
+				exes = append(exes, bundleDir)
+				if !settings.GetTaskSettingBool(TASK_DARWIN_APP, "archive-binary") {
+					continue
+				}
+			}
+		}
 		exes = append(exes, binPath)
 	}
 	outDir := filepath.Join(outDestRoot, settings.GetFullVersionName())
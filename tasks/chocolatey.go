@@ -0,0 +1,141 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/archive"
+	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/packaging/nupkg"
+	"github.com/laher/goxc/platforms"
+	"github.com/laher/goxc/typeutils"
+)
+
+var chocolateyMetadataDefaults = map[string]interface{}{
+	"id":                 "",
+	"title":              "",
+	"authors":            "",
+	"owners":             "",
+	"description":        "",
+	"summary":            "",
+	"project-url":        "",
+	"license-url":        "",
+	"icon-url":           "",
+	"package-source-url": "",
+	"copyright":          "",
+	"release-notes":      "",
+	"tags":               ""}
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_CHOCOLATEY,
+		"Builds a Chocolatey '.nupkg' from the 386 & amd64 Windows zips. With 'embed', the zips are inside the package. Otherwise, the install script downloads them from 'url-template' (the default matches 'publish-github'). Requires 'authors' and 'description' in 'metadata'. Runs on any host.",
+		runTaskChocolatey,
+		map[string]interface{}{
+			"metadata":     chocolateyMetadataDefaults,
+			"embed":        true,
+			"url-template": RELEASE_URL_TEMPLATE_GITHUB}})
+}
+
+func runTaskChocolatey(tp TaskParams) error {
+	metadata := map[string]string{}
+	//configured metadata replaces the default map, so fill in any gaps
+	for key, value := range typeutils.MergeMaps(tp.Settings.GetTaskSettingMap(TASK_CHOCOLATEY, "metadata"), chocolateyMetadataDefaults) {
+		s, err := typeutils.ToString(value, TASK_CHOCOLATEY+".metadata."+key)
+		if err != nil {
+			return err
+		}
+		metadata[key] = s
+	}
+	pkg := nupkg.Package{Nuspec: nupkg.Nuspec{
+		ID:               metadata["id"],
+		Version:          strings.TrimPrefix(tp.Settings.GetFullVersionName(), "v"),
+		Title:            metadata["title"],
+		Authors:          metadata["authors"],
+		Owners:           metadata["owners"],
+		Description:      metadata["description"],
+		Summary:          metadata["summary"],
+		ProjectURL:       metadata["project-url"],
+		LicenseURL:       metadata["license-url"],
+		IconURL:          metadata["icon-url"],
+		PackageSourceURL: metadata["package-source-url"],
+		Copyright:        metadata["copyright"],
+		ReleaseNotes:     metadata["release-notes"],
+		Tags:             metadata["tags"]},
+		Modified: executils.GetBuildDate()}
+	if pkg.ID == "" {
+		pkg.ID = strings.ToLower(tp.Settings.AppName)
+	}
+	if pkg.Title == "" {
+		pkg.Title = tp.Settings.AppName
+	}
+	urlTemplate, err := template.New("url-template").Parse(tp.Settings.GetTaskSettingString(TASK_CHOCOLATEY, "url-template"))
+	if err != nil {
+		return err
+	}
+	embed := tp.Settings.GetTaskSettingBool(TASK_CHOCOLATEY, "embed")
+	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	install := nupkg.ChocolateyInstall{}
+	for _, dest := range tp.DestPlatforms {
+		//Chocolatey distinguishes 32-bit and 64-bit only
+		if dest.Os != platforms.WINDOWS || (dest.Arch != platforms.X86 && dest.Arch != platforms.AMD64) {
+			continue
+		}
+		a, err := findReleaseArchive(dest, []string{"zip"}, urlTemplate, newReleaseURLVars(tp), tp)
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			continue
+		}
+		zip := &nupkg.ChocolateyZip{URL: a.URL, Sha256: a.Sha256}
+		if embed {
+			zip = &nupkg.ChocolateyZip{Embedded: a.FileName}
+			pkg.Files = append(pkg.Files, archive.ArchiveItemFromFileSystem(filepath.Join(versionDir, a.FileName), "tools/"+a.FileName))
+		}
+		if dest.Arch == platforms.AMD64 {
+			install.Zip64 = zip
+		} else {
+			install.Zip32 = zip
+		}
+	}
+	if install.Zip32 == nil && install.Zip64 == nil {
+		return errors.New("No Windows zips (386 or amd64) for the Chocolatey package. Please run 'archive-zip' first")
+	}
+	script, err := install.Script()
+	if err != nil {
+		return err
+	}
+	pkg.Files = append(pkg.Files, archive.ArchiveItemFromBytes(script, "tools/chocolateyinstall.ps1"))
+	nupkgPath := filepath.Join(versionDir, pkg.Filename())
+	err = pkg.Write(nupkgPath)
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Created Chocolatey package %s", nupkgPath)
+	}
+	return nil
+}
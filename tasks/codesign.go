@@ -17,6 +17,8 @@ package tasks
 */
 
 import (
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os/exec"
 	"path/filepath"
@@ -25,24 +27,48 @@ import (
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"github.com/laher/goxc/config"
-	"github.com/laher/goxc/core"
 	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/exefileparse"
+	"github.com/laher/goxc/packaging/cms"
+	"github.com/laher/goxc/packaging/macsign"
 	"github.com/laher/goxc/platforms"
 )
 
 var codesignTask = Task{
 	TASK_CODESIGN,
-	"sign code for Mac. Only Mac hosts are supported for this task.",
+	"sign code for Mac. The 'apple' backend uses Apple's codesign (Mac hosts only), with identity 'id'. The 'go' backend runs on any host, signing ad-hoc or with the certificate in 'p12'. With 'auto', the 'go' backend is used when 'id' is '-' or a 'p12' is configured, or when the host is not a Mac.",
 	runTaskCodesign,
-	map[string]interface{}{"id": ""}}
+	map[string]interface{}{"id": "", "backend": "auto", "p12": "", "p12-password": "", "identifier": ""}}
 
 //runs automatically
 func init() {
 	Register(codesignTask)
 }
 
-func runTaskCodesign(tp TaskParams) (err error) {
+func runTaskCodesign(tp TaskParams) error {
+	backend, err := getCodesignBackend(tp.Settings)
+	if err != nil {
+		return err
+	}
+	if backend == "" {
+		return nil
+	}
+	var signer *cms.Signer
+	p12 := tp.Settings.GetTaskSettingString(TASK_CODESIGN, "p12")
+	if backend == "go" && p12 != "" {
+		if !filepath.IsAbs(p12) {
+			p12 = filepath.Join(tp.WorkingDirectory, p12)
+		}
+		signer, err = cms.LoadPKCS12(p12, tp.Settings.GetTaskSettingString(TASK_CODESIGN, "p12-password"))
+		if err != nil {
+			return err
+		}
+		signer.SigningTime = executils.GetBuildDate()
+	}
 	for _, dest := range tp.DestPlatforms {
+		if dest.Os != platforms.DARWIN {
+			continue
+		}
 		for _, mainDir := range tp.MainDirs {
 			var exeName string
 			if len(tp.MainDirs) == 1 {
@@ -51,16 +77,91 @@ func runTaskCodesign(tp TaskParams) (err error) {
 				exeName = filepath.Base(mainDir)
 
 			}
-			binPath, err := core.GetAbsoluteBin(dest.Os, dest.Arch, tp.Settings.AppName, exeName, tp.WorkingDirectory, tp.Settings.GetFullVersionName(), tp.Settings.OutPath, tp.Settings.ArtifactsDest)
+			binPath, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
 
 			if err != nil {
 				return err
 			}
-			err = codesignPlat(dest.Os, dest.Arch, binPath, tp.Settings)
+			if backend == "apple" {
+				err = codesignPlat(dest.Os, dest.Arch, binPath, tp.Settings)
+			} else {
+				identifier := tp.Settings.GetTaskSettingString(TASK_CODESIGN, "identifier")
+				if identifier == "" {
+					identifier = exeName
+				}
+				err = codesignGo(binPath, identifier, signer, tp.Settings)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// returns "apple", "go", or "" (don't sign)
+func getCodesignBackend(settings *config.Settings) (string, error) {
+	id := settings.GetTaskSettingString(TASK_CODESIGN, "id")
+	backend := settings.GetTaskSettingString(TASK_CODESIGN, "backend")
+	switch backend {
+	case "apple":
+		if id == "" || runtime.GOOS != platforms.DARWIN {
+			return "", nil
+		}
+		return backend, nil
+	case "go":
+		return backend, nil
+	case "auto", "":
+		if id == "-" || settings.GetTaskSettingString(TASK_CODESIGN, "p12") != "" {
+			return "go", nil
+		}
+		if runtime.GOOS != platforms.DARWIN {
+			//ad-hoc, unless there's a p12. (Apple Silicon won't run unsigned binaries)
+			return "go", nil
+		}
+		if id != "" {
+			return "apple", nil
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("Unknown codesign backend '%s'. Use 'auto', 'apple' or 'go'", backend)
+	}
+}
+
+// Signs with the pure-Go signer, replacing the binary. The signature is then verified.
+func codesignGo(binPath, identifier string, signer *cms.Signer, settings *config.Settings) error {
+	data, err := ioutil.ReadFile(binPath)
+	if err != nil {
+		return err
+	}
+	if !macsign.IsSignable(data) {
+		//e.g. darwin/386, which is in the default platforms
+		if !settings.IsQuiet() {
+			log.Printf("Not signing %s: not a thin 64-bit Mach-O file", binPath)
+		}
+		return nil
+	}
+	signed, err := macsign.Sign(data, identifier, signer)
+	if err != nil {
+		log.Printf("codesign failed for %s: %s", binPath, err)
+		return err
+	}
+	err = ioutil.WriteFile(binPath, signed, 0755)
+	if err != nil {
+		return err
+	}
+	err = exefileparse.VerifyMachOSignature(binPath, settings.IsVerbose())
+	if err != nil {
+		return err
+	}
+	if !settings.IsQuiet() {
+		if signer == nil {
+			log.Printf("Signed %s (ad-hoc)", binPath)
+		} else {
+			log.Printf("Signed %s as %q", binPath, signer.Certificate.Subject.CommonName)
 		}
 	}
-	//TODO return error
-	return err
+	return nil
 }
 
 func codesignPlat(goos, arch string, binPath string, settings *config.Settings) error {
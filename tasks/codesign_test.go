@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/platforms"
+)
+
+func TestGetCodesignBackend(t *testing.T) {
+	defaultBackend := "go"
+	if runtime.GOOS == platforms.DARWIN {
+		defaultBackend = ""
+	}
+	for _, test := range []struct {
+		settings map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{}, defaultBackend},
+		{map[string]interface{}{"id": "-"}, "go"},
+		{map[string]interface{}{"p12": "cert.p12"}, "go"},
+		{map[string]interface{}{"backend": "go"}, "go"},
+	} {
+		s := config.Settings{}
+		FillTaskSettingsDefaults(&s)
+		taskSettings := map[string]interface{}{}
+		for k, v := range s.TaskSettings[TASK_CODESIGN] {
+			taskSettings[k] = v
+		}
+		for k, v := range test.settings {
+			taskSettings[k] = v
+		}
+		s.TaskSettings[TASK_CODESIGN] = taskSettings
+		backend, err := getCodesignBackend(&s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if backend != test.expected {
+			t.Errorf("unexpected backend '%s' for %v (expected '%s')", backend, test.settings, test.expected)
+		}
+	}
+}
+
+func TestCodesignGoSkips32Bit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-codesign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	//a 32-bit Mach-O header (MH_MAGIC), as for darwin/386
+	data := append([]byte{0xce, 0xfa, 0xed, 0xfe}, make([]byte, 64)...)
+	binPath := filepath.Join(dir, "app")
+	err = ioutil.WriteFile(binPath, data, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = codesignGo(binPath, "app", nil, &config.Settings{Verbosity: core.VerbosityQuiet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed, _ := ioutil.ReadFile(binPath); !bytes.Equal(signed, data) {
+		t.Errorf("expected the 32-bit binary to be left alone")
+	}
+}
@@ -40,6 +40,7 @@ func init() {
 func runTaskCopyResources(tp TaskParams) error {
 	resources := core.ParseIncludeResources(tp.WorkingDirectory, tp.Settings.ResourcesInclude, tp.Settings.ResourcesExclude, !tp.Settings.IsQuiet())
 	destFolder := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	destFolder = filepath.Join(append([]string{destFolder}, tp.Settings.GetMatrixVars().Parts()...)...)
 	if !tp.Settings.IsQuiet() {
 		log.Printf("resources: %v", resources)
 	}
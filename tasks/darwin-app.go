@@ -0,0 +1,124 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/packaging/macapp"
+	"github.com/laher/goxc/platforms"
+	"github.com/laher/goxc/typeutils"
+)
+
+var darwinAppMetadataDefaults = map[string]interface{}{"bundle-id": "", "display-name": "", "minimum-os": "10.13", "copyright": ""}
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_DARWIN_APP,
+		"Bundles Mac binaries into an '.app', with an Info.plist and an optional icon (converted from PNG). Archive tasks then package the bundle instead of the bare binary (or as well, with 'archive-binary'). Only runs when a 'bundle-id' is configured.",
+		runTaskDarwinApp,
+		map[string]interface{}{
+			"metadata":        darwinAppMetadataDefaults,
+			"icon":            "",
+			"high-resolution": true,
+			"archive-binary":  false}}) //also archive the bare binary, e.g. for a 'homebrew' formula
+}
+
+func runTaskDarwinApp(tp TaskParams) error {
+	metadata := map[string]string{}
+	//configured metadata replaces the default map, so fill in any gaps
+	for key, value := range typeutils.MergeMaps(tp.Settings.GetTaskSettingMap(TASK_DARWIN_APP, "metadata"), darwinAppMetadataDefaults) {
+		s, err := typeutils.ToString(value, TASK_DARWIN_APP+".metadata."+key)
+		if err != nil {
+			return err
+		}
+		metadata[key] = s
+	}
+	if metadata["bundle-id"] == "" {
+		if tp.Settings.IsVerbose() {
+			log.Printf("No bundle-id configured. Not building Mac app bundles")
+		}
+		return nil
+	}
+	var icon []byte
+	iconPath := tp.Settings.GetTaskSettingString(TASK_DARWIN_APP, "icon")
+	if iconPath != "" {
+		if !filepath.IsAbs(iconPath) {
+			iconPath = filepath.Join(tp.WorkingDirectory, iconPath)
+		}
+		f, err := os.Open(iconPath)
+		if err != nil {
+			return err
+		}
+		icon, err = macapp.PngToIcns(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	for _, dest := range tp.DestPlatforms {
+		if dest.Os != platforms.DARWIN {
+			continue
+		}
+		for _, mainDir := range tp.MainDirs {
+			var exeName string
+			if len(tp.MainDirs) == 1 {
+				exeName = tp.Settings.AppName
+			} else {
+				exeName = filepath.Base(mainDir)
+			}
+			binPath, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
+			if err != nil {
+				return err
+			}
+			bundle := macapp.Bundle{
+				Name:                 metadata["display-name"],
+				Executable:           exeName,
+				Identifier:           metadata["bundle-id"],
+				Version:              tp.Settings.GetFullVersionName(),
+				ShortVersion:         tp.Settings.PackageVersion,
+				MinimumSystemVersion: metadata["minimum-os"],
+				Copyright:            metadata["copyright"],
+				HighResolution:       tp.Settings.GetTaskSettingBool(TASK_DARWIN_APP, "high-resolution")}
+			if bundle.Name == "" {
+				bundle.Name = exeName
+			}
+			if icon != nil {
+				bundle.IconFile = exeName + ".icns"
+			}
+			bundleDir := getDarwinAppBundle(binPath, exeName)
+			err = bundle.Write(bundleDir, binPath, icon)
+			if err != nil {
+				return err
+			}
+			if !tp.Settings.IsQuiet() {
+				log.Printf("Created app bundle %s", bundleDir)
+			}
+		}
+	}
+	return nil
+}
+
+// the '.app' bundle sits alongside the binary it contains
+func getDarwinAppBundle(binPath, exeName string) string {
+	return filepath.Join(filepath.Dir(binPath), exeName+".app")
+}
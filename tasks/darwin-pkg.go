@@ -0,0 +1,115 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/archive"
+	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/packaging/macpkg"
+	"github.com/laher/goxc/platforms"
+)
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_DARWIN_PKG,
+		"Builds an unsigned flat '.pkg' installer for Mac, which installs the binaries into 'bin-dir'. Runs on any host. 'identifier' (e.g. 'com.example.app'). Only runs when an 'identifier' is configured.",
+		runTaskDarwinPkg,
+		map[string]interface{}{
+			"bin-dir":    "/usr/local/bin",
+			"identifier": "",
+			"title":      ""}})
+}
+
+func runTaskDarwinPkg(tp TaskParams) error {
+	if tp.Settings.GetTaskSettingString(TASK_DARWIN_PKG, "identifier") == "" {
+		if tp.Settings.IsVerbose() {
+			log.Printf("No identifier configured. Not building Mac installer packages")
+		}
+		return nil
+	}
+	for _, dest := range tp.DestPlatforms {
+		if dest.Os != platforms.DARWIN {
+			continue
+		}
+		err := darwinPkgPlat(dest, tp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func darwinPkgPlat(dest platforms.Platform, tp TaskParams) error {
+	pkg := &macpkg.Package{
+		Identifier:      tp.Settings.GetTaskSettingString(TASK_DARWIN_PKG, "identifier"),
+		Version:         tp.Settings.GetFullVersionName(),
+		Title:           tp.Settings.GetTaskSettingString(TASK_DARWIN_PKG, "title"),
+		InstallLocation: tp.Settings.GetTaskSettingString(TASK_DARWIN_PKG, "bin-dir")}
+	if pkg.Title == "" {
+		pkg.Title = tp.AppName
+	}
+	for _, mainDir := range tp.MainDirs {
+		var exeName string
+		if len(tp.MainDirs) == 1 {
+			exeName = tp.Settings.AppName
+		} else {
+			exeName = filepath.Base(mainDir)
+		}
+		binPath, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(binPath)
+		if err != nil {
+			return err
+		}
+		//(the binary's file name depends on OutPath)
+		pkg.AddFile(exeName, data, 0755)
+	}
+	outDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	err := os.MkdirAll(outDir, 0777)
+	if err != nil {
+		return err
+	}
+	pkgPath := filepath.Join(outDir, archive.ArtifactName(tp.Settings.AppName, dest.Os+"_"+dest.Arch, *tp.Settings)+".pkg")
+	f, err := os.Create(pkgPath)
+	if err != nil {
+		return err
+	}
+	//timestamps honour SOURCE_DATE_EPOCH, for reproducible packages
+	err = pkg.Write(f, executils.GetBuildDate())
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Created installer package %s", pkgPath)
+	}
+	return nil
+}
@@ -28,7 +28,6 @@ import (
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"github.com/laher/goxc/config"
-	"github.com/laher/goxc/core"
 	"github.com/laher/goxc/platforms"
 	"github.com/laher/goxc/typeutils"
 )
@@ -161,6 +160,8 @@ func debBuild(dest platforms.Platform, tp TaskParams) error {
 	}
 	rmtemp := tp.Settings.GetTaskSettingBool(TASK_DEB_GEN, "rmtemp")
 	debDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName()) //v0.8.1 dont use platform dir
+	//build matrix entries (variants, go versions) share a package name, so they need their own dir
+	debDir = filepath.Join(append([]string{debDir}, tp.Settings.GetMatrixVars().Parts()...)...)
 	tmpDir := filepath.Join(debDir, ".goxc-temp")
 
 	shortDescription := "?"
@@ -243,7 +244,7 @@ func debBuild(dest platforms.Platform, tp TaskParams) error {
 				} else {
 					exeName = filepath.Base(mainDir)
 				}
-				binPath, err := core.GetAbsoluteBin(dest.Os, dest.Arch, tp.Settings.AppName, exeName, tp.WorkingDirectory, tp.Settings.GetFullVersionName(), tp.Settings.OutPath, tp.Settings.ArtifactsDest)
+				binPath, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
 				if err != nil {
 					return err
 				}
@@ -18,10 +18,16 @@ package tasks
 
 import (
 	htemplate "html/template"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
 )
 
 //runs automatically
@@ -101,7 +107,7 @@ func runTaskDownloadsPage(tp TaskParams) error {
 	}
 	return out.Close()
 }
-func RunTemplate(reportFilename, templateFile, templateText string, out *os.File, data interface{}, format string) (err error) {
+func RunTemplate(reportFilename, templateFile, templateText string, out io.Writer, data interface{}, format string) (err error) {
 	var tmpl *template.Template
 	var htmpl *htemplate.Template
 	if templateFile != "" {
@@ -152,6 +158,48 @@ func GetCategory(relativePath string) string {
 	return category
 }
 
+// finds the build variant and go version (see MatrixVars.Parts) in a path: as its leading directories (e.g. 'variant/go1.21/...'),
+// or following the app name in an archive name (e.g. 'app_variant_go1.21_1.0_linux_amd64.zip')
+func getMatrixParts(relativePath, appName string, variants []config.Variant) []string {
+	segments := strings.Split(relativePath, "/")
+	if ret := matchMatrixParts(segments[:len(segments)-1], variants); len(ret) > 0 {
+		return ret
+	}
+	name := segments[len(segments)-1]
+	if !strings.HasPrefix(name, appName+"_") {
+		return []string{}
+	}
+	rest := strings.TrimPrefix(name, appName+"_")
+	tokens := []string{}
+	for _, variant := range variants {
+		//variant names may contain '_'
+		if strings.HasPrefix(rest, variant.Name+"_") {
+			tokens = append(tokens, variant.Name)
+			rest = strings.TrimPrefix(rest, variant.Name+"_")
+			break
+		}
+	}
+	return matchMatrixParts(append(tokens, strings.Split(rest, "_")...), variants)
+}
+
+// the variant, then the go version, at the start of parts. Either may be absent
+func matchMatrixParts(parts []string, variants []config.Variant) []string {
+	ret := []string{}
+	if len(parts) > 0 {
+		for _, variant := range variants {
+			if parts[0] == variant.Name {
+				ret = append(ret, variant.Name)
+				parts = parts[1:]
+				break
+			}
+		}
+	}
+	if len(parts) > 0 && core.IsGoVersion(parts[0]) {
+		ret = append(ret, parts[0])
+	}
+	return ret
+}
+
 func downloadsWalkFunc(fullPath string, Version string, fi2 os.FileInfo, err error, tp TaskParams, report Report, reportFilename, format string) error {
 	if fi2.IsDir() || fi2.Name() == reportFilename {
 		return nil
@@ -166,6 +214,9 @@ func downloadsWalkFunc(fullPath string, Version string, fi2 os.FileInfo, err err
 		text = strings.Replace(text, "_", "\\_", -1)
 	}
 	category := GetCategory(relativePath)
+	if matrix := getMatrixParts(relativePath, tp.Settings.AppName, tp.Settings.Variants); len(matrix) > 0 {
+		category += " (" + strings.Join(matrix, ", ") + ")"
+	}
 
 	//log.Printf("Adding: %s", relativePath)
 	download := Download{text, Version, relativePath}
@@ -0,0 +1,26 @@
+package tasks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/laher/goxc/config"
+)
+
+func TestGetMatrixParts(t *testing.T) {
+	variants := []config.Variant{{Name: "linux"}, {Name: "arm"}, {Name: "pro_edition"}}
+	for relativePath, expected := range map[string]string{
+		"app_1.0_linux_amd64.zip":                       "[]",
+		"linux_arm/app_1.0_linux_arm.tar.gz":            "[]",
+		"app_linux_1.0_windows_amd64.zip":               "[linux]",
+		"linux_arm/app_arm_go1.21_1.0_linux_arm.tar.gz": "[arm go1.21]",
+		"app_pro_edition_1.0_darwin_amd64.zip":          "[pro_edition]",
+		"app_go1.22.1_1.0_linux_amd64.zip":              "[go1.22.1]",
+		"arm/go1.21/app_1.0_armhf.deb":                  "[arm go1.21]",
+		"docs/app_1.0_linux_amd64.zip":                  "[]",
+	} {
+		if parts := fmt.Sprint(getMatrixParts(relativePath, "app", variants)); parts != expected {
+			t.Errorf("%s: expected %s, got %s", relativePath, expected, parts)
+		}
+	}
+}
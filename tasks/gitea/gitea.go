@@ -0,0 +1,230 @@
+package gitea
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/tasks"
+	"github.com/laher/goxc/tasks/httpc"
+	"github.com/laher/goxc/tasks/release"
+)
+
+// The Gitea releases API (v1), for one repository. Forgejo shares it
+type giteaPublisher struct {
+	apiHost    string
+	owner      string
+	repository string
+	token      string
+	isVerbose  bool
+	isQuiet    bool
+}
+
+func RunTaskPubGitea(tp tasks.TaskParams) error {
+	apiHost := strings.TrimSuffix(tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITEA, "apihost"), "/")
+	owner := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITEA, "owner")
+	repository := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITEA, "repository")
+	if repository == "" {
+		repository = tp.Settings.AppName
+	}
+	tokenEnv := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITEA, "token-env")
+	token := ""
+	if tokenEnv != "" {
+		token = os.Getenv(tokenEnv)
+	}
+	missing := []string{}
+	if apiHost == "" {
+		missing = append(missing, "apihost")
+	}
+	if owner == "" {
+		missing = append(missing, "owner")
+	}
+	if repository == "" {
+		missing = append(missing, "repository")
+	}
+	if token == "" {
+		missing = append(missing, "token (environment variable '"+tokenEnv+"')")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("gitea configuration missing (%v)", missing)
+	}
+	p := &giteaPublisher{
+		apiHost:    apiHost,
+		owner:      owner,
+		repository: repository,
+		token:      token,
+		isVerbose:  tp.Settings.IsVerbose(),
+		isQuiet:    tp.Settings.IsQuiet()}
+	downloadURL := func(tagName, name string) string {
+		return apiHost + "/" + url.PathEscape(owner) + "/" + url.PathEscape(repository) + "/releases/download/" + url.PathEscape(tagName) + "/" + url.PathEscape(name)
+	}
+	return release.Publish(p, tasks.TASK_PUBLISH_GITEA, downloadURL, tp)
+}
+
+func (p *giteaPublisher) url(path string) string {
+	return p.apiHost + "/api/v1/repos/" + url.PathEscape(p.owner) + "/" + url.PathEscape(p.repository) + path
+}
+
+func (p *giteaPublisher) do(method, path string, body io.Reader, length int64, contentType string) (*http.Response, error) {
+	headers := map[string]string{"Authorization": "token " + p.token, "Accept": "application/json"}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	return httpc.DoHttpWithHeaders(method, p.url(path), headers, body, length, p.isVerbose)
+}
+
+func (p *giteaPublisher) doJson(method, path string, request interface{}) (*http.Response, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	return p.do(method, path, bytes.NewReader(data), int64(len(data)), "application/json")
+}
+
+//POST /repos/:owner/:repo/releases, or update the release if it exists
+func (p *giteaPublisher) EnsureRelease(r *release.Release) error {
+	request := map[string]interface{}{"tag_name": r.TagName, "name": r.Name, "body": r.Body, "draft": r.Draft, "prerelease": r.Prerelease}
+	resp, err := p.doJson("POST", "/releases", request)
+	if err != nil {
+		return err
+	}
+	created, err := httpc.ParseMap(resp, p.isVerbose)
+	if err == nil {
+		r.ID = fmt.Sprintf("%0.f", created["id"])
+		return nil
+	}
+	if serr, ok := err.(httpc.HttpError); !ok || serr.StatusCode != http.StatusConflict {
+		return err
+	}
+	//existing release. update it
+	if !p.isQuiet {
+		log.Printf("Note: release %s already exists. Updating it", r.TagName)
+	}
+	r.ID, err = p.findRelease(r.TagName)
+	if err != nil {
+		return err
+	}
+	return p.updateRelease(r, map[string]interface{}{"name": r.Name, "body": r.Body, "prerelease": r.Prerelease})
+}
+
+func (p *giteaPublisher) Finalize(r *release.Release) error {
+	return p.updateRelease(r, map[string]interface{}{"draft": false})
+}
+
+//PATCH /repos/:owner/:repo/releases/:id
+func (p *giteaPublisher) updateRelease(r *release.Release, request map[string]interface{}) error {
+	resp, err := p.doJson("PATCH", "/releases/"+r.ID, request)
+	if err != nil {
+		return err
+	}
+	_, err = httpc.ParseMap(resp, p.isVerbose)
+	return err
+}
+
+// The id of the release for a tag. Releases are listed (rather than fetched by tag), so that drafts are found too
+func (p *giteaPublisher) findRelease(tagName string) (string, error) {
+	for page := 1; ; page++ {
+		resp, err := p.do("GET", fmt.Sprintf("/releases?limit=50&page=%d", page), nil, 0, "")
+		if err != nil {
+			return "", err
+		}
+		releases, err := httpc.ParseSlice(resp, p.isVerbose)
+		if err != nil {
+			return "", err
+		}
+		if len(releases) == 0 {
+			return "", fmt.Errorf("Release for tag %s not found", tagName)
+		}
+		for _, r := range releases {
+			if r["tag_name"] == tagName {
+				return fmt.Sprintf("%0.f", r["id"]), nil
+			}
+		}
+	}
+}
+
+//GET /repos/:owner/:repo/releases/:id/assets
+func (p *giteaPublisher) Assets(r *release.Release) ([]release.Asset, error) {
+	resp, err := p.do("GET", "/releases/"+r.ID+"/assets", nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	items, err := httpc.ParseSlice(resp, p.isVerbose)
+	if err != nil {
+		return nil, err
+	}
+	assets := []release.Asset{}
+	for _, item := range items {
+		name, _ := item["name"].(string)
+		assets = append(assets, release.Asset{ID: fmt.Sprintf("%0.f", item["id"]), Name: name})
+	}
+	return assets, nil
+}
+
+//POST /repos/:owner/:repo/releases/:id/assets?name=foo.zip, as a multipart form with the file as 'attachment'
+func (p *giteaPublisher) Upload(r *release.Release, name, fullPath, contentType string) error {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="attachment"; filename="%s"`, strings.Replace(name, `"`, "\\\"", -1)))
+		header.Set("Content-Type", contentType)
+		part, err := form.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, f)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+	resp, err := p.do("POST", "/releases/"+r.ID+"/assets?name="+url.QueryEscape(name), body, 0, form.FormDataContentType())
+	//unblock the writer, if the request failed early
+	body.Close()
+	if err != nil {
+		return err
+	}
+	_, err = httpc.ParseMap(resp, p.isVerbose)
+	return err
+}
+
+//DELETE /repos/:owner/:repo/releases/:id/assets/:attachment_id
+func (p *giteaPublisher) DeleteAsset(r *release.Release, a release.Asset) error {
+	resp, err := p.do("DELETE", "/releases/"+r.ID+"/assets/"+a.ID, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	_, err = httpc.ParseMap(resp, p.isVerbose)
+	return err
+}
@@ -0,0 +1,20 @@
+package gitea
+
+import (
+	"github.com/laher/goxc/tasks"
+	"github.com/laher/goxc/tasks/release"
+)
+
+//runs automatically
+func init() {
+	defaultSettings := release.DefaultSettings("gitea.md")
+	defaultSettings["apihost"] = ""    //e.g. 'https://gitea.example.com'
+	defaultSettings["owner"] = ""      //user or organization
+	defaultSettings["repository"] = "" //defaults to the app name
+	defaultSettings["token-env"] = "GITEA_TOKEN"
+	tasks.Register(tasks.Task{
+		Name:            tasks.TASK_PUBLISH_GITEA,
+		Description:     "Upload artifacts to a Gitea (or Forgejo) release for the tag, creating (or updating) the release, and generate a local markdown page of links. The token is read from the environment variable named by 'token-env'. Set 'draft' for a draft release. Releases are marked prereleases when the version has PrereleaseInfo. See `goxc -h publish-gitea`",
+		Run:             RunTaskPubGitea,
+		DefaultSettings: defaultSettings})
+}
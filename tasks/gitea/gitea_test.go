@@ -0,0 +1,141 @@
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/tasks"
+)
+
+// An in-process Gitea, for one repository
+type fakeGitea struct {
+	sync.Mutex
+	release     map[string]interface{}
+	attachments map[string]string
+	updates     int
+}
+
+func (g *fakeGitea) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	g.Lock()
+	defer g.Unlock()
+	if req.Header.Get("Authorization") != "token s3cret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	prefix := "/api/v1/repos/me/app"
+	if !strings.HasPrefix(req.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	path := strings.TrimPrefix(req.URL.Path, prefix)
+	switch {
+	case req.Method == "POST" && path == "/releases":
+		if g.release != nil {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		json.NewDecoder(req.Body).Decode(&g.release)
+		g.release["id"] = 7
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(g.release)
+	case req.Method == "GET" && path == "/releases":
+		releases := []interface{}{}
+		if req.URL.Query().Get("page") == "1" {
+			releases = append(releases, map[string]interface{}{"id": 3, "tag_name": "v0.9"}, g.release)
+		}
+		json.NewEncoder(w).Encode(releases)
+	case req.Method == "PATCH" && path == "/releases/7":
+		json.NewDecoder(req.Body).Decode(&g.release)
+		g.updates++
+		json.NewEncoder(w).Encode(g.release)
+	case req.Method == "GET" && path == "/releases/7/assets":
+		assets := []map[string]interface{}{}
+		for name := range g.attachments {
+			assets = append(assets, map[string]interface{}{"id": len(name), "name": name})
+		}
+		json.NewEncoder(w).Encode(assets)
+	case req.Method == "POST" && path == "/releases/7/assets":
+		f, _, err := req.FormFile("attachment")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := ioutil.ReadAll(f)
+		g.attachments[req.URL.Query().Get("name")] = string(data)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	case req.Method == "DELETE" && strings.HasPrefix(path, "/releases/7/assets/"):
+		for name := range g.attachments {
+			if fmt.Sprintf("/releases/7/assets/%d", len(name)) == path {
+				delete(g.attachments, name)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestRunTaskPubGitea(t *testing.T) {
+	g := &fakeGitea{attachments: map[string]string{}}
+	server := httptest.NewServer(g)
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "goxc-gitea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	versionDir := filepath.Join(dir, "1.0")
+	os.MkdirAll(filepath.Join(versionDir, "linux_amd64"), 0755)
+	ioutil.WriteFile(filepath.Join(versionDir, "linux_amd64", "app_1.0_linux_amd64.tar.gz"), []byte("tgz"), 0644)
+	ioutil.WriteFile(filepath.Join(versionDir, "app_1.0_windows_amd64.zip"), []byte("zip"), 0644)
+	os.Setenv("GOXC_TEST_GITEA_TOKEN", "s3cret")
+	defer os.Unsetenv("GOXC_TEST_GITEA_TOKEN")
+
+	s := config.Settings{AppName: "app", PackageVersion: "1.0", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, dir)
+	tasks.FillTaskSettingsDefaults(&s)
+	s.TaskSettings[tasks.TASK_PUBLISH_GITEA]["apihost"] = server.URL
+	s.TaskSettings[tasks.TASK_PUBLISH_GITEA]["owner"] = "me"
+	s.TaskSettings[tasks.TASK_PUBLISH_GITEA]["token-env"] = "GOXC_TEST_GITEA_TOKEN"
+	s.TaskSettings[tasks.TASK_PUBLISH_GITEA]["draft"] = true
+	s.TaskSettings[tasks.TASK_TAG]["prefix"] = "v"
+	tp := tasks.TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: dir, Settings: &s}
+	err = RunTaskPubGitea(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.release["tag_name"] != "v1.0" || g.release["draft"] != true || g.release["prerelease"] != false {
+		t.Errorf("unexpected release %v", g.release)
+	}
+	if len(g.attachments) != 2 || g.attachments["app_1.0_windows_amd64.zip"] != "zip" {
+		t.Errorf("unexpected attachments %v", g.attachments)
+	}
+	page, err := ioutil.ReadFile(filepath.Join(versionDir, "gitea.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), server.URL+"/me/app/releases/download/v1.0/app_1.0_windows_amd64.zip") {
+		t.Errorf("unexpected downloads page %s", page)
+	}
+	//again, replacing attachments: the existing release is found and updated
+	g.attachments["app_1.0_windows_amd64.zip"] = "old"
+	s.TaskSettings[tasks.TASK_PUBLISH_GITEA]["exists-action"] = "replace"
+	err = RunTaskPubGitea(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.updates != 1 || len(g.attachments) != 2 || g.attachments["app_1.0_windows_amd64.zip"] != "zip" {
+		t.Errorf("expected an updated release & replaced attachments, got %d updates, attachments %v", g.updates, g.attachments)
+	}
+}
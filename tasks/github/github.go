@@ -15,32 +15,40 @@ package github
    See the License for the specific language governing permissions and
    limitations under the License.
 */
-//TODO: handle conflicts (delete or skip?)
-//TODO: own options for downloadspage
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"net/url"
 	"strings"
 
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
-	"github.com/laher/goxc/core"
 	"github.com/laher/goxc/tasks"
 	"github.com/laher/goxc/tasks/httpc"
+	"github.com/laher/goxc/tasks/release"
 )
 
+// The most items github returns per page (the default is 30)
+const ghPageSize = 100
+
+// The github releases API, for one repository
+type ghPublisher struct {
+	apiHost    string
+	owner      string
+	apikey     string
+	repository string
+	isVerbose  bool
+	isQuiet    bool
+}
+
 func RunTaskPubGH(tp tasks.TaskParams) error {
 	owner := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "owner")
 	apikey := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "apikey")
 	repository := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "repository")
 	apiHost := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "apihost")
-	//downloadsHost := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "downloadshost")
-	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	downloadsHost := strings.TrimSuffix(tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "downloadshost"), "/")
 
 	missing := []string{}
 
@@ -57,211 +65,127 @@ func RunTaskPubGH(tp tasks.TaskParams) error {
 		missing = append(missing, "apihost")
 	}
 	if len(missing) > 0 {
-		return errors.New(fmt.Sprintf("github configuration missing (%v)", missing))
-	}
-	outFilename := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "downloadspage")
-	templateText := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "templateText")
-	templateFile := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "templateFile")
-	format := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "outputFormat")
-	body := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "body")
-	preRelease := tp.Settings.GetTaskSettingBool(tasks.TASK_PUBLISH_GITHUB, "prerelease")
-	if format == "by-file-extension" {
-		if strings.HasSuffix(outFilename, ".md") || strings.HasSuffix(outFilename, ".markdown") {
-			format = "markdown"
-		} else if strings.HasSuffix(outFilename, ".html") || strings.HasSuffix(outFilename, ".htm") {
-			format = "html"
-		} else {
-			//unknown ...
-			format = ""
-		}
-	}
-	templateVars := tp.Settings.GetTaskSettingMap(tasks.TASK_DOWNLOADS_PAGE, "templateExtraVars")
-	reportFilename := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName(), outFilename)
-	_, err := os.Stat(filepath.Dir(reportFilename))
+		return fmt.Errorf("github configuration missing (%v)", missing)
+	}
+	p := &ghPublisher{
+		apiHost:    strings.TrimSuffix(apiHost, "/"),
+		owner:      owner,
+		apikey:     apikey,
+		repository: repository,
+		isVerbose:  tp.Settings.IsVerbose(),
+		isQuiet:    tp.Settings.IsQuiet()}
+	downloadURL := func(tagName, name string) string {
+		return downloadsHost + "/" + owner + "/" + repository + "/releases/download/" + tagName + "/" + name
+	}
+	return release.Publish(p, tasks.TASK_PUBLISH_GITHUB, downloadURL, tp)
+}
+
+// Finds the release for the tag (drafts included), updating it, or creates it
+func (p *ghPublisher) EnsureRelease(r *release.Release) error {
+	existing, err := ghFindRelease(p.apiHost, p.owner, p.apikey, p.repository, r.TagName, p.isVerbose)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return errors.New("No artifacts built for this version yet. Please build some artifacts before running the 'publish-github' task")
-		} else {
-			return err
-		}
+		return err
 	}
-	prefix := tp.Settings.GetTaskSettingString(tasks.TASK_TAG, "prefix")
-	tagName := prefix + tp.Settings.GetFullVersionName()
-	err = createRelease(apiHost, owner, apikey, repository, tagName, tp.Settings.GetFullVersionName(), body, preRelease, tp.Settings.IsVerbose())
-	if err != nil {
-		if serr, ok := err.(httpc.HttpError); ok {
-			if serr.StatusCode == 422 {
-				//existing release. ignore.
-				if !tp.Settings.IsQuiet() {
-					log.Printf("Note: release already exists. %v", serr)
-				}
-			} else {
-				return err
-			}
-		} else {
+	if existing == nil {
+		created, err := createRelease(p.apiHost, p.owner, p.apikey, p.repository, r.TagName, r.Name, r.Body, r.Prerelease, r.Draft, p.isVerbose)
+		if err != nil {
 			return err
 		}
+		r.ID, r.UploadURL, err = ghReleaseIDs(created)
+		return err
 	}
-	report := tasks.BtReport{
-		AppName:    tp.AppName,
-		Version:    tp.Settings.GetFullVersionName(),
-		Categories: map[string]*[]tasks.BtDownload{},
-		ExtraVars:  templateVars}
-	flags := os.O_WRONLY | os.O_TRUNC | os.O_CREATE
-	out, err := os.OpenFile(reportFilename, flags, 0600)
+	r.ID, r.UploadURL, err = ghReleaseIDs(existing)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
-	//for 'first entry in dir' detection.
-	dirs := []string{}
-	err = filepath.Walk(versionDir, func(path string, info os.FileInfo, e error) error {
-		return ghWalkFunc(path, info, e, outFilename, dirs, tp, format, report)
-	})
+	if !p.isQuiet {
+		log.Printf("Note: release %s already exists. Updating it", r.TagName)
+	}
+	return p.updateRelease(r, map[string]interface{}{"name": r.Name, "body": r.Body, "prerelease": r.Prerelease})
+}
+
+func (p *ghPublisher) Finalize(r *release.Release) error {
+	return p.updateRelease(r, map[string]interface{}{"draft": false})
+}
+
+//PATCH /repos/:owner/:repo/releases/:id
+func (p *ghPublisher) updateRelease(r *release.Release, req map[string]interface{}) error {
+	requestData, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
-	err = tasks.RunTemplate(reportFilename, templateFile, templateText, out, report, format)
+	resp, err := httpc.DoHttp("PATCH", p.apiHost+"/repos/"+p.owner+"/"+p.repository+"/releases/"+r.ID, "", p.owner, p.apikey, "application/json", bytes.NewReader(requestData), int64(len(requestData)), p.isVerbose)
 	if err != nil {
 		return err
 	}
-	//close explicitly for return value
-	return out.Close()
+	_, err = httpc.ParseMap(resp, p.isVerbose)
+	return err
 }
 
-func ghWalkFunc(fullPath string, fi2 os.FileInfo, err error, reportFilename string, dirs []string, tp tasks.TaskParams, format string, report tasks.BtReport) error {
-	excludeResources := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "exclude")
-	excludeGlobs := core.ParseCommaGlobs(excludeResources)
-	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
-	relativePath := strings.Replace(fullPath, versionDir, "", -1)
-	relativePath = strings.Replace(relativePath, "\\", "/", -1)
-	relativePath = strings.TrimPrefix(relativePath, "/")
-	//fmt.Printf("relative path %s, full path %s\n", relativePath, fullPath)
-	if fi2.IsDir() {
-		//check globs ...
-		for _, excludeGlob := range excludeGlobs {
-			ok, err := filepath.Match(excludeGlob, fi2.Name())
-			if err != nil {
-				return err
-			}
-			if ok {
-				if tp.Settings.IsVerbose() {
-					log.Printf("Excluded: %s (pattern %v)", relativePath, excludeGlob)
-				}
-				return filepath.SkipDir
-			}
-		}
-		return nil
-	}
-	if fi2.Name() == reportFilename {
-		return nil
-	}
-	owner := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "owner")
-	user := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "user")
-	if user == "" {
-		user = owner
-	}
-	apikey := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "apikey")
-	repository := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "repository")
-	apiHost := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "apihost")
-	//uploadApiHost := "https://uploads.github.com"
-	downloadsHost := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "downloadshost")
-	includeResources := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITHUB, "include")
-
-	resourceGlobs := core.ParseCommaGlobs(includeResources)
-	//log.Printf("IncludeGlobs: %v", resourceGlobs)
-	//log.Printf("ExcludeGlobs: %v", excludeGlobs)
-	matches := false
-	for _, resourceGlob := range resourceGlobs {
-		ok, err := filepath.Match(resourceGlob, fi2.Name())
+// All pages of the release's assets
+//GET /repos/:owner/:repo/releases/:id/assets
+func (p *ghPublisher) Assets(r *release.Release) ([]release.Asset, error) {
+	assets := []release.Asset{}
+	for page := 1; ; page++ {
+		resp, err := httpc.DoHttp("GET", fmt.Sprintf("%s/repos/%s/%s/releases/%s/assets?per_page=%d&page=%d", p.apiHost, p.owner, p.repository, r.ID, ghPageSize, page), "", p.owner, p.apikey, "", nil, 0, p.isVerbose)
 		if err != nil {
-			return err
-		}
-		if ok {
-			matches = true
-		}
-	}
-	if matches == false {
-		if tp.Settings.IsVerbose() {
-			log.Printf("Not included: %s (pattern %v)", relativePath, includeResources)
+			return nil, err
 		}
-		return nil
-	}
-	for _, excludeGlob := range excludeGlobs {
-		ok, err := filepath.Match(excludeGlob, fi2.Name())
+		items, err := httpc.ParseSlice(resp, p.isVerbose)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if ok {
-			if tp.Settings.IsVerbose() {
-				log.Printf("Excluded: %s (pattern %v)", relativePath, excludeGlob)
-			}
-			return nil
+		for _, item := range items {
+			name, _ := item["name"].(string)
+			//e.g. 'sha256:abc...'
+			digest, _ := item["digest"].(string)
+			assets = append(assets, release.Asset{ID: fmt.Sprintf("%0.f", item["id"]), Name: name, SHA256: strings.TrimPrefix(digest, "sha256:")})
 		}
-	}
-	first := true
-
-	parent := filepath.Dir(relativePath)
-	//platform := strings.Replace(parent, "_", "/", -1)
-	//fmt.Fprintf(f, "\n * **%s**:", platform)
-	for _, d := range dirs {
-		if d == parent {
-			first = false
+		if len(items) < ghPageSize {
+			return assets, nil
 		}
 	}
-	if first {
-		dirs = append(dirs, parent)
-	}
-	//fmt.Printf("relative path %s, platform %s\n", relativePath, parent)
-	text := fi2.Name()
-	version := tp.Settings.GetFullVersionName()
-	isVerbose := tp.Settings.IsVerbose()
-	isQuiet := tp.Settings.IsQuiet()
-	contentType := httpc.GetContentType(text)
+}
 
-	prefix := tp.Settings.GetTaskSettingString(tasks.TASK_TAG, "prefix")
-	tagName := prefix + version
-	release, uploadApiHost, err := ghGetReleaseForTag(apiHost, owner, apikey, repository, tagName, isVerbose)
-	if err != nil {
-		return err
-	}
-	err = ghDoUpload(uploadApiHost, apikey, owner, repository, release, relativePath, fullPath, contentType, isVerbose, isQuiet)
+func (p *ghPublisher) Upload(r *release.Release, name, fullPath, contentType string) error {
+	return ghDoUpload(r.UploadURL, p.apikey, p.owner, p.repository, r.ID, name, fullPath, contentType, p.isVerbose, p.isQuiet)
+}
+
+//DELETE /repos/:owner/:repo/releases/assets/:id
+func (p *ghPublisher) DeleteAsset(r *release.Release, a release.Asset) error {
+	resp, err := httpc.DoHttp("DELETE", p.apiHost+"/repos/"+p.owner+"/"+p.repository+"/releases/assets/"+a.ID, "", p.owner, p.apikey, "", nil, 0, p.isVerbose)
 	if err != nil {
 		return err
 	}
-	if first {
-		first = false
-	} else {
-		//commaIfRequired = ","
-	}
-	if format == "markdown" {
-		text = strings.Replace(text, "_", "\\_", -1)
-	}
-	category := tasks.GetCategory(relativePath)
-	downloadsUrl := downloadsHost + "/" + owner + "/" + repository + "/releases/download/" + version + "/" + relativePath + ""
-	download := tasks.BtDownload{Text: text, RelativeLink: downloadsUrl}
-	v, ok := report.Categories[category]
-	var existing []tasks.BtDownload
-	if !ok {
-		existing = []tasks.BtDownload{}
-	} else {
-		existing = *v
-	}
-	existing = append(existing, download)
-	report.Categories[category] = &existing
-
+	_, err = httpc.ParseMap(resp, p.isVerbose)
 	return err
 }
 
-func ghGetReleaseForTag(apihost, owner, apikey, repo, tagName string, isVerbose bool) (string, string, error) {
-	r, err := httpc.DoHttp("GET", apihost+"/repos/"+owner+"/"+repo+"/releases/tags/"+tagName, "", owner, apikey, "", nil, 0, isVerbose)
-	if err != nil {
-		return "", "", err
-	}
-	i, err := httpc.ParseMap(r, isVerbose)
-	if err != nil {
-		return "", "", err
+// The release for a tag, or nil. The releases are listed because drafts can't be fetched by tag
+//GET /repos/:owner/:repo/releases
+func ghFindRelease(apihost, owner, apikey, repo, tagName string, isVerbose bool) (map[string]interface{}, error) {
+	for page := 1; ; page++ {
+		r, err := httpc.DoHttp("GET", fmt.Sprintf("%s/repos/%s/%s/releases?per_page=%d&page=%d", apihost, owner, repo, ghPageSize, page), "", owner, apikey, "", nil, 0, isVerbose)
+		if err != nil {
+			return nil, err
+		}
+		releases, err := httpc.ParseSlice(r, isVerbose)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range releases {
+			if rel["tag_name"] == tagName {
+				return rel, nil
+			}
+		}
+		if len(releases) < ghPageSize {
+			return nil, nil
+		}
 	}
+}
+
+// The id & upload URL of a release
+func ghReleaseIDs(i map[string]interface{}) (string, string, error) {
 	var id string
 	idI, ok := i["id"]
 	if !ok {
@@ -278,34 +202,21 @@ func ghGetReleaseForTag(apihost, owner, apikey, repo, tagName string, isVerbose
 	if !ok {
 		return "", "", fmt.Errorf("Upload URL not provided")
 	}
-	uploadURL := uploadURLi.(string)
+	uploadURL, _ := uploadURLi.(string)
 	uploadURL = strings.Split(uploadURL, "{")[0]
-	return id, uploadURL, err
+	return id, uploadURL, nil
 }
 
 //POST https://<upload_url>/repos/:owner/:repo/releases/:id/assets?name=foo.zip
 func ghDoUpload(apiHost, apikey, owner, repository, release, relativePath, fullPath, contentType string, isVerbose, isQuiet bool) error {
 	//POST /repos/:owner/:repo/releases/:id/assets?name=foo.zip
-	url := apiHost + "?name=" + relativePath
-	if !isQuiet {
-		log.Printf("Uploading to %v", url)
+	uploadURL := apiHost + "?name=" + url.QueryEscape(relativePath)
+	if isVerbose {
+		log.Printf("Uploading to %v", uploadURL)
 	}
-	resp, err := httpc.UploadFile("POST", url, repository, owner, apikey, fullPath, relativePath, contentType, isVerbose)
+	resp, err := httpc.UploadFile("POST", uploadURL, repository, owner, apikey, fullPath, relativePath, contentType, isVerbose)
 	if err != nil {
-		if serr, ok := err.(httpc.HttpError); ok {
-			if serr.StatusCode == 409 || serr.StatusCode == 422 {
-				//conflict. skip
-				//continue but dont publish.
-				//TODO - provide an option to replace existing artifact
-				//TODO - ?check exists before attempting upload?
-				log.Printf("WARNING - file already exists. Skipping. %v", resp)
-				return nil
-			} else {
-				return err
-			}
-		} else {
-			return err
-		}
+		return err
 	}
 	if isVerbose {
 		log.Printf("File uploaded. Response: %v", resp)
@@ -316,26 +227,24 @@ func ghDoUpload(apiHost, apikey, owner, repository, release, relativePath, fullP
 }
 
 //POST /repos/:owner/:repo/releases
-func createRelease(apihost, owner, apikey, repo, tagName, version, body string, preRelease, isVerbose bool) error {
-	req := map[string]interface{}{"tag_name": tagName, "name": version, "body": body, "prerelease": preRelease}
+func createRelease(apihost, owner, apikey, repo, tagName, version, body string, preRelease, draft, isVerbose bool) (map[string]interface{}, error) {
+	req := map[string]interface{}{"tag_name": tagName, "name": version, "body": body, "prerelease": preRelease, "draft": draft}
 	requestData, err := json.Marshal(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	requestLength := len(requestData)
 	reader := bytes.NewReader(requestData)
 	resp, err := httpc.DoHttp("POST", apihost+"/repos/"+owner+"/"+repo+"/releases", owner, owner, apikey, "", reader, int64(requestLength), isVerbose)
-	if err == nil {
-		if isVerbose {
-			log.Printf("Created new version. %v", resp)
-			i, err := httpc.ParseMap(resp, isVerbose)
-			if err != nil {
-				log.Printf("Error parsing response as map: %v", err)
-				return err
-			}
-			log.Printf("Created new version: %+v", i)
-
-		}
+	if err != nil {
+		return nil, err
 	}
-	return err
+	i, err := httpc.ParseMap(resp, isVerbose)
+	if err != nil {
+		return nil, err
+	}
+	if isVerbose {
+		log.Printf("Created new version: %+v", i)
+	}
+	return i, nil
 }
@@ -1,24 +1,18 @@
 package github
 
-import "github.com/laher/goxc/tasks"
+import (
+	"github.com/laher/goxc/tasks"
+	"github.com/laher/goxc/tasks/release"
+)
 
 //runs automatically
 func init() {
-	tasks.Register(tasks.Task{
-		Name:        tasks.TASK_PUBLISH_GITHUB,
-		Description: "Upload artifacts to github.com, and generate a local markdown page of links (github project details required in goxc config. See `goxc -h publish-github`)",
-		Run:         RunTaskPubGH,
-		DefaultSettings: map[string]interface{}{"owner": "", "apikey": "", "repository": "",
-			"apihost":       "https://api.github.com",
-			"prerelease":    false,
-			"body":          "Built by goxc",
-			"downloadshost": "https://github.com/",
-			"downloadspage": "github.md",
-			"fileheader":    "---\nlayout: default\ntitle: Downloads\n---\nFiles hosted at [github.com](https://github.com)\n\n",
-			"include":       "*.zip,*.tar.gz,*.deb",
-			"exclude":       "github.md,.goxc-temp",
-			"outputFormat":  "by-file-extension", // use by-file-extension, markdown or html
-			"templateText": `---
+	defaultSettings := release.DefaultSettings("github.md")
+	for k, v := range map[string]interface{}{"owner": "", "apikey": "", "repository": "",
+		"apihost":       "https://api.github.com",
+		"downloadshost": "https://github.com/",
+		"fileheader":    "---\nlayout: default\ntitle: Downloads\n---\nFiles hosted at [github.com](https://github.com)\n\n",
+		"templateText": `---
 layout: default
 title: Downloads
 ---
@@ -32,9 +26,14 @@ Files hosted at [github.com](https://github.com)
 {{end}}
 {{end}}
 
-{{.ExtraVars.footer}}`,
-			"templateFile":      "", //use if populated
-			"templateExtraVars": map[string]interface{}{"footer": "Generated by goxc"}}})
+{{.ExtraVars.footer}}`} {
+		defaultSettings[k] = v
+	}
+	tasks.Register(tasks.Task{
+		Name:            tasks.TASK_PUBLISH_GITHUB,
+		Description:     "Upload artifacts to github.com releases, and generate a local markdown page of links (github project details required in goxc config. See `goxc -h publish-github`). 'body' is a template over the uploaded downloads when 'body-template' is set. Existing assets are handled according to 'exists-action' (replace, omit or fail)",
+		Run:             RunTaskPubGH,
+		DefaultSettings: defaultSettings})
 }
 
 /*
@@ -1,12 +1,26 @@
 package github
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
 	"github.com/laher/goxc/tasks"
 	"github.com/laher/goxc/tasks/httpc"
+	"github.com/laher/goxc/tasks/release"
 )
 
 var apikey = flag.String("api-key", "", "API key")
@@ -25,18 +39,22 @@ func TestCreateRelease(t *testing.T) {
 		t.Skip("api-key is required to run this integration test")
 	}
 	t.Logf("create release")
-	err := createRelease(apihost, owner, *apikey, repo, tagName, version, "Built by goxc", true, isVerbose)
+	_, err := createRelease(apihost, owner, *apikey, repo, tagName, version, "Built by goxc", true, false, isVerbose)
 	if err != nil {
 		t.Errorf("Error creating release %v", err)
 	}
 }
 
-///repos/:owner/:repo/releases/tags/:tag
+//GET /repos/:owner/:repo/releases
 func TestGetTagRelease(t *testing.T) {
 	if *apikey == "" {
 		t.Skip("api-key is required to run this integration test")
 	}
-	id, uploadURL, err := ghGetReleaseForTag(apihost, owner, *apikey, repo, tagName, isVerbose)
+	r, err := ghFindRelease(apihost, owner, *apikey, repo, tagName, isVerbose)
+	if err != nil || r == nil {
+		t.Fatalf("Error getting release %v", err)
+	}
+	id, uploadURL, err := ghReleaseIDs(r)
 	if err != nil {
 		t.Errorf("Error getting release %v", err)
 	}
@@ -83,20 +101,143 @@ func TestGhDoUpload(t *testing.T) {
 	}
 }
 
-func TestGhWalkFunc(t *testing.T) {
+// An in-process github, for one repository with one release
+type fakeGithub struct {
+	sync.Mutex
+	url      string
+	release  map[string]interface{}
+	assets   map[string][]byte
+	nextID   int
+	uploads  int
+	releases int
+}
+
+func (g *fakeGithub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	g.Lock()
+	defer g.Unlock()
+	body, _ := ioutil.ReadAll(req.Body)
+	switch {
+	case req.Method == "GET" && req.URL.Path == "/repos/me/app/releases":
+		releases := []interface{}{}
+		if g.release != nil && req.URL.Query().Get("page") == "1" {
+			releases = append(releases, g.release)
+		}
+		json.NewEncoder(w).Encode(releases)
+	case req.Method == "POST" && req.URL.Path == "/repos/me/app/releases":
+		json.Unmarshal(body, &g.release)
+		g.release["id"] = 5
+		g.release["upload_url"] = g.url + "/uploads/repos/me/app/releases/5/assets{?name,label}"
+		g.releases++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(g.release)
+	case req.Method == "PATCH" && req.URL.Path == "/repos/me/app/releases/5":
+		json.Unmarshal(body, &g.release)
+		json.NewEncoder(w).Encode(g.release)
+	case req.Method == "GET" && req.URL.Path == "/repos/me/app/releases/5/assets":
+		//paged, like github
+		names := []string{}
+		for name := range g.assets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		perPage, _ := strconv.Atoi(req.URL.Query().Get("per_page"))
+		if perPage == 0 {
+			perPage = 30
+		}
+		page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		assets := []map[string]interface{}{}
+		for i := (page - 1) * perPage; i < len(names) && i < page*perPage; i++ {
+			assets = append(assets, map[string]interface{}{"id": len(names[i]), "name": names[i], "digest": fmt.Sprintf("sha256:%x", sha256.Sum256(g.assets[names[i]]))})
+		}
+		json.NewEncoder(w).Encode(assets)
+	case req.Method == "POST" && req.URL.Path == "/uploads/repos/me/app/releases/5/assets":
+		g.assets[req.URL.Query().Get("name")] = body
+		g.uploads++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	case req.Method == "DELETE" && strings.HasPrefix(req.URL.Path, "/repos/me/app/releases/assets/"):
+		for name := range g.assets {
+			if fmt.Sprintf("/repos/me/app/releases/assets/%d", len(name)) == req.URL.Path {
+				delete(g.assets, name)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
 
-	t.Skip()
-	fullPath := ""
-	var fi2 os.FileInfo
-	var errIn error
-	reportFilename := ""
-	dirs := []string{}
-	var tp tasks.TaskParams
-	var report tasks.BtReport
-	format := ""
-	err := ghWalkFunc(fullPath, fi2, errIn, reportFilename, dirs, tp, format, report)
+func TestRunTaskPubGH(t *testing.T) {
+	g := &fakeGithub{assets: map[string][]byte{}}
+	server := httptest.NewServer(g)
+	defer server.Close()
+	g.url = server.URL
+	dir, err := ioutil.TempDir("", "goxc-github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	versionDir := filepath.Join(dir, "1.0")
+	os.MkdirAll(filepath.Join(versionDir, "linux_amd64"), 0755)
+	ioutil.WriteFile(filepath.Join(versionDir, "linux_amd64", "app_1.0_linux_amd64.tar.gz"), []byte("tgz"), 0644)
+	ioutil.WriteFile(filepath.Join(versionDir, "app_1.0_windows_amd64.zip"), []byte("zip"), 0644)
+
+	s := config.Settings{AppName: "app", PackageVersion: "1.0", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, dir)
+	tasks.FillTaskSettingsDefaults(&s)
+	for k, v := range map[string]string{"owner": "me", "repository": "app", "apikey": "k", "apihost": server.URL, "exists-action": "fail"} {
+		s.TaskSettings[tasks.TASK_PUBLISH_GITHUB][k] = v
+	}
+	tp := tasks.TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: dir, Settings: &s}
+	err = RunTaskPubGH(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//uploaded to a draft, which was then published
+	if g.releases != 1 || g.release["draft"] != false || len(g.assets) != 2 || string(g.assets["app_1.0_windows_amd64.zip"]) != "zip" {
+		t.Errorf("unexpected release %v, assets %v", g.release, g.assets)
+	}
+	//re-run, after a partial failure: the (literal) body is updated, unchanged assets are skipped and changed ones fail
+	s.TaskSettings[tasks.TASK_PUBLISH_GITHUB]["body"] = "Built by {{goxc}}, again"
+	delete(g.assets, "app_1.0_windows_amd64.zip")
+	err = RunTaskPubGH(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.releases != 1 || g.uploads != 3 || g.release["body"] != "Built by {{goxc}}, again" {
+		t.Errorf("expected an idempotent publish, got %d releases, %d uploads, release %v", g.releases, g.uploads, g.release)
+	}
+	g.assets["app_1.0_windows_amd64.zip"] = []byte("changed")
+	err = RunTaskPubGH(tp)
+	if err == nil {
+		t.Errorf("expected an error for a changed asset")
+	}
+	s.TaskSettings[tasks.TASK_PUBLISH_GITHUB]["exists-action"] = "replace"
+	err = RunTaskPubGH(tp)
 	if err != nil {
+		t.Fatal(err)
+	}
+	if g.uploads != 4 || string(g.assets["app_1.0_windows_amd64.zip"]) != "zip" {
+		t.Errorf("expected a replaced asset, got %d uploads, assets %v", g.uploads, g.assets)
+	}
+}
 
-		t.Errorf("Error doing walkFunc %v", err)
+func TestAssetsPages(t *testing.T) {
+	g := &fakeGithub{assets: map[string][]byte{}}
+	server := httptest.NewServer(g)
+	defer server.Close()
+	for i := 0; i < 250; i++ {
+		g.assets[fmt.Sprintf("app_%d.zip", i)] = []byte("zip")
+	}
+	p := &ghPublisher{apiHost: server.URL, owner: "me", apikey: "k", repository: "app", isQuiet: true}
+	assets, err := p.Assets(&release.Release{ID: "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 250 {
+		t.Errorf("expected all 250 assets, got %d", len(assets))
 	}
 }
@@ -0,0 +1,314 @@
+package gitlab
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/tasks"
+	"github.com/laher/goxc/tasks/httpc"
+	"github.com/laher/goxc/tasks/release"
+)
+
+// The GitLab REST API (v4), for one project
+type gitlabClient struct {
+	apiHost   string
+	project   string
+	token     string
+	isJob     bool
+	isVerbose bool
+}
+
+func RunTaskPubGitlab(tp tasks.TaskParams) error {
+	apiHost := strings.TrimSuffix(tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITLAB, "apihost"), "/")
+	project := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITLAB, "project")
+	tokenEnv := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITLAB, "token-env")
+	token := ""
+	if tokenEnv != "" {
+		token = os.Getenv(tokenEnv)
+	}
+	missing := []string{}
+	if apiHost == "" {
+		missing = append(missing, "apihost")
+	}
+	if project == "" {
+		missing = append(missing, "project")
+	}
+	if token == "" {
+		missing = append(missing, "token (environment variable '"+tokenEnv+"')")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("gitlab configuration missing (%v)", missing)
+	}
+	client := &gitlabClient{
+		apiHost:   apiHost,
+		project:   project,
+		token:     token,
+		isJob:     tokenEnv == "CI_JOB_TOKEN",
+		isVerbose: tp.Settings.IsVerbose()}
+	packageName := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITLAB, "package-name")
+	if packageName == "" {
+		packageName = tp.Settings.AppName
+	}
+	version := tp.Settings.GetFullVersionName()
+	tagName := tp.Settings.GetTaskSettingString(tasks.TASK_TAG, "prefix") + version
+	artifacts, err := release.FindArtifacts(tasks.TASK_PUBLISH_GITLAB, "", tp)
+	if err != nil {
+		return err
+	}
+	existing, err := client.packageFiles(packageName, version)
+	if err != nil {
+		return err
+	}
+	existsAction := strings.ToLower(tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_GITLAB, "exists-action"))
+	links := map[string]string{}
+	for _, a := range artifacts {
+		downloadURL := client.packageFileURL(packageName, version, a.Name)
+		upload, err := client.applyExistsAction(a, existing, existsAction, downloadURL, tp)
+		if err != nil {
+			return err
+		}
+		if upload {
+			if !tp.Settings.IsQuiet() {
+				log.Printf("Uploading %s to the %s package", a.RelativePath, packageName)
+			}
+			err = client.uploadPackageFile(downloadURL, a.FullPath)
+			if err != nil {
+				return err
+			}
+		}
+		links[a.Name] = downloadURL
+	}
+	report := release.Report(tasks.TASK_PUBLISH_GITLAB, artifacts, true, func(name string) string {
+		return links[name]
+	}, tp)
+	description, err := release.Body(tasks.TASK_PUBLISH_GITLAB, report, tp)
+	if err != nil {
+		return err
+	}
+	err = client.createOrUpdateRelease(tagName, version, description, tp.Settings.IsQuiet())
+	if err != nil {
+		return err
+	}
+	return client.linkAssets(tagName, links, tp.Settings.IsQuiet())
+}
+
+func (c *gitlabClient) url(path string) string {
+	return c.apiHost + "/api/v4/projects/" + url.PathEscape(c.project) + path
+}
+
+func (c *gitlabClient) do(method, path string, body io.Reader, length int64, contentType string) (*http.Response, error) {
+	return httpc.DoHttpWithHeaders(method, c.url(path), c.headers(contentType), body, length, c.isVerbose)
+}
+
+func (c *gitlabClient) headers(contentType string) map[string]string {
+	headers := map[string]string{}
+	//CI jobs authenticate differently
+	if c.isJob {
+		headers["JOB-TOKEN"] = c.token
+	} else {
+		headers["PRIVATE-TOKEN"] = c.token
+	}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	return headers
+}
+
+func (c *gitlabClient) doJson(method, path string, request interface{}) (*http.Response, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(method, path, bytes.NewReader(data), int64(len(data)), "application/json")
+}
+
+// The download URL of a generic package file
+func (c *gitlabClient) packageFileURL(packageName, version, name string) string {
+	return c.url("/packages/generic/" + url.PathEscape(packageName) + "/" + url.PathEscape(version) + "/" + url.PathEscape(name))
+}
+
+//PUT /projects/:id/packages/generic/:package_name/:package_version/:file_name
+func (c *gitlabClient) uploadPackageFile(fileURL, fullPath string) error {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	headers := c.headers("application/octet-stream")
+	resp, err := httpc.DoHttpWithHeaders("PUT", fileURL, headers, f, fi.Size(), c.isVerbose)
+	if err != nil {
+		return err
+	}
+	_, err = httpc.ParseMap(resp, c.isVerbose)
+	return err
+}
+
+// The files already in the package version, if any.
+// GET /projects/:id/packages?package_type=generic&package_name=:name, then GET /projects/:id/packages/:package_id/package_files
+func (c *gitlabClient) packageFiles(packageName, version string) ([]map[string]interface{}, error) {
+	resp, err := c.do("GET", "/packages?package_type=generic&per_page=100&package_name="+url.QueryEscape(packageName), nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	packages, err := httpc.ParseSlice(resp, c.isVerbose)
+	if err != nil {
+		return nil, err
+	}
+	files := []map[string]interface{}{}
+	for _, p := range packages {
+		//package_name matches by prefix, and older GitLabs can't filter by version
+		if p["name"] != packageName || p["version"] != version {
+			continue
+		}
+		resp, err := c.do("GET", fmt.Sprintf("/packages/%.0f/package_files?per_page=100", p["id"]), nil, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		packageFiles, err := httpc.ParseSlice(resp, c.isVerbose)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range packageFiles {
+			f["package_id"] = p["id"]
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// Whether to upload an artifact. Files with the same content are left alone; others are handled according to the exists-action.
+// (GitLab keeps every file uploaded with the same name, so replacing means deleting them all)
+func (c *gitlabClient) applyExistsAction(a release.Artifact, existing []map[string]interface{}, existsAction, location string, tp tasks.TaskParams) (bool, error) {
+	same := []map[string]interface{}{}
+	for _, f := range existing {
+		if f["file_name"] == a.Name {
+			same = append(same, f)
+		}
+	}
+	if len(same) == 0 {
+		return true, nil
+	}
+	if checksum, _ := same[len(same)-1]["file_sha256"].(string); checksum != "" {
+		localChecksum, err := release.FileSHA256(a.FullPath)
+		if err != nil {
+			return false, err
+		}
+		if strings.EqualFold(checksum, localChecksum) {
+			if !tp.Settings.IsQuiet() {
+				log.Printf("Skipping %s, which is already published (same sha256)", a.Name)
+			}
+			return false, nil
+		}
+	}
+	return tasks.PublishExistsAction("gitlab", existsAction, a.Name, location, func() error {
+		for _, f := range same {
+			//DELETE /projects/:id/packages/:package_id/package_files/:id
+			resp, err := c.do("DELETE", fmt.Sprintf("/packages/%.0f/package_files/%.0f", f["package_id"], f["id"]), nil, 0, "")
+			if err != nil {
+				return err
+			}
+			_, err = httpc.ParseMap(resp, c.isVerbose)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, tp)
+}
+
+//POST /projects/:id/releases, or PUT /projects/:id/releases/:tag_name if it exists
+func (c *gitlabClient) createOrUpdateRelease(tagName, name, description string, isQuiet bool) error {
+	resp, err := c.doJson("POST", "/releases", map[string]interface{}{"tag_name": tagName, "name": name, "description": description})
+	if err != nil {
+		return err
+	}
+	_, err = httpc.ParseMap(resp, c.isVerbose)
+	if serr, ok := err.(httpc.HttpError); ok && serr.StatusCode == http.StatusConflict {
+		//existing release. update it
+		if !isQuiet {
+			log.Printf("Note: release %s already exists. Updating it", tagName)
+		}
+		resp, err = c.doJson("PUT", "/releases/"+url.PathEscape(tagName), map[string]interface{}{"name": name, "description": description})
+		if err != nil {
+			return err
+		}
+		_, err = httpc.ParseMap(resp, c.isVerbose)
+	}
+	return err
+}
+
+// Adds a release link for each asset, or updates links of the same name
+func (c *gitlabClient) linkAssets(tagName string, links map[string]string, isQuiet bool) error {
+	path := "/releases/" + url.PathEscape(tagName) + "/assets/links"
+	resp, err := c.do("GET", path, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	existing, err := httpc.ParseSlice(resp, c.isVerbose)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for name := range links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		request := map[string]interface{}{"name": name, "url": links[name], "link_type": "package"}
+		method, linkPath := "POST", path
+		for _, link := range existing {
+			if link["name"] == name {
+				if link["url"] == links[name] {
+					method = ""
+				} else {
+					method, linkPath = "PUT", fmt.Sprintf("%s/%.0f", path, link["id"])
+				}
+			}
+		}
+		if method == "" {
+			if !isQuiet {
+				log.Printf("Release link for %s exists already", name)
+			}
+			continue
+		}
+		resp, err := c.doJson(method, linkPath, request)
+		if err != nil {
+			return err
+		}
+		_, err = httpc.ParseMap(resp, c.isVerbose)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
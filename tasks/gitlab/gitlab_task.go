@@ -0,0 +1,32 @@
+package gitlab
+
+import "github.com/laher/goxc/tasks"
+
+//runs automatically
+func init() {
+	tasks.Register(tasks.Task{
+		Name:        tasks.TASK_PUBLISH_GITLAB,
+		Description: "Upload artifacts to a GitLab project's generic package registry, and create (or update) the release for the tag, with links to them. Package files already uploaded are handled according to 'exists-action'. The token is read from the environment variable named by 'token-env' (use CI_JOB_TOKEN in GitLab CI). See `goxc -h publish-gitlab`",
+		Run:         RunTaskPubGitlab,
+		DefaultSettings: map[string]interface{}{
+			"apihost":       "https://gitlab.com",
+			"project":       "", //e.g. 'group/app', or the numeric id
+			"token-env":     "GITLAB_TOKEN",
+			"package-name":  "", //defaults to the app name
+			"include":       "*.zip,*.tar.gz,*.deb",
+			"exclude":       "*.orig.tar.gz,data.tar.gz,control.tar.gz,*.debian.tar.gz,*-dev_*.deb",
+			"exists-action": "omit", //replace, omit (or skip) or fail. Unchanged package files are always skipped
+			"body-file":     "",     //e.g. RELEASE_NOTES.md (see the 'release-notes' task), within the version directory. Replaces the description template
+			//the release description, as for the downloads page
+			"templateText": `{{.AppName}} downloads (version {{.Version}})
+
+{{range $k, $v := .Categories}}### {{$k}}
+
+{{range $v}} * [{{.Text}}]({{.RelativeLink}})
+{{end}}
+{{end}}
+
+{{.ExtraVars.footer}}`,
+			"templateFile":      "", //use if populated
+			"templateExtraVars": map[string]interface{}{"footer": "Generated by goxc"}}})
+}
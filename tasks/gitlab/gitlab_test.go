@@ -0,0 +1,198 @@
+package gitlab
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/tasks"
+)
+
+type packageFile struct {
+	//empty once deleted
+	name    string
+	content string
+}
+
+// The content of the package files with the name
+func (g *fakeGitlab) packageContents(name string) []string {
+	contents := []string{}
+	for _, f := range g.packages {
+		if f.name == name {
+			contents = append(contents, f.content)
+		}
+	}
+	return contents
+}
+
+// An in-process GitLab, for one project
+type fakeGitlab struct {
+	sync.Mutex
+	packages    []packageFile
+	deletes     int
+	release     map[string]interface{}
+	links       []map[string]interface{}
+	updates     int
+	linkUpdates int
+}
+
+func (g *fakeGitlab) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	g.Lock()
+	defer g.Unlock()
+	if req.Header.Get("PRIVATE-TOKEN") != "s3cret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	prefix := "/api/v4/projects/group%2Fapp"
+	if !strings.HasPrefix(req.URL.EscapedPath(), prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	path := strings.TrimPrefix(req.URL.EscapedPath(), prefix)
+	body, _ := ioutil.ReadAll(req.Body)
+	var request map[string]interface{}
+	json.Unmarshal(body, &request)
+	switch {
+	case req.Method == "PUT" && strings.HasPrefix(path, "/packages/generic/app/1.0/"):
+		//like GitLab, keep duplicates
+		g.packages = append(g.packages, packageFile{strings.TrimPrefix(path, "/packages/generic/app/1.0/"), string(body)})
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"message":"201 Created"}`)
+	case req.Method == "GET" && path == "/packages":
+		if req.URL.Query().Get("package_name") != "app" || len(g.packages) == 0 {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[{"id":7,"name":"app","version":"1.0","package_type":"generic"},{"id":8,"name":"app","version":"0.9","package_type":"generic"}]`)
+	case req.Method == "GET" && path == "/packages/7/package_files":
+		files := []map[string]interface{}{}
+		for i, f := range g.packages {
+			sum := sha256.Sum256([]byte(f.content))
+			files = append(files, map[string]interface{}{"id": i + 100, "file_name": f.name, "file_sha256": hex.EncodeToString(sum[:])})
+		}
+		data, _ := json.Marshal(files)
+		w.Write(data)
+	case req.Method == "DELETE" && strings.HasPrefix(path, "/packages/7/package_files/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "/packages/7/package_files/"))
+		g.packages[id-100].name = ""
+		g.deletes++
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == "POST" && path == "/releases":
+		if g.release != nil {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"message":"Release already exists"}`)
+			return
+		}
+		g.release = request
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	case req.Method == "PUT" && path == "/releases/v1.0":
+		g.release["description"] = request["description"]
+		g.updates++
+		fmt.Fprint(w, `{}`)
+	case req.Method == "GET" && path == "/releases/v1.0/assets/links":
+		data, _ := json.Marshal(g.links)
+		w.Write(data)
+	case req.Method == "POST" && path == "/releases/v1.0/assets/links":
+		request["id"] = float64(len(g.links) + 1000000)
+		g.links = append(g.links, request)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	case req.Method == "PUT" && strings.HasPrefix(path, "/releases/v1.0/assets/links/"):
+		for _, link := range g.links {
+			if fmt.Sprintf("/releases/v1.0/assets/links/%.0f", link["id"]) == path {
+				link["url"] = request["url"]
+				g.linkUpdates++
+			}
+		}
+		fmt.Fprint(w, `{}`)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestRunTaskPubGitlab(t *testing.T) {
+	g := &fakeGitlab{}
+	server := httptest.NewServer(g)
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "goxc-gitlab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	versionDir := filepath.Join(dir, "1.0")
+	os.MkdirAll(filepath.Join(versionDir, "linux_amd64"), 0755)
+	ioutil.WriteFile(filepath.Join(versionDir, "linux_amd64", "app_1.0_linux_amd64.tar.gz"), []byte("tgz"), 0644)
+	ioutil.WriteFile(filepath.Join(versionDir, "app_1.0_windows_amd64.zip"), []byte("zip"), 0644)
+	ioutil.WriteFile(filepath.Join(versionDir, "notes.txt"), []byte("excluded"), 0644)
+	os.Setenv("GOXC_TEST_GITLAB_TOKEN", "s3cret")
+	defer os.Unsetenv("GOXC_TEST_GITLAB_TOKEN")
+
+	s := config.Settings{AppName: "app", PackageVersion: "1.0", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, dir)
+	tasks.FillTaskSettingsDefaults(&s)
+	s.TaskSettings[tasks.TASK_PUBLISH_GITLAB]["apihost"] = server.URL
+	s.TaskSettings[tasks.TASK_PUBLISH_GITLAB]["project"] = "group/app"
+	s.TaskSettings[tasks.TASK_PUBLISH_GITLAB]["token-env"] = "GOXC_TEST_GITLAB_TOKEN"
+	s.TaskSettings[tasks.TASK_TAG]["prefix"] = "v"
+	tp := tasks.TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: dir, Settings: &s}
+	err = RunTaskPubGitlab(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.packages) != 2 || fmt.Sprint(g.packageContents("app_1.0_windows_amd64.zip")) != "[zip]" {
+		t.Errorf("unexpected packages %v", g.packages)
+	}
+	zipURL := server.URL + "/api/v4/projects/group%2Fapp/packages/generic/app/1.0/app_1.0_windows_amd64.zip"
+	description, _ := g.release["description"].(string)
+	if g.release["tag_name"] != "v1.0" || !strings.Contains(description, "### MS Windows") || !strings.Contains(description, "[app\\_1.0\\_windows\\_amd64.zip]("+zipURL+")") {
+		t.Errorf("unexpected release %v", g.release)
+	}
+	if len(g.links) != 2 || g.links[1]["url"] != zipURL || g.links[1]["link_type"] != "package" {
+		t.Errorf("unexpected links %v", g.links)
+	}
+	//again, with a moved link: the release is updated, and links are neither duplicated nor left stale
+	g.links[0]["url"] = "https://example.com/old"
+	err = RunTaskPubGitlab(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.updates != 1 || len(g.links) != 2 || g.linkUpdates != 1 || g.links[0]["url"] == "https://example.com/old" {
+		t.Errorf("expected an idempotent publish, got %d updates, links %v", g.updates, g.links)
+	}
+	if len(g.packages) != 2 {
+		t.Errorf("expected unchanged package files to be skipped, got %v", g.packages)
+	}
+	//a changed file is omitted by default, or replaced
+	ioutil.WriteFile(filepath.Join(versionDir, "app_1.0_windows_amd64.zip"), []byte("new zip"), 0644)
+	err = RunTaskPubGitlab(tp)
+	if err != nil || len(g.packages) != 2 {
+		t.Fatalf("expected the changed file to be omitted: %v %v", err, g.packages)
+	}
+	s.TaskSettings[tasks.TASK_PUBLISH_GITLAB]["exists-action"] = "replace"
+	err = RunTaskPubGitlab(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.deletes != 1 || fmt.Sprint(g.packageContents("app_1.0_windows_amd64.zip")) != "[new zip]" {
+		t.Errorf("expected a replaced file, got %d deletes, %v", g.deletes, g.packageContents("app_1.0_windows_amd64.zip"))
+	}
+	s.TaskSettings[tasks.TASK_PUBLISH_GITLAB]["exists-action"] = "fail"
+	ioutil.WriteFile(filepath.Join(versionDir, "app_1.0_windows_amd64.zip"), []byte("newer zip"), 0644)
+	err = RunTaskPubGitlab(tp)
+	if err == nil {
+		t.Errorf("expected an error for a changed file")
+	}
+}
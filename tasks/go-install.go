@@ -33,7 +33,8 @@ func init() {
 
 func runTaskGoInstall(tp TaskParams) error {
 	for _, mainDir := range tp.MainDirs {
-		err := executils.InvokeGo(mainDir, "install", []string{}, []string{}, tp.Settings)
+		goDir, target := tp.GoBuildTarget(mainDir)
+		err := executils.InvokeGo(goDir, "install", []string{target}, []string{}, tp.Settings)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,173 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/template"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/packaging/homebrew"
+	"github.com/laher/goxc/platforms"
+)
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_HOMEBREW,
+		"Generates a Homebrew formula (or, with 'type' set to 'cask', a cask) with the url and sha256 of each darwin & linux archive. 'url-template' should match the publisher's download URLs (the default matches 'publish-github'). Set 'tap-dir' to a local clone of a tap, to commit the formula there.",
+		runTaskHomebrew,
+		map[string]interface{}{
+			"type":           "formula",
+			"name":           "",
+			"description":    "",
+			"homepage":       "",
+			"license":        "",
+			"url-template":   RELEASE_URL_TEMPLATE_GITHUB,
+			"tap-dir":        "",
+			"commit-message": "{{.Name}} {{.Version}}"}})
+}
+
+func runTaskHomebrew(tp TaskParams) error {
+	formulaType := tp.Settings.GetTaskSettingString(TASK_HOMEBREW, "type")
+	if formulaType != "formula" && formulaType != "cask" {
+		return fmt.Errorf("Unsupported homebrew type '%s'. Use 'formula' or 'cask'", formulaType)
+	}
+	urlTemplate, err := template.New("url-template").Parse(tp.Settings.GetTaskSettingString(TASK_HOMEBREW, "url-template"))
+	if err != nil {
+		return err
+	}
+	formula := homebrew.Formula{
+		Name:        tp.Settings.GetTaskSettingString(TASK_HOMEBREW, "name"),
+		Description: tp.Settings.GetTaskSettingString(TASK_HOMEBREW, "description"),
+		Homepage:    tp.Settings.GetTaskSettingString(TASK_HOMEBREW, "homepage"),
+		Version:     tp.Settings.GetFullVersionName(),
+		License:     tp.Settings.GetTaskSettingString(TASK_HOMEBREW, "license")}
+	if formula.Name == "" {
+		formula.Name = tp.Settings.AppName
+	}
+	for _, mainDir := range tp.MainDirs {
+		if len(tp.MainDirs) == 1 {
+			formula.Binaries = append(formula.Binaries, tp.Settings.AppName)
+		} else {
+			formula.Binaries = append(formula.Binaries, filepath.Base(mainDir))
+		}
+	}
+	urlVars := newReleaseURLVars(tp)
+	for _, dest := range tp.DestPlatforms {
+		if dest.Os != platforms.DARWIN && dest.Os != platforms.LINUX {
+			continue
+		}
+		a, err := findReleaseArchive(dest, []string{"tar.gz", "zip"}, urlTemplate, urlVars, tp)
+		if err != nil {
+			return err
+		}
+		if a != nil {
+			formula.Archives = append(formula.Archives, homebrew.Archive{Os: dest.Os, Arch: dest.Arch, URL: a.URL, Sha256: a.Sha256, Dir: a.Dir})
+		}
+	}
+	var rb []byte
+	var tapSubdir string
+	if formulaType == "cask" {
+		rb, err = formula.Cask()
+		tapSubdir = "Casks"
+	} else {
+		rb, err = formula.Ruby()
+		tapSubdir = "Formula"
+	}
+	if err != nil {
+		return err
+	}
+	rbPath := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName(), formula.Name+".rb")
+	err = ioutil.WriteFile(rbPath, rb, 0644)
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Created Homebrew %s %s", formulaType, rbPath)
+	}
+	tapDir := tp.Settings.GetTaskSettingString(TASK_HOMEBREW, "tap-dir")
+	if tapDir == "" {
+		return nil
+	}
+	if !filepath.IsAbs(tapDir) {
+		tapDir = filepath.Join(tp.WorkingDirectory, tapDir)
+	}
+	return homebrewCommitToTap(tapDir, filepath.Join(tapSubdir, formula.Name+".rb"), rb, formula, tp)
+}
+
+// Writes the formula into the tap's clone and commits it, using git as the 'tag' task does
+func homebrewCommitToTap(tapDir, relativePath string, rb []byte, formula homebrew.Formula, tp TaskParams) error {
+	if exists, _ := core.FileExists(filepath.Join(tapDir, ".git")); !exists {
+		return errors.New("homebrew 'tap-dir' is not a git clone: " + tapDir)
+	}
+	err := os.MkdirAll(filepath.Join(tapDir, filepath.Dir(relativePath)), 0777)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(tapDir, relativePath), rb, 0644)
+	if err != nil {
+		return err
+	}
+	messageTemplate, err := template.New("commit-message").Parse(tp.Settings.GetTaskSettingString(TASK_HOMEBREW, "commit-message"))
+	if err != nil {
+		return err
+	}
+	message := &bytes.Buffer{}
+	err = messageTemplate.Execute(message, formula)
+	if err != nil {
+		return err
+	}
+	err = homebrewGit(tapDir, tp, "add", filepath.ToSlash(relativePath))
+	if err != nil {
+		return err
+	}
+	//'diff --quiet' succeeds when there's nothing to commit. Anything else staged in the tap is left alone
+	if homebrewGit(tapDir, tp, "diff", "--cached", "--quiet", "--", filepath.ToSlash(relativePath)) == nil {
+		if !tp.Settings.IsQuiet() {
+			log.Printf("Homebrew tap already up to date (%s)", relativePath)
+		}
+		return nil
+	}
+	err = homebrewGit(tapDir, tp, "commit", "-m", message.String(), "--", filepath.ToSlash(relativePath))
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Committed %s to the Homebrew tap at %s", relativePath, tapDir)
+	}
+	return nil
+}
+
+func homebrewGit(tapDir string, tp TaskParams, args ...string) error {
+	cmd := exec.Command("git")
+	err := executils.PrepareCmd(cmd, tapDir, args, []string{}, tp.Settings.IsVerbose())
+	if err != nil {
+		return err
+	}
+	return executils.StartAndWait(cmd)
+}
@@ -101,13 +101,22 @@ func httpWalk(config *httpTaskConfig, fullPath string, fi os.FileInfo, err error
 	if !tp.Settings.IsQuiet() {
 		log.Printf("Considering %s", relativePath)
 	}
-	resourceGlobs := core.ParseCommaGlobs(config.includePatterns)
-	excludeGlobs := core.ParseCommaGlobs(config.excludePatterns)
+	included, err := PublishIncluded(config.includePatterns, config.excludePatterns, relativePath, fi, tp)
+	if err != nil || !included {
+		return err
+	}
+	return httpUploadFile(config, fullPath, fi, tp)
+}
+
+// PublishIncluded reports whether an artifact matches one of the comma-separated 'include' globs, and none of the 'exclude' globs
+func PublishIncluded(includePatterns, excludePatterns, relativePath string, fi os.FileInfo, tp TaskParams) (bool, error) {
+	resourceGlobs := core.ParseCommaGlobs(includePatterns)
+	excludeGlobs := core.ParseCommaGlobs(excludePatterns)
 	matches := false
 	for _, resourceGlob := range resourceGlobs {
 		ok, err := filepath.Match(resourceGlob, fi.Name())
 		if err != nil {
-			return err
+			return false, err
 		}
 		if ok {
 			matches = true
@@ -117,21 +126,21 @@ func httpWalk(config *httpTaskConfig, fullPath string, fi os.FileInfo, err error
 		if !tp.Settings.IsQuiet() {
 			log.Printf("Not including %s (for include patterns %s)", relativePath, strings.Join(resourceGlobs, ", "))
 		}
-		return nil
+		return false, nil
 	}
 	for _, excludeGlob := range excludeGlobs {
 		ok, err := filepath.Match(excludeGlob, fi.Name())
 		if err != nil {
-			return err
+			return false, err
 		}
 		if ok {
 			if !tp.Settings.IsQuiet() {
 				log.Printf("Excluding %s (for exclude pattern %s)", relativePath, excludeGlob)
 			}
-			return nil
+			return false, nil
 		}
 	}
-	return httpUploadFile(config, fullPath, fi, tp)
+	return true, nil
 }
 
 func httpURLTemplateContext(tp TaskParams, fi os.FileInfo) map[string]interface{} {
@@ -178,6 +187,28 @@ func httpDeleteFile(config *httpTaskConfig, url string) error {
 	return nil
 }
 
+// PublishExistsAction applies an 'exists-action' to an artifact which is already published: 'replace' (calling 'remove' first), 'omit' (or 'skip') or 'fail'.
+// 'publisher' names the kind of publish (e.g. 'http') in errors. Returns whether to go ahead with the upload
+func PublishExistsAction(publisher, action, name, location string, remove func() error, tp TaskParams) (bool, error) {
+	switch action {
+	case "replace":
+		if !tp.Settings.IsQuiet() {
+			log.Printf("Deleting existent file %v at %v", name, location)
+		}
+		if err := remove(); err != nil {
+			return false, err
+		}
+	case "omit", "skip":
+		if !tp.Settings.IsQuiet() {
+			log.Printf("Omitting existent file %v at %v", name, location)
+		}
+		return false, nil
+	case "fail":
+		return false, fmt.Errorf("Failing %s publish of %v because it already exists at %v", publisher, name, location)
+	}
+	return true, nil
+}
+
 func httpUploadFile(config *httpTaskConfig, fullPath string, fi os.FileInfo, tp TaskParams) error {
 	var urlb bytes.Buffer
 	err := config.urlTemplate.Execute(&urlb, httpURLTemplateContext(tp, fi))
@@ -190,21 +221,11 @@ func httpUploadFile(config *httpTaskConfig, fullPath string, fi os.FileInfo, tp
 		return err
 	}
 	if exists {
-		switch config.exists {
-		case "replace":
-			if !tp.Settings.IsQuiet() {
-				log.Printf("Deleting existent file %v at %v", fi.Name(), url)
-			}
-			if err := httpDeleteFile(config, url); err != nil {
-				return err
-			}
-		case "omit":
-			if !tp.Settings.IsQuiet() {
-				log.Printf("Omitting existent file %v at %v", fi.Name(), url)
-			}
-			return nil
-		case "fail":
-			return fmt.Errorf("Failing http publish of %v because it already exists at %v", fi.Name(), url)
+		upload, err := PublishExistsAction("http", config.exists, fi.Name(), url, func() error {
+			return httpDeleteFile(config, url)
+		}, tp)
+		if err != nil || !upload {
+			return err
 		}
 	}
 	if !tp.Settings.IsQuiet() {
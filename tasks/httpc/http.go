@@ -1,6 +1,7 @@
 package httpc
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -22,24 +23,31 @@ func (e HttpError) Error() string {
 }
 
 func DoHttp(method, url, _deprecated, user, apikey, contentType string, requestReader io.Reader, requestLength int64, isVerbose bool) (*http.Response, error) {
+	headers := map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+apikey))}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	return DoHttpWithHeaders(method, url, headers, requestReader, requestLength, isVerbose)
+}
+
+// Like DoHttp, for other kinds of authorization (e.g. bearer tokens) or none
+func DoHttpWithHeaders(method, url string, headers map[string]string, requestReader io.Reader, requestLength int64, isVerbose bool) (*http.Response, error) {
 	client := &http.Client{}
 	req, err := http.NewRequest(method, url, requestReader)
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth(user, apikey)
 	if requestLength > 0 {
 		if isVerbose {
 			log.Printf("Adding Header - Content-Length: %s", strconv.FormatInt(requestLength, 10))
 		}
 		req.ContentLength = requestLength
 	}
-	if contentType != "" {
-		if isVerbose {
-			log.Printf("Adding Header - Content-Type: %s", contentType)
+	for key, value := range headers {
+		if isVerbose && key != "Authorization" {
+			log.Printf("Adding Header - %s: %s", key, value)
 		}
-
-		req.Header.Add("Content-Type", contentType)
+		req.Header.Add(key, value)
 	}
 	//log.Printf("req: %v", req)
 	if isVerbose {
@@ -137,6 +145,8 @@ func UploadFile(method, url, subject, user, apikey, fullPath, relativePath, cont
 		return nil, err
 	}
 	resp, err := DoHttp(method, url, subject, user, apikey, contentType, file, fi.Size(), isVerbose)
-	r, err := ParseMap(resp, isVerbose)
-	return r, err
+	if err != nil {
+		return nil, err
+	}
+	return ParseMap(resp, isVerbose)
 }
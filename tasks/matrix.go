@@ -0,0 +1,100 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"log"
+	"strings"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+)
+
+// outcome of a task for one Go toolchain
+type matrixResult struct {
+	GoVersion string
+	TaskName  string
+	Label     string
+	Err       error
+}
+
+// Resolves the configured GoToolchains into settings for each toolchain
+func resolveGoToolchains(settings *config.Settings) ([]*config.Settings, error) {
+	ret := []*config.Settings{}
+	for _, toolchain := range settings.GoToolchains {
+		goroot, err := core.ResolveGoRoot(toolchain)
+		if err != nil {
+			return nil, err
+		}
+		goVersion, err := core.GetGoVersion(goroot)
+		if err != nil {
+			return nil, err
+		}
+		if settings.IsVerbose() {
+			log.Printf("Using Go toolchain %s (%s)", goVersion, goroot)
+		}
+		ret = append(ret, settings.ForGoToolchain(goroot, goVersion))
+	}
+	return ret, nil
+}
+
+// Lists the settings to run a task with - once per Go toolchain and/or variant, where applicable.
+func matrixSettings(taskName string, settings *config.Settings, toolchainSettings []*config.Settings) []*config.Settings {
+	ret := []*config.Settings{settings}
+	if len(toolchainSettings) > 0 && core.ContainsString(TASKS_PER_GO_TOOLCHAIN, taskName) {
+		ret = toolchainSettings
+	}
+	if len(settings.Variants) > 0 && core.ContainsString(TASKS_PER_VARIANT, taskName) {
+		withVariants := []*config.Settings{}
+		for _, s := range ret {
+			for _, variant := range settings.Variants {
+				withVariants = append(withVariants, s.ForVariant(variant))
+			}
+		}
+		ret = withVariants
+	}
+	return ret
+}
+
+func reportMatrixResults(results []matrixResult) {
+	if len(results) == 0 {
+		return
+	}
+	versions := []string{}
+	byVersion := map[string][]string{}
+	for _, result := range results {
+		if _, exists := byVersion[result.GoVersion]; !exists {
+			versions = append(versions, result.GoVersion)
+		}
+		name := result.TaskName
+		if result.Label != "" {
+			name += " (" + result.Label + ")"
+		}
+		if result.Err != nil {
+			name += ": FAILED"
+		} else {
+			name += ": ok"
+		}
+		byVersion[result.GoVersion] = append(byVersion[result.GoVersion], name)
+	}
+	log.Printf("Results per Go version:")
+	for _, version := range versions {
+		log.Printf("  %s - %s", version, strings.Join(byVersion[version], ", "))
+	}
+}
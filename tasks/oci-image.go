@@ -0,0 +1,224 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/archive"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/packaging/oci"
+	"github.com/laher/goxc/platforms"
+	"github.com/laher/goxc/typeutils"
+)
+
+// where CA certificate bundles live on common Linux distributions (and Mac)
+var caCertificatesLocations = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/ssl/cert.pem"}
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_OCI_IMAGE,
+		"Builds an OCI image for each linux binary, plus a multi-arch index, without a container runtime. Each image has one layer containing the binaries (in 'bin-dir'), the resources (in 'resources-dir', if set) and a CA certificate bundle ('ca-certificates': 'system', or a file). Writes an OCI layout tarball, or a directory with 'format' set to 'dir'. 'metadata' becomes the image labels. Only runs with 'enabled' set to true.",
+		runTaskOciImage,
+		map[string]interface{}{
+			"enabled":         false,
+			"bin-dir":         "/usr/local/bin",
+			"resources-dir":   "",
+			"ca-certificates": "",
+			"entrypoint":      []string{},
+			"cmd":             []string{},
+			"env":             []interface{}{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"},
+			"user":            "",
+			"workdir":         "",
+			"metadata":        map[string]interface{}{},
+			"tag":             "",
+			"format":          "tar"}})
+}
+
+func runTaskOciImage(tp TaskParams) error {
+	if !tp.Settings.GetTaskSettingBool(TASK_OCI_IMAGE, "enabled") {
+		if tp.Settings.IsVerbose() {
+			log.Printf("Not enabled. Not building OCI images")
+		}
+		return nil
+	}
+	hasLinux := false
+	for _, dest := range tp.DestPlatforms {
+		hasLinux = hasLinux || dest.Os == platforms.LINUX
+	}
+	if !hasLinux {
+		log.Printf("No linux platforms. Not building OCI images")
+		return nil
+	}
+	format := tp.Settings.GetTaskSettingString(TASK_OCI_IMAGE, "format")
+	if format != "tar" && format != "dir" {
+		return fmt.Errorf("Unsupported oci-image format '%s'. Use 'tar' or 'dir'", format)
+	}
+	created := executils.GetBuildDate()
+	config := oci.Config{
+		User:       tp.Settings.GetTaskSettingString(TASK_OCI_IMAGE, "user"),
+		Env:        tp.Settings.GetTaskSettingStringSlice(TASK_OCI_IMAGE, "env"),
+		Entrypoint: tp.Settings.GetTaskSettingStringSlice(TASK_OCI_IMAGE, "entrypoint"),
+		Cmd:        tp.Settings.GetTaskSettingStringSlice(TASK_OCI_IMAGE, "cmd"),
+		WorkingDir: tp.Settings.GetTaskSettingString(TASK_OCI_IMAGE, "workdir"),
+		Labels: map[string]string{
+			"org.opencontainers.image.title":   tp.Settings.AppName,
+			"org.opencontainers.image.version": tp.Settings.GetFullVersionName(),
+			"org.opencontainers.image.created": created.UTC().Format("2006-01-02T15:04:05Z")}}
+	for key, value := range tp.Settings.GetTaskSettingMap(TASK_OCI_IMAGE, "metadata") {
+		s, err := typeutils.ToString(value, TASK_OCI_IMAGE+".metadata."+key)
+		if err != nil {
+			return err
+		}
+		config.Labels[key] = s
+	}
+	common, err := ociCommonFiles(tp)
+	if err != nil {
+		return err
+	}
+	binDir := tp.Settings.GetTaskSettingString(TASK_OCI_IMAGE, "bin-dir")
+	layout := oci.NewLayout(created)
+	for _, dest := range tp.DestPlatforms {
+		if dest.Os != platforms.LINUX {
+			continue
+		}
+		img := oci.Image{
+			Platform: oci.Platform{Architecture: dest.Arch, OS: dest.Os, Variant: ociVariant(dest, tp)},
+			Config:   config,
+			Files:    append([]oci.File{}, common...),
+			Created:  created}
+		for _, mainDir := range tp.MainDirs {
+			var exeName string
+			if len(tp.MainDirs) == 1 {
+				exeName = tp.Settings.AppName
+			} else {
+				exeName = filepath.Base(mainDir)
+			}
+			binPath, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
+			if err != nil {
+				return err
+			}
+			//(the binary's file name depends on OutPath)
+			img.Files = append(img.Files, oci.File{Path: path.Join(binDir, exeName), Source: binPath, Mode: 0755})
+		}
+		if len(img.Config.Entrypoint) == 0 {
+			//the first binary
+			img.Config.Entrypoint = []string{img.Files[len(common)].Path}
+		}
+		_, err := layout.AddImage(img)
+		if err != nil {
+			return err
+		}
+	}
+	tag := tp.Settings.GetTaskSettingString(TASK_OCI_IMAGE, "tag")
+	if tag == "" {
+		tag = tp.Settings.GetFullVersionName()
+	}
+	outDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	err = os.MkdirAll(outDir, 0777)
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(outDir, archive.ArtifactName(tp.Settings.AppName, "oci-image", *tp.Settings))
+	if format == "dir" {
+		err = layout.WriteDir(outPath, tag)
+	} else {
+		outPath += ".tar"
+		err = ociWriteTar(layout, outPath, tag)
+	}
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Created OCI image %s (tag %s)", outPath, tag)
+	}
+	return nil
+}
+
+// Files for every image: resources and CA certificates
+func ociCommonFiles(tp TaskParams) ([]oci.File, error) {
+	files := []oci.File{}
+	caCertificates := tp.Settings.GetTaskSettingString(TASK_OCI_IMAGE, "ca-certificates")
+	if caCertificates == "system" {
+		caCertificates = ""
+		for _, location := range caCertificatesLocations {
+			if exists, _ := core.FileExists(location); exists {
+				caCertificates = location
+				break
+			}
+		}
+		if caCertificates == "" {
+			return nil, errors.New("No system CA certificates found. Please set 'ca-certificates' to a file")
+		}
+	} else if caCertificates != "" && !filepath.IsAbs(caCertificates) {
+		caCertificates = filepath.Join(tp.WorkingDirectory, caCertificates)
+	}
+	if caCertificates != "" {
+		//where Go (and most distros) look for them
+		files = append(files, oci.File{Path: "/etc/ssl/certs/ca-certificates.crt", Source: caCertificates, Mode: 0644})
+	}
+	resourcesDir := tp.Settings.GetTaskSettingString(TASK_OCI_IMAGE, "resources-dir")
+	if resourcesDir != "" {
+		resources := core.ParseIncludeResources(tp.WorkingDirectory, tp.Settings.ResourcesInclude, tp.Settings.ResourcesExclude, tp.Settings.IsVerbose())
+		for _, resource := range resources {
+			files = append(files, oci.File{Path: path.Join(resourcesDir, filepath.ToSlash(resource)), Source: filepath.Join(tp.WorkingDirectory, resource), Mode: 0644})
+		}
+	}
+	return files, nil
+}
+
+// OCI platform variants for arm, from GOARM
+func ociVariant(dest platforms.Platform, tp TaskParams) string {
+	switch dest.Arch {
+	case platforms.ARM:
+		goArm := tp.Settings.GetTaskSettingString(TASK_XC, "GOARM")
+		if goArm == "" {
+			//Go's default when cross-compiling
+			goArm = "7"
+		}
+		return "v" + goArm
+	case platforms.ARM64:
+		return "v8"
+	}
+	return ""
+}
+
+func ociWriteTar(layout *oci.Layout, outPath, tag string) error {
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	err = layout.WriteTar(f, tag)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
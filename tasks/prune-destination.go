@@ -0,0 +1,38 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/core"
+)
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_PRUNE_DESTINATION,
+		"Delete all but the newest 'keep-last' version directories from the output directory. The current version is always kept.",
+		runTaskPruneDestination,
+		map[string]interface{}{"keep-last": 5}})
+}
+
+func runTaskPruneDestination(tp TaskParams) error {
+	keepLast := tp.Settings.GetTaskSettingInt(TASK_PRUNE_DESTINATION, "keep-last", 5)
+	_, err := core.PruneVersions(tp.OutDestRoot, keepLast, []string{tp.Settings.GetFullVersionName()}, !tp.Settings.IsQuiet())
+	return err
+}
@@ -0,0 +1,230 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/core"
+)
+
+// TASK_PUBLISH_DIR is the task name to be used in the CLI
+const TASK_PUBLISH_DIR = "publish-dir"
+
+func init() {
+	Register(Task{TASK_PUBLISH_DIR,
+		"Copy artifacts into a local directory or mounted share ('dest'), under 'dir' (a template, with {{.Channel}}, {{.Version}} etc). Files are copied under a temporary name, then renamed. Maintains a 'latest' symlink (or copy) beside the newest version of each channel, and removes all but the newest 'keep-last' versions (0 keeps everything).",
+		publishDirRunTask,
+		map[string]interface{}{
+			"dest":          "",
+			"dir":           "{{.AppName}}/{{.Channel}}/{{.Version}}", //the last element should be the version
+			"channel":       "{{if .PrereleaseInfo}}prerelease{{else}}stable{{end}}",
+			"latest":        "symlink", //symlink, copy or none
+			"keep-last":     0,
+			"include":       "*.zip,*.tar.gz,*.deb",
+			"exclude":       "*.orig.tar.gz,data.tar.gz,control.tar.gz,*.debian.tar.gz,*-dev_*.deb",
+			"exists-action": "fail",
+		}})
+}
+
+const publishDirLatest = "latest"
+
+func publishDirRunTask(tp TaskParams) error {
+	dest := tp.Settings.GetTaskSettingString(TASK_PUBLISH_DIR, "dest")
+	if dest == "" {
+		return fmt.Errorf("publish-dir configuration missing ([dest])")
+	}
+	latest := strings.ToLower(tp.Settings.GetTaskSettingString(TASK_PUBLISH_DIR, "latest"))
+	if latest != "symlink" && latest != "copy" && latest != "none" && latest != "" {
+		return fmt.Errorf("Unknown 'latest' setting '%s'. Use symlink, copy or none", latest)
+	}
+	context := map[string]interface{}{
+		"AppName":        tp.Settings.AppName,
+		"Version":        tp.Settings.GetFullVersionName(),
+		"PackageVersion": tp.Settings.PackageVersion,
+		"PrereleaseInfo": tp.Settings.PrereleaseInfo,
+		"BranchName":     tp.Settings.BranchName,
+	}
+	channel, err := publishDirTemplate("channel", tp.Settings.GetTaskSettingString(TASK_PUBLISH_DIR, "channel"), context)
+	if err != nil {
+		return err
+	}
+	context["Channel"] = channel
+	dir, err := publishDirTemplate("dir", tp.Settings.GetTaskSettingString(TASK_PUBLISH_DIR, "dir"), context)
+	if err != nil {
+		return err
+	}
+	destVersionDir := filepath.Join(dest, filepath.FromSlash(dir))
+	channelDir, version := filepath.Split(destVersionDir)
+	keepLast := tp.Settings.GetTaskSettingInt(TASK_PUBLISH_DIR, "keep-last", 0)
+	//'dest' may hold anything
+	if keepLast > 0 && filepath.Clean(channelDir) == filepath.Clean(dest) {
+		return fmt.Errorf("publish-dir won't prune 'dest' itself. Put the version in a subdirectory ('dir'), or set 'keep-last' to 0")
+	}
+	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	include := tp.Settings.GetTaskSettingString(TASK_PUBLISH_DIR, "include")
+	exclude := tp.Settings.GetTaskSettingString(TASK_PUBLISH_DIR, "exclude")
+	existsAction := strings.ToLower(tp.Settings.GetTaskSettingString(TASK_PUBLISH_DIR, "exists-action"))
+	err = filepath.Walk(versionDir, func(fullPath string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		relativePath, err := filepath.Rel(versionDir, fullPath)
+		if err != nil {
+			return err
+		}
+		included, err := PublishIncluded(include, exclude, filepath.ToSlash(relativePath), fi, tp)
+		if err != nil || !included {
+			return err
+		}
+		return publishDirCopy(fullPath, filepath.Join(destVersionDir, relativePath), fi, existsAction, tp)
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("No artifacts built for this version yet. Please build some artifacts before running the 'publish-dir' task")
+		}
+		return err
+	}
+	if latest == "symlink" || latest == "copy" {
+		err = publishDirLatestVersion(filepath.Clean(channelDir), version, latest, tp)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = core.PruneVersions(channelDir, keepLast, []string{version}, !tp.Settings.IsQuiet())
+	return err
+}
+
+func publishDirTemplate(name, text string, context map[string]interface{}) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	err = tmpl.Execute(&out, context)
+	return out.String(), err
+}
+
+// Copies under a temporary name, then renames, so that the file appears complete or not at all
+func publishDirCopy(fullPath, destPath string, fi os.FileInfo, existsAction string, tp TaskParams) error {
+	exists, err := core.FileExists(destPath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		upload, err := PublishExistsAction("dir", existsAction, fi.Name(), destPath, func() error {
+			//the rename replaces it
+			return nil
+		}, tp)
+		if err != nil || !upload {
+			return err
+		}
+	}
+	err = os.MkdirAll(filepath.Dir(destPath), 0755)
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Copying %s to %s", fi.Name(), destPath)
+	}
+	tmpPath := filepath.Join(filepath.Dir(destPath), "."+fi.Name()+".goxc-tmp")
+	_, err = copyFile(fullPath, tmpPath, tp.Settings.IsVerbose())
+	if err == nil {
+		err = os.Chmod(tmpPath, fi.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, destPath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// Points 'latest' at the newest version in the channel's directory (which isn't necessarily the one just published)
+func publishDirLatestVersion(channelDir, version, latest string, tp TaskParams) error {
+	versions, err := core.VersionDirs(channelDir)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 || versions[0] != version {
+		if !tp.Settings.IsQuiet() {
+			log.Printf("Not updating '%s': %s is not the newest version in %s", publishDirLatest, version, channelDir)
+		}
+		return nil
+	}
+	latestPath := filepath.Join(channelDir, publishDirLatest)
+	tmpPath := filepath.Join(channelDir, "."+publishDirLatest+".goxc-tmp")
+	os.RemoveAll(tmpPath)
+	if latest == "symlink" {
+		err = os.Symlink(version, tmpPath)
+		if err != nil {
+			log.Printf("WARNING: could not create a symlink (%v). Copying instead", err)
+			latest = "copy"
+		}
+	}
+	if latest == "copy" {
+		err = publishDirCopyTree(filepath.Join(channelDir, version), tmpPath, tp)
+		if err != nil {
+			os.RemoveAll(tmpPath)
+			return err
+		}
+	}
+	//renaming over a directory fails, so move it aside first
+	if fi, err := os.Lstat(latestPath); err == nil && fi.IsDir() {
+		oldPath := filepath.Join(channelDir, "."+publishDirLatest+".goxc-old")
+		os.RemoveAll(oldPath)
+		err = os.Rename(latestPath, oldPath)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(oldPath)
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Pointing '%s' at %s", latestPath, version)
+	}
+	return os.Rename(tmpPath, latestPath)
+}
+
+func publishDirCopyTree(srcDir, destDir string, tp TaskParams) error {
+	return filepath.Walk(srcDir, func(fullPath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(srcDir, fullPath)
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return os.MkdirAll(filepath.Join(destDir, relativePath), 0755)
+		}
+		_, err = copyFile(fullPath, filepath.Join(destDir, relativePath), tp.Settings.IsVerbose())
+		if err == nil {
+			err = os.Chmod(filepath.Join(destDir, relativePath), fi.Mode().Perm())
+		}
+		return err
+	})
+}
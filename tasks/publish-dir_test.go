@@ -0,0 +1,69 @@
+package tasks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+)
+
+func TestPublishDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-publish-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "share")
+	publish := func(version string) error {
+		if err := os.MkdirAll(filepath.Join(dir, version), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, version, "app_"+version+"_linux_amd64.tar.gz"), []byte(version), 0644); err != nil {
+			t.Fatal(err)
+		}
+		s := config.Settings{AppName: "app", PackageVersion: version, Verbosity: core.VerbosityQuiet}
+		config.FillSettingsDefaults(&s, dir)
+		FillTaskSettingsDefaults(&s)
+		s.TaskSettings[TASK_PUBLISH_DIR]["dest"] = dest
+		s.TaskSettings[TASK_PUBLISH_DIR]["keep-last"] = 2
+		return publishDirRunTask(TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: dir, Settings: &s})
+	}
+	for _, version := range []string{"0.9", "1.0", "1.1", "1.0.1"} {
+		err = publish(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	stable := filepath.Join(dest, "app", "stable")
+	versions, err := core.VersionDirs(stable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//1.0.1 was published after 1.1, so it's kept but isn't 'latest'
+	if len(versions) != 2 || versions[0] != "1.1" || versions[1] != "1.0.1" {
+		t.Errorf("unexpected versions %v", versions)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(stable, "latest", "app_1.1_linux_amd64.tar.gz"))
+	if err != nil || string(data) != "1.1" {
+		t.Errorf("unexpected latest: '%s' (%v)", data, err)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(stable, "*", ".*")); len(tmps) > 0 {
+		t.Errorf("temporary files left behind: %v", tmps)
+	}
+	err = publish("1.1")
+	if err == nil {
+		t.Errorf("expected an error for existing files")
+	}
+	//versions directly in 'dest': not pruned
+	s := config.Settings{AppName: "app", PackageVersion: "1.2", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, dir)
+	FillTaskSettingsDefaults(&s)
+	s.TaskSettings[TASK_PUBLISH_DIR] = map[string]interface{}{"dest": dest, "dir": "{{.Version}}", "keep-last": 2}
+	err = publishDirRunTask(TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: dir, Settings: &s})
+	if err == nil {
+		t.Errorf("expected an error for pruning 'dest'")
+	}
+}
@@ -0,0 +1,301 @@
+package registry
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/packaging/oci"
+	"github.com/laher/goxc/tasks/httpc"
+)
+
+// A client for one repository of a registry, using the OCI distribution API
+type Client struct {
+	//e.g. 'https://ghcr.io'
+	BaseURL string
+	//e.g. 'owner/app'
+	Repository string
+	User       string
+	Password   string
+	IsVerbose  bool
+	//the Authorization header, once the registry has challenged us
+	authorization string
+}
+
+// The API's base URL for a registry host. 'http://' can be given for local registries.
+func RegistryURL(registry string) string {
+	if !strings.Contains(registry, "://") {
+		registry = "https://" + registry
+	}
+	registry = strings.TrimSuffix(registry, "/")
+	//Docker Hub's API is on a different host
+	if registry == "https://docker.io" || registry == "https://index.docker.io" {
+		return "https://registry-1.docker.io"
+	}
+	return registry
+}
+
+// Pushes the image index in a layout (see oci.ReadFiles) with its images and blobs, tagged with each of 'tags'
+func (c *Client) Push(files map[string][]byte, tags []string) error {
+	index, err := oci.Index(files)
+	if err != nil {
+		return err
+	}
+	if len(index) != 1 {
+		return fmt.Errorf("Expected one image index in the OCI layout, found %d", len(index))
+	}
+	if len(tags) == 0 {
+		return errors.New("No tags to push")
+	}
+	data, err := c.pushChildren(files, index[0])
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		err = c.putManifest(tag, index[0].MediaType, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pushes everything a manifest or index refers to, returning its content
+func (c *Client) pushChildren(files map[string][]byte, d oci.Descriptor) ([]byte, error) {
+	data, err := oci.Blob(files, d)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := oci.References(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		switch ref.MediaType {
+		case oci.MEDIA_TYPE_INDEX, oci.MEDIA_TYPE_MANIFEST:
+			refData, err := c.pushChildren(files, ref)
+			if err != nil {
+				return nil, err
+			}
+			//images in an index are pushed by digest
+			err = c.putManifest(ref.Digest, ref.MediaType, refData)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			err = c.pushBlob(files, ref)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return data, nil
+}
+
+func (c *Client) pushBlob(files map[string][]byte, d oci.Descriptor) error {
+	resp, err := c.do("HEAD", c.url("blobs/"+d.Digest), "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if c.IsVerbose {
+			log.Printf("Blob %s exists already", d.Digest)
+		}
+		return nil
+	}
+	data, err := oci.Blob(files, d)
+	if err != nil {
+		return err
+	}
+	//a 'monolithic' upload: start a session, then put the whole blob
+	resp, err = c.do("POST", c.url("blobs/uploads/"), "", nil)
+	if err != nil {
+		return err
+	}
+	_, err = httpc.ParseMap(resp, c.IsVerbose)
+	if err != nil {
+		return err
+	}
+	location, err := c.resolve(resp.Header.Get("Location"))
+	if err != nil {
+		return err
+	}
+	if strings.Contains(location, "?") {
+		location += "&digest=" + url.QueryEscape(d.Digest)
+	} else {
+		location += "?digest=" + url.QueryEscape(d.Digest)
+	}
+	resp, err = c.do("PUT", location, "application/octet-stream", data)
+	if err != nil {
+		return err
+	}
+	_, err = httpc.ParseMap(resp, c.IsVerbose)
+	return err
+}
+
+func (c *Client) putManifest(reference, mediaType string, data []byte) error {
+	resp, err := c.do("PUT", c.url("manifests/"+reference), mediaType, data)
+	if err != nil {
+		return err
+	}
+	_, err = httpc.ParseMap(resp, c.IsVerbose)
+	return err
+}
+
+func (c *Client) url(path string) string {
+	return c.BaseURL + "/v2/" + c.Repository + "/" + path
+}
+
+// Upload locations may be relative to the registry
+func (c *Client) resolve(location string) (string, error) {
+	if location == "" {
+		return "", errors.New("Registry did not return an upload location")
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// Sends a request, authorizing & retrying once if the registry asks for it
+func (c *Client) do(method, u, contentType string, data []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		headers := map[string]string{}
+		if c.authorization != "" {
+			headers["Authorization"] = c.authorization
+		}
+		if contentType != "" {
+			headers["Content-Type"] = contentType
+		}
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		resp, err := httpc.DoHttpWithHeaders(method, u, headers, body, int64(len(data)), c.IsVerbose)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+		resp.Body.Close()
+		err = c.authorize(resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Answers an authentication challenge, with basic auth or by fetching a bearer token
+func (c *Client) authorize(challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte(c.User+":"+c.Password))
+	switch scheme {
+	case "basic":
+		if c.User == "" {
+			return errors.New("Registry requires a user and apikey")
+		}
+		c.authorization = basic
+		return nil
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return fmt.Errorf("Invalid token realm in challenge '%s'", challenge)
+		}
+		query := realm.Query()
+		if params["service"] != "" {
+			query.Set("service", params["service"])
+		}
+		//the challenge's scope may only be for the current request (e.g. a pull), so ask for everything up front
+		query.Set("scope", "repository:"+c.Repository+":pull,push")
+		realm.RawQuery = query.Encode()
+		headers := map[string]string{}
+		if c.User != "" {
+			headers["Authorization"] = basic
+		}
+		resp, err := httpc.DoHttpWithHeaders("GET", realm.String(), headers, nil, 0, c.IsVerbose)
+		if err != nil {
+			return err
+		}
+		token, err := httpc.ParseMap(resp, c.IsVerbose)
+		if err != nil {
+			return err
+		}
+		for _, key := range []string{"token", "access_token"} {
+			if s, ok := token[key].(string); ok && s != "" {
+				c.authorization = "Bearer " + s
+				return nil
+			}
+		}
+		return errors.New("No token in the registry's token response")
+	}
+	return fmt.Errorf("Unsupported registry authentication challenge '%s'", challenge)
+}
+
+// Parses e.g. 'Bearer realm="https://auth.docker.io/token",service="registry.docker.io"'
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	scheme := strings.ToLower(parts[0])
+	if len(parts) < 2 {
+		return scheme, params
+	}
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				value, rest = rest, ""
+			} else {
+				value, rest = rest[:end], rest[end:]
+			}
+		}
+		params[key] = value
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return scheme, params
+}
@@ -0,0 +1,121 @@
+package registry
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/archive"
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/packaging/oci"
+	"github.com/laher/goxc/tasks"
+)
+
+//runs automatically
+func init() {
+	tasks.Register(tasks.Task{
+		Name:        tasks.TASK_PUBLISH_OCI,
+		Description: "Pushes the image built by 'oci-image' to a registry (e.g. 'ghcr.io', or 'http://localhost:5000'), tagged with the version, 'major.minor' and 'latest' (except for prereleases), plus any extra 'tags'. Uses basic or token authentication, with 'user' & 'apikey'.",
+		Run:         RunTaskPubOCI,
+		DefaultSettings: map[string]interface{}{
+			"registry":   "",
+			"repository": "",
+			"user":       "",
+			"apikey":     "",
+			"image":      "", //defaults to the output of 'oci-image'
+			"tags":       []string{},
+			"latest":     true}})
+}
+
+func RunTaskPubOCI(tp tasks.TaskParams) error {
+	registry := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_OCI, "registry")
+	repository := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_OCI, "repository")
+	missing := []string{}
+	if registry == "" {
+		missing = append(missing, "registry")
+	}
+	if repository == "" {
+		missing = append(missing, "repository")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("publish-oci configuration missing (%v)", missing)
+	}
+	image := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_OCI, "image")
+	if image == "" {
+		image = filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName(), archive.ArtifactName(tp.Settings.AppName, "oci-image", *tp.Settings))
+		//'oci-image' writes a tarball unless its format is 'dir'
+		if exists, _ := core.FileExists(image + ".tar"); exists {
+			image += ".tar"
+		}
+	} else if !filepath.IsAbs(image) {
+		image = filepath.Join(tp.WorkingDirectory, image)
+	}
+	files, err := oci.ReadFiles(image)
+	if err != nil {
+		return fmt.Errorf("Could not read the OCI image. Please run 'oci-image' first, with 'enabled' set to true (%v)", err)
+	}
+	tags := Tags(tp.Settings, tp.Settings.GetTaskSettingStringSlice(tasks.TASK_PUBLISH_OCI, "tags"), tp.Settings.GetTaskSettingBool(tasks.TASK_PUBLISH_OCI, "latest"))
+	client := &Client{
+		BaseURL:    RegistryURL(registry),
+		Repository: repository,
+		User:       tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_OCI, "user"),
+		Password:   tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_OCI, "apikey"),
+		IsVerbose:  tp.Settings.IsVerbose()}
+	err = client.Push(files, tags)
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Pushed %s to %s/%s (tags %v)", filepath.Base(image), registry, repository, tags)
+	}
+	return nil
+}
+
+// characters which are not allowed in tags (e.g. semver's '+')
+var invalidTagChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// The full version, plus 'major.minor' and 'latest' for releases (i.e. without prerelease info), plus any extras
+func Tags(settings *config.Settings, extra []string, latest bool) []string {
+	tags := []string{settings.GetFullVersionName()}
+	if settings.PrereleaseInfo == "" && settings.BranchName == "" {
+		parts := strings.Split(settings.PackageVersion, ".")
+		if len(parts) > 2 {
+			tags = append(tags, parts[0]+"."+parts[1])
+		}
+		if latest {
+			tags = append(tags, "latest")
+		}
+	}
+	tags = append(tags, extra...)
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = invalidTagChars.ReplaceAllString(tag, "_")
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/packaging/oci"
+)
+
+// An in-process registry, requiring a bearer token
+type fakeRegistry struct {
+	sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string]string
+	uploads   int
+	server    *httptest.Server
+}
+
+func newFakeRegistry() *fakeRegistry {
+	r := &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string]string{}}
+	r.server = httptest.NewServer(r)
+	return r
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	if req.URL.Path == "/token" {
+		user, pass, _ := req.BasicAuth()
+		if user != "me" || pass != "secret" || req.URL.Query().Get("scope") != "repository:me/app:pull,push" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"token":"t0k3n"}`))
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer t0k3n" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.server.URL+`/token",service="fake",scope="repository:me/app:pull"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	prefix := "/v2/me/app/"
+	if !strings.HasPrefix(req.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	path := strings.TrimPrefix(req.URL.Path, prefix)
+	switch {
+	case req.Method == "HEAD" && strings.HasPrefix(path, "blobs/"):
+		if _, exists := r.blobs[strings.TrimPrefix(path, "blobs/")]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case req.Method == "POST" && path == "blobs/uploads/":
+		r.uploads++
+		//relative, with a query string
+		w.Header().Set("Location", "/v2/me/app/blobs/uploads/session?state=x")
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == "PUT" && path == "blobs/uploads/session":
+		data, _ := ioutil.ReadAll(req.Body)
+		digest := req.URL.Query().Get("digest")
+		if oci.Digest(data) != digest || req.URL.Query().Get("state") != "x" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.blobs[digest] = data
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == "PUT" && strings.HasPrefix(path, "manifests/"):
+		data, _ := ioutil.ReadAll(req.Body)
+		r.manifests[strings.TrimPrefix(path, "manifests/")] = req.Header.Get("Content-Type") + " " + oci.Digest(data)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func testFiles(t *testing.T) map[string][]byte {
+	l := oci.NewLayout(time.Unix(0, 0))
+	for _, arch := range []string{"amd64", "arm64"} {
+		_, err := l.AddImage(oci.Image{
+			Platform: oci.Platform{Architecture: arch, OS: "linux"},
+			Files:    []oci.File{{Path: "/usr/local/bin/app", Data: []byte("binary-" + arch), Mode: 0755}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := l.Files("1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestPush(t *testing.T) {
+	r := newFakeRegistry()
+	defer r.server.Close()
+	files := testFiles(t)
+	c := &Client{BaseURL: r.server.URL, Repository: "me/app", User: "me", Password: "secret"}
+	err := c.Push(files, []string{"1.2.3", "latest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := oci.Index(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []string{"1.2.3", "latest"} {
+		if r.manifests[tag] != oci.MEDIA_TYPE_INDEX+" "+index[0].Digest {
+			t.Errorf("unexpected manifest for %s: %v", tag, r.manifests)
+		}
+	}
+	//2 images by digest, 2 tags
+	if len(r.manifests) != 4 {
+		t.Errorf("unexpected manifests %v", r.manifests)
+	}
+	//2 layers & 2 configs
+	if len(r.blobs) != 4 {
+		t.Errorf("unexpected blobs %d", len(r.blobs))
+	}
+	//existing blobs are skipped
+	uploads := r.uploads
+	err = c.Push(files, []string{"1.2.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.uploads != uploads {
+		t.Errorf("expected existing blobs to be skipped")
+	}
+	wrong := &Client{BaseURL: r.server.URL, Repository: "me/app", User: "me", Password: "wrong"}
+	if err := wrong.Push(files, []string{"1.2.3"}); err == nil {
+		t.Errorf("expected an error for a bad password")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:a/b:pull,push"`)
+	expected := map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:a/b:pull,push"}
+	if scheme != "bearer" || !reflect.DeepEqual(params, expected) {
+		t.Errorf("unexpected challenge %s %v", scheme, params)
+	}
+	scheme, params = parseChallenge(`Basic realm=registry`)
+	if scheme != "basic" || params["realm"] != "registry" {
+		t.Errorf("unexpected challenge %s %v", scheme, params)
+	}
+}
+
+func TestTags(t *testing.T) {
+	s := &config.Settings{PackageVersion: "1.2.3"}
+	if tags := Tags(s, []string{"stable", "latest"}, true); !reflect.DeepEqual(tags, []string{"1.2.3", "1.2", "latest", "stable"}) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	s = &config.Settings{PackageVersion: "1.2.3", PrereleaseInfo: "rc1", BuildName: "7"}
+	if tags := Tags(s, nil, true); !reflect.DeepEqual(tags, []string{"1.2.3-rc1_b7"}) {
+		t.Errorf("unexpected prerelease tags %v", tags)
+	}
+	if url := RegistryURL("docker.io"); url != "https://registry-1.docker.io" {
+		t.Errorf("unexpected url %s", url)
+	}
+	if url := RegistryURL("http://localhost:5000/"); url != "http://localhost:5000" {
+		t.Errorf("unexpected url %s", url)
+	}
+}
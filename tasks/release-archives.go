@@ -0,0 +1,99 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"log"
+	"path/filepath"
+	"text/template"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/archive"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/platforms"
+)
+
+// default 'url-template' for package manager manifests, matching 'publish-github'
+const RELEASE_URL_TEMPLATE_GITHUB = "https://github.com/{{.Owner}}/{{.Repository}}/releases/download/{{.Tag}}/{{.FileName}}"
+
+// variables for the 'url-template' of package manager manifests (homebrew, windows-manifests)
+type releaseURLVars struct {
+	AppName    string
+	Version    string
+	Tag        string
+	FileName   string
+	Os         string
+	Arch       string
+	Owner      string
+	Repository string
+}
+
+// A published archive, as referenced by package manager manifests
+type releaseArchive struct {
+	FileName string
+	URL      string
+	Sha256   string
+	//top-level directory inside the archive. Empty if there's none
+	Dir string
+}
+
+func newReleaseURLVars(tp TaskParams) releaseURLVars {
+	return releaseURLVars{
+		AppName:    tp.Settings.AppName,
+		Version:    tp.Settings.GetFullVersionName(),
+		Tag:        tp.Settings.GetTaskSettingString(TASK_TAG, "prefix") + tp.Settings.GetFullVersionName(),
+		Owner:      tp.Settings.GetTaskSettingString(TASK_PUBLISH_GITHUB, "owner"),
+		Repository: tp.Settings.GetTaskSettingString(TASK_PUBLISH_GITHUB, "repository")}
+}
+
+// Finds a platform's archive (trying each of 'endings' in turn, e.g. 'tar.gz' then 'zip'), and computes its URL and checksum. Returns nil if there's no archive
+func findReleaseArchive(dest platforms.Platform, endings []string, urlTemplate *template.Template, urlVars releaseURLVars, tp TaskParams) (*releaseArchive, error) {
+	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	name := archive.ArtifactName(tp.Settings.AppName, dest.Os+"_"+dest.Arch, *tp.Settings)
+	archiveTasks := map[string]string{"tar.gz": TASK_ARCHIVE_TAR_GZ, "zip": TASK_ARCHIVE_ZIP}
+	for _, ending := range endings {
+		fileName := name + "." + ending
+		archivePath := filepath.Join(versionDir, fileName)
+		if exists, _ := core.FileExists(archivePath); !exists {
+			continue
+		}
+		sum, err := archive.Sha256File(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		urlVars.FileName = fileName
+		urlVars.Os = dest.Os
+		urlVars.Arch = dest.Arch
+		buf := &bytes.Buffer{}
+		err = urlTemplate.Execute(buf, urlVars)
+		if err != nil {
+			return nil, err
+		}
+		a := &releaseArchive{FileName: fileName, URL: buf.String(), Sha256: sum}
+		bcTopLevelDir := tp.Settings.GetTaskSettingString(archiveTasks[ending], "include-top-level-dir")
+		if platforms.ContainsPlatform(platforms.ApplyBuildConstraints(bcTopLevelDir, []platforms.Platform{dest}), dest) {
+			a.Dir = name
+		}
+		return a, nil
+	}
+	if tp.Settings.IsVerbose() {
+		log.Printf("No archive for %s_%s in %s", dest.Os, dest.Arch, versionDir)
+	}
+	return nil, nil
+}
@@ -0,0 +1,235 @@
+package release
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/tasks"
+	"github.com/laher/goxc/typeutils"
+)
+
+const keepAChangelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+
+## [Unreleased]
+
+`
+
+var (
+	changelogHeading           = regexp.MustCompile(`(?m)^## `)
+	changelogUnreleasedHeading = regexp.MustCompile(`^## \[?(?i:unreleased)\b`)
+)
+
+func RunTaskChangelog(tp tasks.TaskParams) error {
+	notes, err := GatherNotes(tasks.TASK_CHANGELOG, tp)
+	if err != nil {
+		return err
+	}
+	file := tp.Settings.GetTaskSettingString(tasks.TASK_CHANGELOG, "file")
+	debianFile := tp.Settings.GetTaskSettingString(tasks.TASK_CHANGELOG, "debian-file")
+	updates := map[string][]byte{}
+	if file != "" {
+		filename := filepath.Join(tp.WorkingDirectory, file)
+		existing, err := readIfExists(filename)
+		if err != nil {
+			return err
+		}
+		updates[filename], err = prependChangelog(existing, notes)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	if debianFile != "" {
+		filename := filepath.Join(tp.WorkingDirectory, debianFile)
+		existing, err := readIfExists(filename)
+		if err != nil {
+			return err
+		}
+		entry, err := debianChangelogEntry(notes, tp)
+		if err != nil {
+			return err
+		}
+		updates[filename], err = prependDebianChangelog(existing, entry)
+		if err != nil {
+			return fmt.Errorf("%s: %v", debianFile, err)
+		}
+	}
+	//only write once both entries are known to be new
+	for filename, data := range updates {
+		err = os.MkdirAll(filepath.Dir(filename), 0755)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filename, data, 0644)
+		if err != nil {
+			return err
+		}
+		if !tp.Settings.IsQuiet() {
+			log.Printf("Added version %s to %s", notes.Version, filename)
+		}
+	}
+	return nil
+}
+
+func readIfExists(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Inserts a Keep a Changelog entry before the latest version (i.e. after the 'Unreleased' section)
+func prependChangelog(existing []byte, notes Notes) ([]byte, error) {
+	if regexp.MustCompile(`(?m)^## \[?` + regexp.QuoteMeta(notes.Version) + `\]?(\s|$)`).Match(existing) {
+		return nil, fmt.Errorf("version %s already has an entry", notes.Version)
+	}
+	entry := &bytes.Buffer{}
+	fmt.Fprintf(entry, "## [%s] - %s\n\n", notes.Version, notes.Date)
+	for _, group := range notes.Groups {
+		fmt.Fprintf(entry, "### %s\n\n", group.Title)
+		for _, note := range group.Notes {
+			if note.Scope != "" {
+				fmt.Fprintf(entry, "- **%s:** %s\n", note.Scope, note.Text)
+			} else {
+				fmt.Fprintf(entry, "- %s\n", note.Text)
+			}
+		}
+		entry.WriteString("\n")
+	}
+	if len(notes.Groups) == 0 {
+		fmt.Fprintf(entry, "Release %s\n\n", notes.Version)
+	}
+	if len(existing) == 0 {
+		return append([]byte(keepAChangelogHeader), entry.Bytes()...), nil
+	}
+	for _, loc := range changelogHeading.FindAllIndex(existing, -1) {
+		if !changelogUnreleasedHeading.Match(existing[loc[0]:]) {
+			ret := append([]byte{}, existing[:loc[0]]...)
+			ret = append(ret, entry.Bytes()...)
+			return append(ret, existing[loc[0]:]...), nil
+		}
+	}
+	//no versions yet
+	if !bytes.HasSuffix(existing, []byte("\n\n")) {
+		existing = append(existing, '\n')
+	}
+	return append(existing, entry.Bytes()...), nil
+}
+
+// An entry in Debian's format (see https://www.debian.org/doc/debian-policy/ch-source.html#debian-changelog-debian-changelog)
+func debianChangelogEntry(notes Notes, tp tasks.TaskParams) (string, error) {
+	metadata := tp.Settings.GetTaskSettingMap(tasks.TASK_DEB_GEN, "metadata")
+	maintainer, err := metadataString(metadata, "maintainer")
+	if err != nil {
+		return "", err
+	}
+	maintainerEmail, err := metadataString(metadata, "maintainer-email", "maintainerEmail")
+	if err != nil {
+		return "", err
+	}
+	//the deb task's placeholder defaults don't belong in a changelog
+	if maintainer == "" || maintainer == "unknown" || maintainerEmail == "" || maintainerEmail == "unknown@example.com" {
+		return "", fmt.Errorf("deb metadata missing ([maintainer maintainer-email])")
+	}
+	packageName, err := debianSourceName(tp)
+	if err != nil {
+		return "", err
+	}
+	//'~' sorts before the release itself
+	version := strings.Replace(notes.Version, "-", "~", -1)
+	if revision := tp.Settings.GetTaskSettingString(tasks.TASK_CHANGELOG, "debian-revision"); revision != "" {
+		version += "-" + revision
+	}
+	entry := &bytes.Buffer{}
+	fmt.Fprintf(entry, "%s (%s) %s; urgency=%s\n\n", packageName, version,
+		tp.Settings.GetTaskSettingString(tasks.TASK_CHANGELOG, "distribution"),
+		tp.Settings.GetTaskSettingString(tasks.TASK_CHANGELOG, "urgency"))
+	count := 0
+	for _, group := range notes.Groups {
+		for _, note := range group.Notes {
+			summary := note.Summary
+			if note.Scope != "" {
+				summary = note.Scope + ": " + summary
+			}
+			fmt.Fprintf(entry, "  * %s\n", summary)
+			count++
+		}
+	}
+	if count == 0 {
+		fmt.Fprintf(entry, "  * Release %s\n", notes.Version)
+	}
+	fmt.Fprintf(entry, "\n -- %s <%s>  %s\n", maintainer, maintainerEmail, executils.GetBuildDate().Format(time.RFC1123Z))
+	return entry.String(), nil
+}
+
+func prependDebianChangelog(existing []byte, entry string) ([]byte, error) {
+	heading := entry[:strings.Index(entry, ")")+1]
+	for _, line := range strings.Split(string(existing), "\n") {
+		if strings.HasPrefix(line, heading) {
+			return nil, fmt.Errorf("version already has an entry (%s)", heading)
+		}
+	}
+	if len(existing) == 0 {
+		return []byte(entry), nil
+	}
+	return append([]byte(entry+"\n"), existing...), nil
+}
+
+// The first of the keys which is present
+func metadataString(metadata map[string]interface{}, keys ...string) (string, error) {
+	for _, key := range keys {
+		if v, keyExists := metadata[key]; keyExists {
+			return typeutils.ToString(v, key)
+		}
+	}
+	return "", nil
+}
+
+// The 'Source' in debian/control, or else the app name
+func debianSourceName(tp tasks.TaskParams) (string, error) {
+	f, err := os.Open(filepath.Join(tp.WorkingDirectory, "debian", "control"))
+	if os.IsNotExist(err) {
+		return tp.AppName, nil
+	} else if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "Source:") {
+			return strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "Source:")), nil
+		}
+	}
+	return tp.AppName, scanner.Err()
+}
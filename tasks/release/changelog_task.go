@@ -0,0 +1,29 @@
+package release
+
+import "github.com/laher/goxc/tasks"
+
+//runs automatically
+func init() {
+	tasks.Register(tasks.Task{
+		Name:        tasks.TASK_CHANGELOG,
+		Description: "Prepends an entry for the current version to CHANGELOG.md (Keep a Changelog format) and to debian/changelog (with the maintainer from the 'deb' task's metadata), listing the git commits since the previous tag. Commits are grouped as for 'release-notes'. Refuses to add a version which is already there",
+		Run:         RunTaskChangelog,
+		DefaultSettings: map[string]interface{}{
+			"file":            "CHANGELOG.md",     //leave empty to skip
+			"debian-file":     "debian/changelog", //leave empty to skip
+			"distribution":    "unstable",
+			"urgency":         "medium",
+			"debian-revision": "", //e.g. '1', for non-native packages
+			//matched against commit subjects, in order
+			"groups": []interface{}{
+				`Added=^feat(\([^)]*\))?!?:`,
+				`Fixed=^fix(\([^)]*\))?!?:`,
+				`Security=^security(\([^)]*\))?!?:`,
+				`Deprecated=^deprecate(\([^)]*\))?!?:`},
+			"other-title":     "Changed", //for unmatched commits. Leave empty to omit them
+			"exclude-pattern": `^(chore|ci|style|test)(\([^)]*\))?!?:`,
+			"strip-type":      true, //strip 'type(scope):' from subjects
+			"issue-pattern":   `#(\d+)`,
+			"issue-url":       "", //e.g. 'https://github.com/owner/repo/issues/$1' (CHANGELOG.md only)
+		}})
+}
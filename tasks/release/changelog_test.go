@@ -0,0 +1,115 @@
+package release
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/tasks"
+)
+
+func TestPrependChangelog(t *testing.T) {
+	notes := Notes{Version: "1.1", Date: "2020-01-02", Groups: []NotesGroup{
+		{"Added", []Note{{Scope: "cli", Text: "flags"}}},
+		{"Fixed", []Note{{Text: "crash"}}}}}
+	existing := "# Changelog\n\n## [Unreleased]\n\n- pending\n\n## [1.0] - 2019-01-01\n\n### Added\n\n- everything\n"
+	actual, err := prependChangelog([]byte(existing), notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Changelog\n\n## [Unreleased]\n\n- pending\n\n## [1.1] - 2020-01-02\n\n### Added\n\n- **cli:** flags\n\n### Fixed\n\n- crash\n\n## [1.0] - 2019-01-01\n\n### Added\n\n- everything\n"
+	if string(actual) != expected {
+		t.Errorf("unexpected changelog:\n%s", actual)
+	}
+	_, err = prependChangelog(actual, notes)
+	if err == nil {
+		t.Errorf("expected an error for a duplicate version")
+	}
+	//new file
+	actual, err = prependChangelog(nil, notes)
+	if err != nil || !strings.HasPrefix(string(actual), keepAChangelogHeader+"## [1.1] - 2020-01-02\n") {
+		t.Errorf("unexpected changelog (%v):\n%s", err, actual)
+	}
+}
+
+func TestRunTaskChangelog(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is required for this test")
+	}
+	dir, err := ioutil.TempDir("", "goxc-changelog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Tester", "-c", "user.email=tester@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+	git("tag", "v0.9")
+	git("commit", "-q", "--allow-empty", "-m", "feat: add flags (#12)")
+	git("commit", "-q", "--allow-empty", "-m", "tidy up")
+	os.MkdirAll(filepath.Join(dir, "debian"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "debian", "control"), []byte("Source: app-src\nMaintainer: Someone <someone@example.com>\n"), 0644)
+	oldEntry := "app-src (0.9) unstable; urgency=medium\n\n  * Initial release\n\n -- A Maintainer <maint@example.com>  Thu, 01 Jan 1970 00:00:00 +0000\n"
+	ioutil.WriteFile(filepath.Join(dir, "debian", "changelog"), []byte(oldEntry), 0644)
+	defer os.Setenv("SOURCE_DATE_EPOCH", os.Getenv("SOURCE_DATE_EPOCH"))
+	os.Setenv("SOURCE_DATE_EPOCH", "86400")
+
+	s := config.Settings{AppName: "app", PackageVersion: "1.0", PrereleaseInfo: "rc1", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, dir)
+	tasks.FillTaskSettingsDefaults(&s)
+	s.TaskSettings[tasks.TASK_DEB_GEN]["metadata"] = map[string]interface{}{"maintainer": "A Maintainer", "maintainer-email": "maint@example.com"}
+	tp := tasks.TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: filepath.Join(dir, "dist"), Settings: &s}
+	err = RunTaskChangelog(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	debian, err := ioutil.ReadFile(filepath.Join(dir, "debian", "changelog"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "app-src (1.0~rc1) unstable; urgency=medium\n\n  * add flags (#12)\n  * tidy up\n\n -- A Maintainer <maint@example.com>  Fri, 02 Jan 1970 00:00:00 +0000\n\n" + oldEntry
+	if string(debian) != expected {
+		t.Errorf("unexpected debian/changelog:\n%s", debian)
+	}
+	changelog, err := ioutil.ReadFile(filepath.Join(dir, "CHANGELOG.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(changelog), "## [1.0-rc1] - 1970-01-02\n\n### Added\n\n- add flags (#12)\n\n### Changed\n\n- tidy up\n") {
+		t.Errorf("unexpected CHANGELOG.md:\n%s", changelog)
+	}
+	//again: refused, without touching either file
+	ioutil.WriteFile(filepath.Join(dir, "debian", "changelog"), []byte(oldEntry), 0644)
+	err = RunTaskChangelog(tp)
+	if err == nil {
+		t.Fatal("expected an error for a duplicate version")
+	}
+	debian, _ = ioutil.ReadFile(filepath.Join(dir, "debian", "changelog"))
+	if string(debian) != oldEntry {
+		t.Errorf("debian/changelog was modified:\n%s", debian)
+	}
+}
+
+func TestDebianChangelogEntryDefaultMaintainer(t *testing.T) {
+	s := config.Settings{AppName: "app", PackageVersion: "1.0", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, ".")
+	tasks.FillTaskSettingsDefaults(&s)
+	//the deb task's defaults
+	s.TaskSettings[tasks.TASK_DEB_GEN]["metadata"] = map[string]interface{}{"maintainer": "unknown", "maintainerEmail": "unknown@example.com"}
+	tp := tasks.TaskParams{AppName: "app", WorkingDirectory: ".", Settings: &s}
+	_, err := debianChangelogEntry(Notes{Version: "1.0"}, tp)
+	if err == nil || !strings.Contains(err.Error(), "deb metadata missing") {
+		t.Errorf("expected the placeholder maintainer to be rejected, got %v", err)
+	}
+}
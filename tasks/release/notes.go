@@ -0,0 +1,202 @@
+package release
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/tasks"
+)
+
+// Release notes, for the release notes template
+type Notes struct {
+	AppName     string
+	Version     string
+	Tag         string
+	PreviousTag string
+	Date        string
+	Groups      []NotesGroup
+}
+
+type NotesGroup struct {
+	Title string
+	Notes []Note
+}
+
+// A commit, for the release notes
+type Note struct {
+	executils.Commit
+	Type     string
+	Scope    string
+	Breaking bool
+	//the subject (with 'strip-type', without the Conventional Commit type)
+	Summary string
+	//the summary, with issues linked
+	Text string
+}
+
+type notesGroupPattern struct {
+	title   string
+	pattern *regexp.Regexp
+}
+
+var conventionalCommit = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.*)$`)
+
+func RunTaskReleaseNotes(tp tasks.TaskParams) error {
+	notes, err := GatherNotes(tasks.TASK_RELEASE_NOTES, tp)
+	if err != nil {
+		return err
+	}
+	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	err = os.MkdirAll(versionDir, 0755)
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(versionDir, tp.Settings.GetTaskSettingString(tasks.TASK_RELEASE_NOTES, "filename"))
+	out, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	templateText := tp.Settings.GetTaskSettingString(tasks.TASK_RELEASE_NOTES, "templateText")
+	templateFile := tp.Settings.GetTaskSettingString(tasks.TASK_RELEASE_NOTES, "templateFile")
+	err = tasks.RunTemplate(filename, templateFile, templateText, out, notes, "markdown")
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Wrote release notes to %s (%d commits since '%s')", filename, countNotes(notes), notes.PreviousTag)
+	}
+	//close explicitly for return value
+	return out.Close()
+}
+
+// Gathers the commits since the previous tag, grouped according to the task's settings (see the 'release-notes' task's defaults)
+func GatherNotes(taskName string, tp tasks.TaskParams) (Notes, error) {
+	prefix := tp.Settings.GetTaskSettingString(tasks.TASK_TAG, "prefix")
+	version := tp.Settings.GetFullVersionName()
+	notes := Notes{
+		AppName: tp.AppName,
+		Version: version,
+		Tag:     prefix + version,
+		Date:    executils.GetBuildDate().UTC().Format("2006-01-02"),
+		Groups:  []NotesGroup{}}
+	groupPatterns := []notesGroupPattern{}
+	for _, group := range tp.Settings.GetTaskSettingStringSlice(taskName, "groups") {
+		parts := strings.SplitN(group, "=", 2)
+		if len(parts) != 2 {
+			return notes, fmt.Errorf("Invalid %s group '%s'. Use 'Title=regex'", taskName, group)
+		}
+		pattern, err := regexp.Compile(parts[1])
+		if err != nil {
+			return notes, err
+		}
+		groupPatterns = append(groupPatterns, notesGroupPattern{parts[0], pattern})
+	}
+	var exclude, issuePattern *regexp.Regexp
+	var err error
+	if excludePattern := tp.Settings.GetTaskSettingString(taskName, "exclude-pattern"); excludePattern != "" {
+		exclude, err = regexp.Compile(excludePattern)
+		if err != nil {
+			return notes, err
+		}
+	}
+	issueURL := tp.Settings.GetTaskSettingString(taskName, "issue-url")
+	if issueURL != "" {
+		issuePattern, err = regexp.Compile(tp.Settings.GetTaskSettingString(taskName, "issue-pattern"))
+		if err != nil {
+			return notes, err
+		}
+	}
+	notes.PreviousTag, err = executils.GitPreviousTag(tp.WorkingDirectory, prefix, notes.Tag)
+	if err != nil {
+		return notes, err
+	}
+	commits, err := executils.GitCommitsSince(tp.WorkingDirectory, notes.PreviousTag)
+	if err != nil {
+		return notes, err
+	}
+	breakingTitle := tp.Settings.GetTaskSettingString(taskName, "breaking-title")
+	otherTitle := tp.Settings.GetTaskSettingString(taskName, "other-title")
+	stripType := tp.Settings.GetTaskSettingBool(taskName, "strip-type")
+	grouped := map[string][]Note{}
+	for _, commit := range commits {
+		note := Note{Commit: commit, Summary: commit.Subject}
+		if m := conventionalCommit.FindStringSubmatch(commit.Subject); m != nil {
+			note.Type, note.Scope, note.Breaking = m[1], m[2], m[3] == "!"
+			if stripType {
+				note.Summary = m[4]
+			}
+		}
+		if strings.Contains(commit.Body, "BREAKING CHANGE:") || strings.Contains(commit.Body, "BREAKING-CHANGE:") {
+			note.Breaking = true
+		}
+		//breaking changes are never excluded
+		if exclude != nil && exclude.MatchString(commit.Subject) && !note.Breaking {
+			continue
+		}
+		note.Text = note.Summary
+		if issuePattern != nil {
+			note.Text = issuePattern.ReplaceAllString(note.Text, "[$0]("+issueURL+")")
+		}
+		title := otherTitle
+		if note.Breaking && breakingTitle != "" {
+			title = breakingTitle
+		} else {
+			for _, group := range groupPatterns {
+				if group.pattern.MatchString(commit.Subject) {
+					title = group.title
+					break
+				}
+			}
+		}
+		if title != "" {
+			grouped[title] = append(grouped[title], note)
+		}
+	}
+	//breaking changes, then groups in the configured order, then 'other'
+	if len(grouped[breakingTitle]) > 0 {
+		notes.Groups = append(notes.Groups, NotesGroup{breakingTitle, grouped[breakingTitle]})
+		delete(grouped, breakingTitle)
+	}
+	for _, group := range groupPatterns {
+		if len(grouped[group.title]) > 0 {
+			notes.Groups = append(notes.Groups, NotesGroup{group.title, grouped[group.title]})
+			delete(grouped, group.title)
+		}
+	}
+	if len(grouped[otherTitle]) > 0 {
+		notes.Groups = append(notes.Groups, NotesGroup{otherTitle, grouped[otherTitle]})
+	}
+	return notes, nil
+}
+
+func countNotes(notes Notes) int {
+	count := 0
+	for _, group := range notes.Groups {
+		count += len(group.Notes)
+	}
+	return count
+}
@@ -0,0 +1,34 @@
+package release
+
+import "github.com/laher/goxc/tasks"
+
+//runs automatically
+func init() {
+	tasks.Register(tasks.Task{
+		Name:        tasks.TASK_RELEASE_NOTES,
+		Description: "Writes release notes (RELEASE_NOTES.md) into the version directory, from the git commits since the previous tag (see the 'tag' task's prefix). Breaking changes ('type!:' or a 'BREAKING CHANGE:' footer) are grouped under 'breaking-title'; other commits are grouped by the first matching 'groups' entry ('Title=regex' - by default, Conventional Commit types), and issue numbers are linked using 'issue-url'. Publishers can use the notes as the release body, with 'body-file'",
+		Run:         RunTaskReleaseNotes,
+		DefaultSettings: map[string]interface{}{
+			"filename": "RELEASE_NOTES.md",
+			//matched against commit subjects, in order
+			"groups": []interface{}{
+				`Features=^feat(\([^)]*\))?:`,
+				`Bug Fixes=^fix(\([^)]*\))?:`,
+				`Performance=^perf(\([^)]*\))?:`,
+				`Reverts=^revert(\([^)]*\))?:`,
+				`Documentation=^docs(\([^)]*\))?:`},
+			"breaking-title":  "Breaking Changes", //for breaking changes, whatever their type. Leave empty to group them by type
+			"other-title":     "Other Changes",    //for unmatched commits. Leave empty to omit them
+			"exclude-pattern": `^(chore|ci|style|test)(\([^)]*\))?!?:`,
+			"strip-type":      true, //strip 'type(scope):' from subjects
+			"issue-pattern":   `#(\d+)`,
+			"issue-url":       "", //e.g. 'https://github.com/owner/repo/issues/$1'
+			"templateText": `## {{.Version}} ({{.Date}})
+{{range .Groups}}
+### {{.Title}}
+
+{{range .Notes}} * {{if .Scope}}**{{.Scope}}:** {{end}}{{.Text}} ({{.ShortHash}})
+{{end}}{{end}}`,
+			"templateFile": "", //use if populated
+		}})
+}
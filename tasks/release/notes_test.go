@@ -0,0 +1,77 @@
+package release
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/tasks"
+)
+
+func TestRunTaskReleaseNotes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is required for this test")
+	}
+	dir, err := ioutil.TempDir("", "goxc-notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Tester", "-c", "user.email=tester@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "feat: before")
+	git("tag", "v0.9")
+	for _, message := range []string{"feat(cli): add flags (#12)", "fix: crash", "chore: tidy", "refactor!: drop old API", "update readme", "feat: new config\n\nBREAKING CHANGE: the old config is gone"} {
+		git("commit", "-q", "--allow-empty", "-m", message)
+	}
+	defer os.Setenv("SOURCE_DATE_EPOCH", os.Getenv("SOURCE_DATE_EPOCH"))
+	os.Setenv("SOURCE_DATE_EPOCH", "0")
+
+	s := config.Settings{AppName: "app", PackageVersion: "1.0", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, dir)
+	tasks.FillTaskSettingsDefaults(&s)
+	s.TaskSettings[tasks.TASK_RELEASE_NOTES]["issue-url"] = "https://example.com/issues/$1"
+	tp := tasks.TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: filepath.Join(dir, "dist"), Settings: &s}
+	notes, err := GatherNotes(tasks.TASK_RELEASE_NOTES, tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notes.PreviousTag != "v0.9" || len(notes.Groups) != 4 {
+		t.Fatalf("unexpected notes %+v", notes)
+	}
+	expectedTitles := []string{"Breaking Changes", "Features", "Bug Fixes", "Other Changes"}
+	//breaking changes are grouped together, whatever their type
+	expectedCounts := []int{2, 1, 1, 1}
+	for i, group := range notes.Groups {
+		if group.Title != expectedTitles[i] || len(group.Notes) != expectedCounts[i] {
+			t.Errorf("unexpected group %+v", group)
+		}
+	}
+	if !notes.Groups[0].Notes[0].Breaking || notes.Groups[1].Notes[0].Scope != "cli" {
+		t.Errorf("unexpected notes %+v", notes.Groups)
+	}
+	err = RunTaskReleaseNotes(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "dist", "1.0", "RELEASE_NOTES.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "## 1.0 (1970-01-01)\n\n### Breaking Changes\n\n * new config (" + notes.Groups[0].Notes[0].ShortHash + ")\n * drop old API (" + notes.Groups[0].Notes[1].ShortHash + ")\n" +
+		"\n### Features\n\n * **cli:** add flags ([#12](https://example.com/issues/12)) (" + notes.Groups[1].Notes[0].ShortHash + ")\n"
+	if !strings.HasPrefix(string(data), expected) {
+		t.Errorf("unexpected release notes:\n%s", data)
+	}
+}
@@ -0,0 +1,421 @@
+// Publishing artifacts to a forge's releases (GitHub, Gitea, ...). Each forge implements Publisher, and Publish does the rest
+package release
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/tasks"
+	"github.com/laher/goxc/tasks/httpc"
+)
+
+// The downloads page template, for the 'templateText' default of release tasks
+const DOWNLOADS_TEMPLATE = `---
+layout: default
+title: Downloads
+---
+{{.AppName}} downloads (version {{.Version}})
+
+{{range $k, $v := .Categories}}### {{$k}}
+
+{{range $v}} * [{{.Text}}]({{.RelativeLink}})
+{{end}}
+{{end}}
+
+{{.ExtraVars.footer}}`
+
+// A release, for a tag
+type Release struct {
+	TagName    string
+	Name       string
+	Body       string
+	Prerelease bool
+	Draft      bool
+	//set by the Publisher
+	ID        string
+	UploadURL string
+}
+
+// A file attached to a release
+type Asset struct {
+	ID   string
+	Name string
+	//hex-encoded, when the forge provides it
+	SHA256 string
+}
+
+// A forge's releases API
+type Publisher interface {
+	// Creates the release, or finds the existing one for its tag and updates its name, body & prerelease flag. Sets the release's ID
+	EnsureRelease(r *Release) error
+	Assets(r *Release) ([]Asset, error)
+	Upload(r *Release, name, fullPath, contentType string) error
+	DeleteAsset(r *Release, a Asset) error
+	// Publishes a draft release
+	Finalize(r *Release) error
+}
+
+// The delay before the first retry of an upload. It doubles for each retry
+var RetryDelay = 2 * time.Second
+
+// A file to publish, from the version directory
+type Artifact struct {
+	FullPath     string
+	RelativePath string
+	Name         string
+}
+
+// Default settings common to release tasks, for Register. 'downloadsPage' is the name of the local downloads page (if any)
+func DefaultSettings(downloadsPage string) map[string]interface{} {
+	return map[string]interface{}{
+		"body":              "Built by goxc",
+		"body-template":     false, //treat 'body' as a template over the uploaded downloads (e.g. '{{range .Categories}}...')
+		"body-file":         "",    //e.g. RELEASE_NOTES.md (see the 'release-notes' task), within the version directory. Replaces 'body'
+		"prerelease":        false, //releases with PrereleaseInfo are always prereleases
+		"draft":             false, //leave the release as a draft
+		"finalize":          true,  //create new releases as drafts, and publish them once all artifacts are uploaded
+		"parallel-uploads":  4,
+		"retries":           3,
+		"include":           "*.zip,*.tar.gz,*.deb",
+		"exclude":           downloadsPage + ",.goxc-temp",
+		"exists-action":     "omit", //replace, omit (or skip) or fail. Unchanged assets are always skipped
+		"downloadspage":     downloadsPage,
+		"outputFormat":      "by-file-extension", // use by-file-extension, markdown or html
+		"templateText":      DOWNLOADS_TEMPLATE,
+		"templateFile":      "", //use if populated
+		"templateExtraVars": map[string]interface{}{"footer": "Generated by goxc"}}
+}
+
+// Publishes the artifacts in the version directory to the release for the tag (see the 'tag' task), using the task's settings (see DefaultSettings).
+// 'downloadURL' gives an asset's public URL, for the body template & the downloads page.
+func Publish(p Publisher, taskName string, downloadURL func(tagName, name string) string, tp tasks.TaskParams) error {
+	version := tp.Settings.GetFullVersionName()
+	versionDir := filepath.Join(tp.OutDestRoot, version)
+	tagName := tp.Settings.GetTaskSettingString(tasks.TASK_TAG, "prefix") + version
+	downloadsPage := tp.Settings.GetTaskSettingString(taskName, "downloadspage")
+	format := outputFormat(tp.Settings.GetTaskSettingString(taskName, "outputFormat"), downloadsPage)
+	artifacts, err := FindArtifacts(taskName, downloadsPage, tp)
+	if err != nil {
+		return err
+	}
+	report := Report(taskName, artifacts, format == "markdown", func(name string) string {
+		return downloadURL(tagName, name)
+	}, tp)
+	body, err := Body(taskName, report, tp)
+	if err != nil {
+		return err
+	}
+	draft := tp.Settings.GetTaskSettingBool(taskName, "draft")
+	finalize := tp.Settings.GetTaskSettingBool(taskName, "finalize") && !draft
+	r := &Release{
+		TagName:    tagName,
+		Name:       version,
+		Body:       body,
+		Prerelease: tp.Settings.GetTaskSettingBool(taskName, "prerelease") || tp.Settings.PrereleaseInfo != "",
+		Draft:      draft || finalize}
+	err = p.EnsureRelease(r)
+	if err != nil {
+		return err
+	}
+	existing, err := p.Assets(r)
+	if err != nil {
+		return err
+	}
+	existsAction := strings.ToLower(tp.Settings.GetTaskSettingString(taskName, "exists-action"))
+	pending := []Artifact{}
+	for _, a := range artifacts {
+		upload, err := applyExistsAction(p, taskName, r, a, existing, existsAction, downloadURL(tagName, a.Name), tp)
+		if err != nil {
+			return err
+		}
+		if upload {
+			pending = append(pending, a)
+		}
+	}
+	err = uploadAll(p, r, pending, tp.Settings.GetTaskSettingInt(taskName, "parallel-uploads", 4), tp.Settings.GetTaskSettingInt(taskName, "retries", 3), tp)
+	if err != nil {
+		return err
+	}
+	if finalize {
+		if !tp.Settings.IsQuiet() {
+			log.Printf("Publishing release %s", tagName)
+		}
+		err = p.Finalize(r)
+		if err != nil {
+			return err
+		}
+	}
+	if downloadsPage == "" {
+		return nil
+	}
+	return writeDownloadsPage(filepath.Join(versionDir, downloadsPage), taskName, format, report, tp)
+}
+
+// The downloads of the artifacts, by category. 'downloadURL' gives an artifact's public URL
+func Report(taskName string, artifacts []Artifact, markdown bool, downloadURL func(name string) string, tp tasks.TaskParams) tasks.Report {
+	version := tp.Settings.GetFullVersionName()
+	report := tasks.Report{
+		AppName:    tp.AppName,
+		Version:    version,
+		Categories: map[string]*[]tasks.Download{},
+		ExtraVars:  tp.Settings.GetTaskSettingMap(taskName, "templateExtraVars")}
+	for _, a := range artifacts {
+		text := a.Name
+		if markdown {
+			text = strings.Replace(text, "_", "\\_", -1)
+		}
+		category := tasks.GetCategory(a.RelativePath)
+		downloads, ok := report.Categories[category]
+		if !ok {
+			downloads = &[]tasks.Download{}
+			report.Categories[category] = downloads
+		}
+		*downloads = append(*downloads, tasks.Download{Text: text, Version: version, RelativeLink: downloadURL(a.Name)})
+	}
+	return report
+}
+
+// The release body: the 'body-file' (within the version directory), or else the task's 'body' (a template over the report if 'body-template' is set).
+// Tasks without a 'body' setting use the markdown 'templateText' (or 'templateFile') instead
+func Body(taskName string, report tasks.Report, tp tasks.TaskParams) (string, error) {
+	if bodyFile := tp.Settings.GetTaskSettingString(taskName, "body-file"); bodyFile != "" {
+		data, err := ioutil.ReadFile(filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName(), bodyFile))
+		return string(data), err
+	}
+	body := &bytes.Buffer{}
+	if tp.Settings.GetTaskSetting(taskName, "body") == nil {
+		templateText := tp.Settings.GetTaskSettingString(taskName, "templateText")
+		templateFile := tp.Settings.GetTaskSettingString(taskName, "templateFile")
+		err := tasks.RunTemplate("body", templateFile, templateText, body, report, "markdown")
+		return body.String(), err
+	}
+	if !tp.Settings.GetTaskSettingBool(taskName, "body-template") {
+		return tp.Settings.GetTaskSettingString(taskName, "body"), nil
+	}
+	bodyTemplate, err := template.New("body").Parse(tp.Settings.GetTaskSettingString(taskName, "body"))
+	if err != nil {
+		return "", err
+	}
+	err = bodyTemplate.Execute(body, report)
+	return body.String(), err
+}
+
+// Whether to upload an artifact. Assets with the same content are left alone; others are handled according to the exists-action
+func applyExistsAction(p Publisher, taskName string, r *Release, a Artifact, existing []Asset, existsAction, location string, tp tasks.TaskParams) (bool, error) {
+	for _, asset := range existing {
+		if asset.Name != a.Name {
+			continue
+		}
+		if asset.SHA256 != "" {
+			checksum, err := FileSHA256(a.FullPath)
+			if err != nil {
+				return false, err
+			}
+			if strings.EqualFold(checksum, asset.SHA256) {
+				if !tp.Settings.IsQuiet() {
+					log.Printf("Skipping %s, which is already published (same sha256)", a.Name)
+				}
+				return false, nil
+			}
+		}
+		asset := asset
+		return tasks.PublishExistsAction(strings.TrimPrefix(taskName, "publish-"), existsAction, a.Name, location, func() error {
+			return p.DeleteAsset(r, asset)
+		}, tp)
+	}
+	return true, nil
+}
+
+// Uploads artifacts with up to 'parallel' at a time, retrying failures with an exponential backoff
+func uploadAll(p Publisher, r *Release, artifacts []Artifact, parallel, retries int, tp tasks.TaskParams) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+	queue := make(chan Artifact)
+	errs := make(chan error, len(artifacts))
+	wg := sync.WaitGroup{}
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range queue {
+				errs <- uploadWithRetries(p, r, a, retries, tp)
+			}
+		}()
+	}
+	for _, a := range artifacts {
+		queue <- a
+	}
+	close(queue)
+	wg.Wait()
+	close(errs)
+	failed := []string{}
+	for err := range errs {
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d upload(s) failed: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func uploadWithRetries(p Publisher, r *Release, a Artifact, retries int, tp tasks.TaskParams) error {
+	delay := RetryDelay
+	for attempt := 0; ; attempt++ {
+		if !tp.Settings.IsQuiet() {
+			log.Printf("Uploading %s", a.RelativePath)
+		}
+		err := p.Upload(r, a.Name, a.FullPath, httpc.GetContentType(a.Name))
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries || !isRetryable(err) {
+			return fmt.Errorf("%s: %v", a.Name, err)
+		}
+		log.Printf("Upload of %s failed (%v). Retrying in %v", a.Name, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+		//a failed upload can leave a broken asset behind
+		err = removeAsset(p, r, a.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", a.Name, err)
+		}
+	}
+}
+
+// Network errors, server errors & rate limits are worth retrying. Other client errors aren't
+func isRetryable(err error) bool {
+	if serr, ok := err.(httpc.HttpError); ok {
+		return serr.StatusCode >= 500 || serr.StatusCode == 429
+	}
+	return true
+}
+
+func removeAsset(p Publisher, r *Release, name string) error {
+	assets, err := p.Assets(r)
+	if err != nil {
+		return err
+	}
+	for _, asset := range assets {
+		if asset.Name == name {
+			return p.DeleteAsset(r, asset)
+		}
+	}
+	return nil
+}
+
+// The hex-encoded sha256 of a file, to compare with a published copy
+func FileSHA256(fullPath string) (string, error) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeDownloadsPage(reportFilename, taskName, format string, report tasks.Report, tp tasks.TaskParams) error {
+	templateText := tp.Settings.GetTaskSettingString(taskName, "templateText")
+	templateFile := tp.Settings.GetTaskSettingString(taskName, "templateFile")
+	flags := os.O_WRONLY | os.O_TRUNC | os.O_CREATE
+	out, err := os.OpenFile(reportFilename, flags, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	err = tasks.RunTemplate(reportFilename, templateFile, templateText, out, report, format)
+	if err != nil {
+		return err
+	}
+	//close explicitly for return value
+	return out.Close()
+}
+
+func outputFormat(format, downloadsPage string) string {
+	if format == "by-file-extension" {
+		if strings.HasSuffix(downloadsPage, ".md") || strings.HasSuffix(downloadsPage, ".markdown") {
+			format = "markdown"
+		} else if strings.HasSuffix(downloadsPage, ".html") || strings.HasSuffix(downloadsPage, ".htm") {
+			format = "html"
+		} else {
+			//unknown ...
+			format = ""
+		}
+	}
+	return format
+}
+
+// The task's included files in the version directory, sorted, excluding the downloads page (if any).
+// Published files are named after the file, so names must be unique
+func FindArtifacts(taskName, downloadsPage string, tp tasks.TaskParams) ([]Artifact, error) {
+	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	include := tp.Settings.GetTaskSettingString(taskName, "include")
+	exclude := tp.Settings.GetTaskSettingString(taskName, "exclude")
+	artifacts := []Artifact{}
+	seen := map[string]string{}
+	err := filepath.Walk(versionDir, func(fullPath string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() || fi.Name() == downloadsPage {
+			return err
+		}
+		relativePath, err := filepath.Rel(versionDir, fullPath)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		included, err := tasks.PublishIncluded(include, exclude, relativePath, fi, tp)
+		if err != nil || !included {
+			return err
+		}
+		if other, exists := seen[fi.Name()]; exists {
+			return fmt.Errorf("Duplicate file name %s (%s). Published files must have unique names", relativePath, other)
+		}
+		seen[fi.Name()] = relativePath
+		artifacts = append(artifacts, Artifact{fullPath, relativePath, fi.Name()})
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("No artifacts built for this version yet. Please build some artifacts before running the '%s' task", taskName)
+		}
+		return nil, err
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].RelativePath < artifacts[j].RelativePath })
+	return artifacts, nil
+}
@@ -0,0 +1,189 @@
+package release
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/tasks"
+)
+
+const testTask = "publish-test"
+
+// An in-memory forge
+type fakePublisher struct {
+	sync.Mutex
+	release   *Release
+	assets    map[string]string
+	sha256s   map[string]string
+	deleted   []string
+	failures  int
+	finalized bool
+}
+
+func (p *fakePublisher) EnsureRelease(r *Release) error {
+	r.ID = "1"
+	p.release = r
+	return nil
+}
+
+func (p *fakePublisher) Assets(r *Release) ([]Asset, error) {
+	p.Lock()
+	defer p.Unlock()
+	assets := []Asset{}
+	for name := range p.assets {
+		assets = append(assets, Asset{ID: "id-" + name, Name: name, SHA256: p.sha256s[name]})
+	}
+	return assets, nil
+}
+
+func (p *fakePublisher) Upload(r *Release, name, fullPath, contentType string) error {
+	p.Lock()
+	defer p.Unlock()
+	data, err := ioutil.ReadFile(fullPath)
+	p.assets[name] = string(data)
+	if p.failures > 0 {
+		//a broken upload
+		p.failures--
+		return errors.New("connection reset by peer")
+	}
+	return err
+}
+
+func (p *fakePublisher) DeleteAsset(r *Release, a Asset) error {
+	p.Lock()
+	defer p.Unlock()
+	p.deleted = append(p.deleted, a.ID)
+	delete(p.assets, a.Name)
+	return nil
+}
+
+func (p *fakePublisher) Finalize(r *Release) error {
+	p.finalized = true
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	versionDir := filepath.Join(dir, "1.0-rc1")
+	os.MkdirAll(filepath.Join(versionDir, "linux_amd64"), 0755)
+	ioutil.WriteFile(filepath.Join(versionDir, "linux_amd64", "app_1.0-rc1_linux_amd64.tar.gz"), []byte("tgz"), 0644)
+	ioutil.WriteFile(filepath.Join(versionDir, "app_1.0-rc1_windows_amd64.zip"), []byte("zip"), 0644)
+	ioutil.WriteFile(filepath.Join(versionDir, "notes.txt"), []byte("not included"), 0644)
+
+	s := config.Settings{AppName: "app", PackageVersion: "1.0", PrereleaseInfo: "rc1", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, dir)
+	tasks.FillTaskSettingsDefaults(&s)
+	s.TaskSettings[testTask] = DefaultSettings("test.md")
+	s.TaskSettings[testTask]["body"] = "{{range $k, $v := .Categories}}{{range $v}}{{.RelativeLink}} {{end}}{{end}}"
+	s.TaskSettings[testTask]["body-template"] = true
+	s.TaskSettings[tasks.TASK_TAG]["prefix"] = "v"
+	tp := tasks.TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: dir, Settings: &s}
+	downloadURL := func(tagName, name string) string {
+		return "https://example.com/" + tagName + "/" + name
+	}
+	p := &fakePublisher{assets: map[string]string{"app_1.0-rc1_windows_amd64.zip": "old"}}
+	err = Publish(p, testTask, downloadURL, tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//created as a draft, then published
+	if p.release.TagName != "v1.0-rc1" || !p.release.Prerelease || !p.release.Draft || !p.finalized {
+		t.Errorf("unexpected release %+v", p.release)
+	}
+	if !strings.Contains(p.release.Body, "https://example.com/v1.0-rc1/app_1.0-rc1_linux_amd64.tar.gz") {
+		t.Errorf("unexpected body %s", p.release.Body)
+	}
+	//existing assets are omitted by default
+	if len(p.assets) != 2 || p.assets["app_1.0-rc1_windows_amd64.zip"] != "old" || p.assets["app_1.0-rc1_linux_amd64.tar.gz"] != "tgz" {
+		t.Errorf("unexpected assets %v", p.assets)
+	}
+	page, err := ioutil.ReadFile(filepath.Join(versionDir, "test.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), "[app\\_1.0-rc1\\_windows\\_amd64.zip](https://example.com/v1.0-rc1/app_1.0-rc1_windows_amd64.zip)") {
+		t.Errorf("unexpected downloads page %s", page)
+	}
+
+	s.TaskSettings[testTask]["exists-action"] = "replace"
+	err = Publish(p, testTask, downloadURL, tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.deleted) != 2 || p.assets["app_1.0-rc1_windows_amd64.zip"] != "zip" {
+		t.Errorf("expected replaced assets, got %v (deleted %v)", p.assets, p.deleted)
+	}
+
+	s.TaskSettings[testTask]["exists-action"] = "fail"
+	err = Publish(p, testTask, downloadURL, tp)
+	if err == nil {
+		t.Errorf("expected an error for existing assets")
+	}
+	//unchanged assets aren't a failure
+	p.sha256s = map[string]string{
+		"app_1.0-rc1_windows_amd64.zip":  "4A70FE9AA6436E02C2DEA340FBD1E352E4EF2D8CE6CA52AD25D4B95471FC8BF2",
+		"app_1.0-rc1_linux_amd64.tar.gz": "407f3ae627dafcf01abdcc6632da77b6a2ab73d22077e5c494a90c66329b5fb9"}
+	err = Publish(p, testTask, downloadURL, tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//release notes as the body
+	ioutil.WriteFile(filepath.Join(versionDir, "RELEASE_NOTES.md"), []byte("## 1.0-rc1 {{not a template}}"), 0644)
+	s.TaskSettings[testTask]["body-file"] = "RELEASE_NOTES.md"
+	err = Publish(p, testTask, downloadURL, tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.release.Body != "## 1.0-rc1 {{not a template}}" {
+		t.Errorf("unexpected body %s", p.release.Body)
+	}
+}
+
+func TestUploadRetries(t *testing.T) {
+	RetryDelay = 0
+	dir, err := ioutil.TempDir("", "goxc-release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "1.0"), 0755)
+	for _, name := range []string{"a.zip", "b.zip", "c.zip"} {
+		ioutil.WriteFile(filepath.Join(dir, "1.0", name), []byte(name), 0644)
+	}
+	s := config.Settings{AppName: "app", PackageVersion: "1.0", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, dir)
+	tasks.FillTaskSettingsDefaults(&s)
+	s.TaskSettings[testTask] = DefaultSettings("")
+	s.TaskSettings[testTask]["retries"] = 2
+	tp := tasks.TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: dir, Settings: &s}
+	downloadURL := func(tagName, name string) string {
+		return name
+	}
+	p := &fakePublisher{assets: map[string]string{}, failures: 2}
+	err = Publish(p, testTask, downloadURL, tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//broken uploads were removed before retrying
+	if len(p.assets) != 3 || len(p.deleted) != 2 || !p.finalized {
+		t.Errorf("unexpected assets %v (deleted %v)", p.assets, p.deleted)
+	}
+	//too many failures: the release is left as a draft
+	p = &fakePublisher{assets: map[string]string{}, failures: 3}
+	s.TaskSettings[testTask]["parallel-uploads"] = 1
+	err = Publish(p, testTask, downloadURL, tp)
+	if err == nil || p.finalized {
+		t.Errorf("expected a failed upload, got %v", err)
+	}
+}
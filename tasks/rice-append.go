@@ -7,7 +7,6 @@ import (
 	"path/filepath"
 
 	"github.com/laher/goxc/config"
-	"github.com/laher/goxc/core"
 	"github.com/laher/goxc/executils"
 )
 
@@ -45,7 +44,7 @@ func runTaskRiceAppend(tp TaskParams) (err error) {
 				exeName = filepath.Base(mainDir)
 
 			}
-			binPath, err := core.GetAbsoluteBin(dest.Os, dest.Arch, tp.Settings.AppName, exeName, tp.WorkingDirectory, tp.Settings.GetFullVersionName(), tp.Settings.OutPath, tp.Settings.ArtifactsDest)
+			binPath, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
 
 			if err != nil {
 				return err
@@ -24,7 +24,6 @@ import (
 
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
-	"github.com/laher/goxc/core"
 	"github.com/laher/goxc/platforms"
 )
 
@@ -59,7 +58,7 @@ func runTaskRmBin(tp TaskParams) error {
 }
 
 func rmBinPlat(dest platforms.Platform, tp TaskParams, exeName string) error {
-	binPath, err := core.GetAbsoluteBin(dest.Os, dest.Arch, tp.Settings.AppName, exeName, tp.WorkingDirectory, tp.Settings.GetFullVersionName(), tp.Settings.OutPath, tp.Settings.ArtifactsDest)
+	binPath, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
 	if err != nil {
 		return err
 	}
@@ -67,6 +66,12 @@ func rmBinPlat(dest platforms.Platform, tp TaskParams, exeName string) error {
 	if err != nil {
 		return err
 	}
+	if dest.Os == platforms.DARWIN {
+		err = os.RemoveAll(getDarwinAppBundle(binPath, exeName))
+		if err != nil {
+			return err
+		}
+	}
 	//if empty, remove dir
 	binDir := filepath.Dir(binPath)
 	files, err := ioutil.ReadDir(binDir)
@@ -0,0 +1,164 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/tasks/httpc"
+	"github.com/laher/goxc/tasks/s3"
+)
+
+// TASK_PUBLISH_S3 is the task name to be used in the CLI
+const TASK_PUBLISH_S3 = "publish-s3"
+
+type s3TaskConfig struct {
+	includePatterns string
+	excludePatterns string
+	exists          string
+	prefixTemplate  *template.Template
+	client          *s3.Client
+}
+
+func init() {
+	Register(Task{TASK_PUBLISH_S3,
+		"Upload artifacts to S3-compatible object storage (AWS S3, MinIO, ...). Requires 'bucket', plus AWS_ACCESS_KEY_ID & AWS_SECRET_ACCESS_KEY in the environment. Set 'endpoint' and 'path-style' for MinIO & co. Keys are 'prefix' (a template) plus the path within the version directory. Large files are uploaded in parts of 'part-size-mb'.",
+		s3RunTask,
+		map[string]interface{}{
+			"endpoint":      "", //defaults to AWS, for the region
+			"region":        "us-east-1",
+			"bucket":        "",
+			"prefix":        "{{.AppName}}/{{.Version}}/",
+			"path-style":    false,
+			"part-size-mb":  64,
+			"include":       "*.zip,*.tar.gz,*.deb",
+			"exclude":       "*.orig.tar.gz,data.tar.gz,control.tar.gz,*.debian.tar.gz,*-dev_*.deb",
+			"exists-action": "fail",
+		}})
+}
+
+func s3RunTask(tp TaskParams) error {
+	bucket := tp.Settings.GetTaskSettingString(TASK_PUBLISH_S3, "bucket")
+	region := tp.Settings.GetTaskSettingString(TASK_PUBLISH_S3, "region")
+	missing := []string{}
+	if bucket == "" {
+		missing = append(missing, "bucket")
+	}
+	if region == "" {
+		missing = append(missing, "region")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("S3 task configuration missing %v", missing)
+	}
+	prefixTemplate, err := template.New("prefix").Parse(tp.Settings.GetTaskSettingString(TASK_PUBLISH_S3, "prefix"))
+	if err != nil {
+		return err
+	}
+	endpoint := tp.Settings.GetTaskSettingString(TASK_PUBLISH_S3, "endpoint")
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	client := &s3.Client{
+		Endpoint:  endpoint,
+		Region:    region,
+		Bucket:    bucket,
+		PathStyle: tp.Settings.GetTaskSettingBool(TASK_PUBLISH_S3, "path-style"),
+		PartSize:  int64(tp.Settings.GetTaskSettingInt(TASK_PUBLISH_S3, "part-size-mb", 64)) * 1024 * 1024,
+		IsVerbose: tp.Settings.IsVerbose()}
+	err = client.CredentialsFromEnv()
+	if err != nil {
+		return err
+	}
+	config := &s3TaskConfig{
+		exists:          strings.ToLower(tp.Settings.GetTaskSettingString(TASK_PUBLISH_S3, "exists-action")),
+		includePatterns: tp.Settings.GetTaskSettingString(TASK_PUBLISH_S3, "include"),
+		excludePatterns: tp.Settings.GetTaskSettingString(TASK_PUBLISH_S3, "exclude"),
+		prefixTemplate:  prefixTemplate,
+		client:          client,
+	}
+	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	return filepath.Walk(versionDir, func(path string, info os.FileInfo, e error) error {
+		return s3Walk(config, path, info, e, tp)
+	})
+}
+
+func s3Walk(config *s3TaskConfig, fullPath string, fi os.FileInfo, err error, tp TaskParams) error {
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return nil
+	}
+	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	relativePath, err := filepath.Rel(versionDir, fullPath)
+	if err != nil {
+		return err
+	}
+	relativePath = filepath.ToSlash(relativePath)
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Considering %s", relativePath)
+	}
+	included, err := PublishIncluded(config.includePatterns, config.excludePatterns, relativePath, fi, tp)
+	if err != nil || !included {
+		return err
+	}
+	var prefix bytes.Buffer
+	err = config.prefixTemplate.Execute(&prefix, httpURLTemplateContext(tp, fi))
+	if err != nil {
+		return err
+	}
+	key := strings.TrimPrefix(prefix.String()+relativePath, "/")
+	location := "s3://" + config.client.Bucket + "/" + key
+	exists, err := config.client.Exists(key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		upload, err := PublishExistsAction("s3", config.exists, fi.Name(), location, func() error {
+			//uploads overwrite objects anyway
+			return nil
+		}, tp)
+		if err != nil || !upload {
+			return err
+		}
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Putting %s to %s", fi.Name(), location)
+	}
+	return config.client.Upload(key, fullPath, s3ContentType(fi.Name()))
+}
+
+// Content types for browsers downloading artifacts
+func s3ContentType(name string) string {
+	contentType := httpc.GetContentType(name)
+	if contentType != "text/plain" {
+		return contentType
+	}
+	if contentType = mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
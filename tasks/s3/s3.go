@@ -0,0 +1,372 @@
+// A minimal client for S3-compatible object storage (AWS, MinIO, ...), signing requests with AWS Signature Version 4
+package s3
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/tasks/httpc"
+)
+
+const (
+	//the smallest part S3 accepts, except for the last one
+	MIN_PART_SIZE = 5 * 1024 * 1024
+	//S3's limit on the number of parts
+	MAX_PARTS = 10000
+	//hash of an empty payload
+	EMPTY_SHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+type Client struct {
+	//e.g. 'https://s3.eu-west-1.amazonaws.com' or 'http://localhost:9000'
+	Endpoint     string
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	//'https://endpoint/bucket/key' rather than 'https://bucket.endpoint/key'. MinIO usually needs this
+	PathStyle bool
+	//files larger than this are uploaded in parts of this size
+	PartSize  int64
+	IsVerbose bool
+	//for tests
+	now func() time.Time
+}
+
+// Credentials from the standard AWS environment variables
+func (c *Client) CredentialsFromEnv() error {
+	c.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	c.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	c.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return errors.New("S3 credentials missing. Please set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return nil
+}
+
+// The URL of an object, or of the bucket for an empty key
+func (c *Client) ObjectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("Invalid S3 endpoint '%s'", c.Endpoint)
+	}
+	if c.PathStyle {
+		u.Path += "/" + c.Bucket + "/" + key
+	} else {
+		u.Host = c.Bucket + "." + u.Host
+		u.Path += "/" + key
+	}
+	//send the path exactly as it is signed (Go would leave e.g. '+' unescaped)
+	u.RawPath = uriEncode(u.Path, false)
+	return u, nil
+}
+
+// Whether an object exists
+func (c *Client) Exists(key string) (bool, error) {
+	resp, err := c.do("HEAD", key, url.Values{}, nil, "")
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("Unexpected status %s when checking for existence of %s", resp.Status, key)
+}
+
+func (c *Client) Delete(key string) error {
+	resp, err := c.do("DELETE", key, url.Values{}, nil, "")
+	if err != nil {
+		return err
+	}
+	_, err = parse(resp, nil)
+	return err
+}
+
+// Uploads a file, in parts if it is larger than PartSize
+func (c *Client) Upload(key, filename, contentType string) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	partSize := c.PartSize
+	if partSize < MIN_PART_SIZE {
+		partSize = MIN_PART_SIZE
+	}
+	//grow parts for very large files
+	for fi.Size()/partSize >= MAX_PARTS {
+		partSize *= 2
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if fi.Size() <= partSize {
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		resp, err := c.do("PUT", key, url.Values{}, data, contentType)
+		if err != nil {
+			return err
+		}
+		_, err = parse(resp, nil)
+		return err
+	}
+	return c.uploadParts(key, f, partSize, contentType)
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+func (c *Client) uploadParts(key string, r io.Reader, partSize int64, contentType string) error {
+	resp, err := c.do("POST", key, url.Values{"uploads": {""}}, nil, contentType)
+	if err != nil {
+		return err
+	}
+	var initiated initiateMultipartUploadResult
+	_, err = parse(resp, &initiated)
+	if err != nil {
+		return err
+	}
+	if initiated.UploadID == "" {
+		return errors.New("No UploadId in S3's response")
+	}
+	complete := completeMultipartUpload{}
+	err = func() error {
+		buf := make([]byte, partSize)
+		for partNumber := 1; ; partNumber++ {
+			n, err := io.ReadFull(r, buf)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return err
+			}
+			if c.IsVerbose {
+				log.Printf("Uploading part %d of %s (%d bytes)", partNumber, key, n)
+			}
+			query := url.Values{"partNumber": {fmt.Sprintf("%d", partNumber)}, "uploadId": {initiated.UploadID}}
+			resp, err := c.do("PUT", key, query, buf[:n], "")
+			if err != nil {
+				return err
+			}
+			_, err = parse(resp, nil)
+			if err != nil {
+				return err
+			}
+			complete.Parts = append(complete.Parts, completedPart{partNumber, resp.Header.Get("ETag")})
+		}
+	}()
+	if err == nil {
+		var data []byte
+		data, err = xml.Marshal(complete)
+		if err == nil {
+			resp, err = c.do("POST", key, url.Values{"uploadId": {initiated.UploadID}}, data, "application/xml")
+			if err == nil {
+				//errors can arrive after a '200 OK'
+				_, err = parse(resp, nil)
+			}
+		}
+	}
+	if err != nil {
+		//don't leave parts lying around (they cost money)
+		resp, abortErr := c.do("DELETE", key, url.Values{"uploadId": {initiated.UploadID}}, nil, "")
+		if abortErr == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+// An S3 error response
+type Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("S3 error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Reads a response, returning an Error for S3 errors, and unmarshalling XML into 'v' (if not nil)
+func parse(resp *http.Response, v interface{}) ([]byte, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 || bytes.Contains(body, []byte("<Error>")) {
+		e := Error{StatusCode: resp.StatusCode}
+		xml.Unmarshal(body, &e)
+		if e.Code == "" {
+			e.Code = resp.Status
+		}
+		return nil, e
+	}
+	if v != nil {
+		err = xml.Unmarshal(body, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+func (c *Client) do(method, key string, query url.Values, data []byte, contentType string) (*http.Response, error) {
+	u, err := c.ObjectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = canonicalQuery(query)
+	headers := map[string]string{}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	c.sign(method, u, headers, data, now())
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	return httpc.DoHttpWithHeaders(method, u.String(), headers, body, int64(len(data)), c.IsVerbose)
+}
+
+// Adds the SigV4 'Authorization' header (and the x-amz-* headers it covers) for a request. All of 'headers' are signed.
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (c *Client) sign(method string, u *url.URL, headers map[string]string, payload []byte, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	payloadHash := EMPTY_SHA256
+	if len(payload) > 0 {
+		payloadHash = hexSha256(payload)
+	}
+	headers["x-amz-date"] = amzDate
+	headers["x-amz-content-sha256"] = payloadHash
+	if c.SessionToken != "" {
+		headers["x-amz-security-token"] = c.SessionToken
+	}
+	canonical := map[string]string{"host": u.Host}
+	names := []string{"host"}
+	for name, value := range headers {
+		name = strings.ToLower(name)
+		canonical[name] = strings.TrimSpace(value)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + canonical[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		method,
+		uriEncode(u.Path, false),
+		u.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash}, "\n")
+	scope := amzDate[:8] + "/" + c.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSha256([]byte(canonicalRequest))
+	key := hmacSha256([]byte("AWS4"+c.SecretKey), amzDate[:8])
+	for _, part := range []string{c.Region, "s3", "aws4_request"} {
+		key = hmacSha256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+	headers["Authorization"] = "AWS4-HMAC-SHA256 Credential=" + c.AccessKey + "/" + scope + ", SignedHeaders=" + signedHeaders + ", Signature=" + signature
+}
+
+// Query strings sorted by key, with SigV4's encoding
+func canonicalQuery(query url.Values) string {
+	keys := []string{}
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := []string{}
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// RFC 3986 encoding, as SigV4 specifies it. Slashes are kept in paths
+func uriEncode(s string, encodeSlash bool) string {
+	buf := &bytes.Buffer{}
+	for _, b := range []byte(s) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' || (b == '/' && !encodeSlash) {
+			buf.WriteByte(b)
+		} else {
+			fmt.Fprintf(buf, "%%%02X", b)
+		}
+	}
+	return buf.String()
+}
+
+func hexSha256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,325 @@
+package sftp
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP (version 3) packet types & flags. See https://datatracker.ietf.org/doc/html/draft-ietf-secsh-filexfer-02
+const (
+	fxpInit     = 1
+	fxpVersion  = 2
+	fxpOpen     = 3
+	fxpClose    = 4
+	fxpWrite    = 6
+	fxpRemove   = 13
+	fxpMkdir    = 14
+	fxpStat     = 17
+	fxpRename   = 18
+	fxpStatus   = 101
+	fxpHandle   = 102
+	fxpAttrs    = 105
+	fxpExtended = 200
+
+	fxfWrite = 0x02
+	fxfCreat = 0x08
+	fxfTrunc = 0x10
+
+	fxOK         = 0
+	fxNoSuchFile = 2
+
+	attrPermissions = 0x04
+
+	//a common maximum for servers
+	writeChunkSize = 32 * 1024
+	posixRename    = "posix-rename@openssh.com"
+)
+
+// A status other than 'OK', from the server
+type StatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sftp error %d: %s", e.Code, e.Message)
+}
+
+func IsNotExist(err error) bool {
+	serr, ok := err.(*StatusError)
+	return ok && serr.Code == fxNoSuchFile
+}
+
+// A minimal SFTP client: enough to upload files. Requests are sent one at a time
+type Client struct {
+	r          io.Reader
+	w          io.WriteCloser
+	session    *ssh.Session
+	nextID     uint32
+	extensions map[string]string
+}
+
+// Starts the 'sftp' subsystem on a new session
+func NewClient(conn *ssh.Client) (*Client, error) {
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	w, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	err = session.RequestSubsystem("sftp")
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	c, err := NewClientPipe(r, w)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	c.session = session
+	return c, nil
+}
+
+// An SFTP client over any pipe
+func NewClientPipe(r io.Reader, w io.WriteCloser) (*Client, error) {
+	c := &Client{r: r, w: w, extensions: map[string]string{}}
+	_, err := w.Write(marshal(fxpInit, uint32(3)))
+	if err != nil {
+		return nil, err
+	}
+	typ, data, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	if typ != fxpVersion || len(data) < 4 {
+		return nil, fmt.Errorf("unexpected sftp packet %d", typ)
+	}
+	if version := binary.BigEndian.Uint32(data); version != 3 {
+		return nil, fmt.Errorf("unsupported sftp version %d", version)
+	}
+	data = data[4:]
+	for len(data) > 0 {
+		var name, value string
+		name, data = readString(data)
+		value, data = readString(data)
+		c.extensions[name] = value
+	}
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	err := c.w.Close()
+	if c.session != nil {
+		c.session.Close()
+	}
+	return err
+}
+
+// Whether the path exists
+func (c *Client) Exists(p string) (bool, error) {
+	typ, data, err := c.request(fxpStat, p)
+	if err != nil {
+		return false, err
+	}
+	if typ == fxpAttrs {
+		return true, nil
+	}
+	err = statusError(typ, data)
+	if IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (c *Client) Mkdir(p string) error {
+	return c.requestStatus(fxpMkdir, p, uint32(0))
+}
+
+// Makes the directory and any missing parents
+func (c *Client) MkdirAll(p string) error {
+	exists, err := c.Exists(p)
+	if err != nil || exists {
+		return err
+	}
+	if parent := path.Dir(p); parent != p && parent != "." {
+		err = c.MkdirAll(parent)
+		if err != nil {
+			return err
+		}
+	}
+	return c.Mkdir(p)
+}
+
+func (c *Client) Remove(p string) error {
+	return c.requestStatus(fxpRemove, p)
+}
+
+// Renames, replacing any existing file at 'newPath' (atomically, if the server supports posix-rename)
+func (c *Client) Rename(oldPath, newPath string) error {
+	if _, ok := c.extensions[posixRename]; ok {
+		return c.requestStatus(fxpExtended, posixRename, oldPath, newPath)
+	}
+	//standard renames fail for existing files
+	err := c.Remove(newPath)
+	if err != nil && !IsNotExist(err) {
+		return err
+	}
+	return c.requestStatus(fxpRename, oldPath, newPath)
+}
+
+// Uploads a local file (creating or truncating the remote file), with the given permissions
+func (c *Client) Put(localPath, remotePath string, perm os.FileMode) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	typ, data, err := c.request(fxpOpen, remotePath, uint32(fxfWrite|fxfCreat|fxfTrunc), uint32(attrPermissions), uint32(perm.Perm()))
+	if err != nil {
+		return err
+	}
+	if typ != fxpHandle {
+		return statusError(typ, data)
+	}
+	handle, _ := readString(data)
+	buf := make([]byte, writeChunkSize)
+	var offset uint64
+	for {
+		n, rerr := f.Read(buf)
+		if n > 0 {
+			err = c.requestStatus(fxpWrite, handle, offset, buf[:n])
+			if err != nil {
+				c.requestStatus(fxpClose, handle)
+				return err
+			}
+			offset += uint64(n)
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			c.requestStatus(fxpClose, handle)
+			return rerr
+		}
+	}
+	return c.requestStatus(fxpClose, handle)
+}
+
+// Sends a request (with a new id) and reads its response. Returns the response's type & data (after the id)
+func (c *Client) request(typ byte, args ...interface{}) (byte, []byte, error) {
+	c.nextID++
+	id := c.nextID
+	_, err := c.w.Write(marshal(typ, append([]interface{}{id}, args...)...))
+	if err != nil {
+		return 0, nil, err
+	}
+	rtyp, data, err := readPacket(c.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != id {
+		return 0, nil, errors.New("unexpected sftp response id")
+	}
+	return rtyp, data[4:], nil
+}
+
+func (c *Client) requestStatus(typ byte, args ...interface{}) error {
+	rtyp, data, err := c.request(typ, args...)
+	if err != nil {
+		return err
+	}
+	return statusError(rtyp, data)
+}
+
+func statusError(typ byte, data []byte) error {
+	if typ != fxpStatus || len(data) < 4 {
+		return fmt.Errorf("unexpected sftp packet %d", typ)
+	}
+	code := binary.BigEndian.Uint32(data)
+	if code == fxOK {
+		return nil
+	}
+	message, _ := readString(data[4:])
+	return &StatusError{code, message}
+}
+
+// A packet: its length, type & fields. Strings & byte slices are length-prefixed
+func marshal(typ byte, args ...interface{}) []byte {
+	packet := []byte{0, 0, 0, 0, typ}
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case uint32:
+			packet = binary.BigEndian.AppendUint32(packet, v)
+		case uint64:
+			packet = binary.BigEndian.AppendUint64(packet, v)
+		case string:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+			packet = append(packet, v...)
+		case []byte:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+			packet = append(packet, v...)
+		default:
+			panic(fmt.Sprintf("unsupported sftp field %T", arg))
+		}
+	}
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+	return packet
+}
+
+// Reads a packet, returning its type and data
+func readPacket(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length < 1 || length > 256*1024 {
+		return 0, nil, fmt.Errorf("invalid sftp packet length %d", length)
+	}
+	data := make([]byte, length-1)
+	_, err = io.ReadFull(r, data)
+	return header[4], data, err
+}
+
+func readString(data []byte) (string, []byte) {
+	if len(data) < 4 {
+		return "", nil
+	}
+	length := binary.BigEndian.Uint32(data)
+	if uint32(len(data)-4) < length {
+		return "", nil
+	}
+	return string(data[4 : 4+length]), data[4+length:]
+}
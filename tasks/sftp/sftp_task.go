@@ -0,0 +1,320 @@
+package sftp
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/platforms"
+	"github.com/laher/goxc/tasks"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+//runs automatically
+func init() {
+	tasks.Register(tasks.Task{
+		Name:        tasks.TASK_PUBLISH_SFTP,
+		Description: "Upload artifacts to an SFTP server, into 'dir' (a template, with {{.Version}}, {{.Os}}, {{.Arch}} etc), creating missing directories. Authenticates with 'key-file', the ssh agent, or the password in the environment variable named by 'password-env', and verifies the host against 'known-hosts'. Files are uploaded under a temporary name, then renamed.",
+		Run:         RunTaskPubSFTP,
+		DefaultSettings: map[string]interface{}{
+			"host":               "",
+			"port":               22,
+			"user":               "", //defaults to the current user
+			"key-file":           "", //e.g. '~/.ssh/id_ed25519'
+			"key-passphrase-env": "",
+			"password-env":       "SFTP_PASSWORD",
+			"agent":              true, //use the agent at SSH_AUTH_SOCK, if any
+			"known-hosts":        "~/.ssh/known_hosts",
+			"dir":                "{{.AppName}}/{{.Version}}",
+			"include":            "*.zip,*.tar.gz,*.deb",
+			"exclude":            "*.orig.tar.gz,data.tar.gz,control.tar.gz,*.debian.tar.gz,*-dev_*.deb",
+			"exists-action":      "fail",
+			"timeout":            30}}) //seconds, for connecting
+}
+
+var platformSeparators = regexp.MustCompile(`[/_.-]`)
+
+func RunTaskPubSFTP(tp tasks.TaskParams) error {
+	host := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "host")
+	user := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "user")
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	knownHosts := expandHome(tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "known-hosts"))
+	missing := []string{}
+	if host == "" {
+		missing = append(missing, "host")
+	}
+	if user == "" {
+		missing = append(missing, "user")
+	}
+	if knownHosts == "" {
+		missing = append(missing, "known-hosts")
+	}
+	auths, agentConn, err := authMethods(tp)
+	if err != nil {
+		return err
+	}
+	if agentConn != nil {
+		defer agentConn.Close()
+	}
+	if len(auths) == 0 {
+		missing = append(missing, "authentication (key-file, ssh agent or password-env)")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("sftp configuration missing (%v)", missing)
+	}
+	dirTemplate, err := template.New("dir").Parse(tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "dir"))
+	if err != nil {
+		return err
+	}
+	hostKeyCallback, err := knownhosts.New(knownHosts)
+	if err != nil {
+		return err
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(tp.Settings.GetTaskSettingInt(tasks.TASK_PUBLISH_SFTP, "port", 22)))
+	hostKeyAlgorithms, err := knownHostKeyAlgorithms(knownHosts, address)
+	if err != nil {
+		return err
+	}
+	timeout := time.Duration(tp.Settings.GetTaskSettingInt(tasks.TASK_PUBLISH_SFTP, "timeout", 30)) * time.Second
+	conn, err := ssh.Dial("tcp", address, &ssh.ClientConfig{User: user, Auth: auths, HostKeyCallback: hostKeyCallback, HostKeyAlgorithms: hostKeyAlgorithms, Timeout: timeout})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client, err := NewClient(conn)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	include := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "include")
+	exclude := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "exclude")
+	existsAction := strings.ToLower(tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "exists-action"))
+	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	return filepath.Walk(versionDir, func(fullPath string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		relativePath, err := filepath.Rel(versionDir, fullPath)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		included, err := tasks.PublishIncluded(include, exclude, relativePath, fi, tp)
+		if err != nil || !included {
+			return err
+		}
+		var dir bytes.Buffer
+		err = dirTemplate.Execute(&dir, templateContext(tp, relativePath, fi))
+		if err != nil {
+			return err
+		}
+		return upload(client, fullPath, path.Clean(dir.String()), fi, existsAction, address, tp)
+	})
+}
+
+// Uploads under a temporary name, then renames, so that the file appears complete or not at all
+func upload(client *Client, fullPath, dir string, fi os.FileInfo, existsAction, address string, tp tasks.TaskParams) error {
+	remotePath := path.Join(dir, fi.Name())
+	location := "sftp://" + address + "/" + strings.TrimPrefix(remotePath, "/")
+	err := client.MkdirAll(dir)
+	if err != nil {
+		return err
+	}
+	exists, err := client.Exists(remotePath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		upload, err := tasks.PublishExistsAction("sftp", existsAction, fi.Name(), location, func() error {
+			//the rename replaces it
+			return nil
+		}, tp)
+		if err != nil || !upload {
+			return err
+		}
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Uploading %s to %s", fi.Name(), location)
+	}
+	tmpPath := path.Join(dir, "."+fi.Name()+".goxc-tmp")
+	err = client.Put(fullPath, tmpPath, fi.Mode())
+	if err == nil {
+		err = client.Rename(tmpPath, remotePath)
+	}
+	if err != nil {
+		client.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// The authentication methods, and the connection to the ssh agent (if any), for closing once done
+func authMethods(tp tasks.TaskParams) ([]ssh.AuthMethod, net.Conn, error) {
+	auths := []ssh.AuthMethod{}
+	var agentConn net.Conn
+	if keyFile := expandHome(tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "key-file")); keyFile != "" {
+		key, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		var signer ssh.Signer
+		if passphraseEnv := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "key-passphrase-env"); passphraseEnv != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(os.Getenv(passphraseEnv)))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", keyFile, err)
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" && tp.Settings.GetTaskSettingBool(tasks.TASK_PUBLISH_SFTP, "agent") {
+		var err error
+		agentConn, err = net.Dial("unix", socket)
+		if err != nil {
+			log.Printf("WARNING: ssh agent unavailable: %v", err)
+		} else {
+			auths = append(auths, ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers))
+		}
+	}
+	if passwordEnv := tp.Settings.GetTaskSettingString(tasks.TASK_PUBLISH_SFTP, "password-env"); passwordEnv != "" {
+		if password := os.Getenv(passwordEnv); password != "" {
+			auths = append(auths, ssh.Password(password))
+		}
+	}
+	return auths, agentConn, nil
+}
+
+func templateContext(tp tasks.TaskParams, relativePath string, fi os.FileInfo) map[string]interface{} {
+	goos, arch := artifactPlatform(relativePath)
+	return map[string]interface{}{
+		"AppName":        tp.Settings.AppName,
+		"Version":        tp.Settings.GetFullVersionName(),
+		"PackageVersion": tp.Settings.PackageVersion,
+		"PrereleaseInfo": tp.Settings.PrereleaseInfo,
+		"Os":             goos,
+		"Arch":           arch,
+		"FileName":       fi.Name(),
+	}
+}
+
+// The platform in an artifact's path (e.g. 'linux_amd64/app' or 'app_1.0_linux_amd64.tar.gz'), if any
+func artifactPlatform(relativePath string) (string, string) {
+	parts := platformSeparators.Split(relativePath, -1)
+	for i := 0; i+1 < len(parts); i++ {
+		if platforms.IsOs(parts[i]) && platforms.IsArch(parts[i+1]) {
+			return parts[i], parts[i+1]
+		}
+	}
+	return "", ""
+}
+
+func expandHome(p string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[2:])
+		}
+	}
+	return p
+}
+
+// The types of the host's keys in the known_hosts file, so that the server offers one of those (rather than another key, which would fail verification).
+// nil (the defaults) when there are none, e.g. for hosts matched by wildcards
+func knownHostKeyAlgorithms(knownHosts, address string) ([]string, error) {
+	data, err := ioutil.ReadFile(knownHosts)
+	if err != nil {
+		return nil, err
+	}
+	host := knownhosts.Normalize(address)
+	var algorithms []string
+	for len(data) > 0 {
+		marker, hosts, key, _, rest, err := ssh.ParseKnownHosts(data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			//e.g. an unsupported key type. Skip a line, rather than every entry after it
+			next := bytes.IndexByte(data, '\n')
+			if next < 0 {
+				break
+			}
+			data = data[next+1:]
+			continue
+		}
+		data = rest
+		if marker != "" || !knownHostMatches(hosts, host) {
+			continue
+		}
+		switch key.Type() {
+		case ssh.KeyAlgoRSA:
+			//the same key signs with SHA-2 too
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algorithms = append(algorithms, key.Type())
+		}
+	}
+	return algorithms, nil
+}
+
+// Matches plain or hashed ('|1|salt|hash') host entries
+func knownHostMatches(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+		parts := strings.Split(h, "|")
+		if len(parts) != 4 || parts[0] != "" || parts[1] != "1" {
+			continue
+		}
+		salt, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			continue
+		}
+		hash, err := base64.StdEncoding.DecodeString(parts[3])
+		if err != nil {
+			continue
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(host))
+		if hmac.Equal(mac.Sum(nil), hash) {
+			return true
+		}
+	}
+	return false
+}
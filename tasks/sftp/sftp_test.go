@@ -0,0 +1,269 @@
+package sftp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/tasks"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// An in-process SSH server, with an 'sftp' subsystem serving a directory
+type fakeServer struct {
+	root        string
+	posixRename bool
+	listener    net.Listener
+	hostKey     ssh.Signer
+}
+
+func newFakeServer(t *testing.T, root string, clientKey ssh.PublicKey) *fakeServer {
+	_, hostPriv, _ := ed25519.GenerateKey(rand.Reader)
+	hostKey, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "deployer" && string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostKey)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{root: root, posixRename: true, listener: listener, hostKey: hostKey}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serveConn(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					go func() {
+						s.serveSFTP(channel)
+						channel.Close()
+					}()
+				}
+			}
+		}()
+	}
+}
+
+func (s *fakeServer) serveSFTP(channel io.ReadWriter) {
+	files := map[string]*os.File{}
+	for {
+		typ, data, err := readPacket(channel)
+		if err != nil {
+			return
+		}
+		if typ == fxpInit {
+			if s.posixRename {
+				channel.Write(marshal(fxpVersion, uint32(3), posixRename, "1"))
+			} else {
+				channel.Write(marshal(fxpVersion, uint32(3)))
+			}
+			continue
+		}
+		id := binary.BigEndian.Uint32(data)
+		first, rest := readString(data[4:])
+		local := filepath.Join(s.root, filepath.FromSlash(first))
+		status := func(err error) {
+			code := uint32(fxOK)
+			message := ""
+			if os.IsNotExist(err) {
+				code, message = fxNoSuchFile, err.Error()
+			} else if err != nil {
+				code, message = 4, err.Error()
+			}
+			channel.Write(marshal(fxpStatus, id, code, message, ""))
+		}
+		switch typ {
+		case fxpStat:
+			if _, err := os.Stat(local); err != nil {
+				status(err)
+			} else {
+				channel.Write(marshal(fxpAttrs, id, uint32(0)))
+			}
+		case fxpMkdir:
+			status(os.Mkdir(local, 0755))
+		case fxpOpen:
+			f, err := os.OpenFile(local, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+			if err != nil {
+				status(err)
+			} else {
+				handle := strconv.Itoa(len(files))
+				files[handle] = f
+				channel.Write(marshal(fxpHandle, id, handle))
+			}
+		case fxpWrite:
+			offset := binary.BigEndian.Uint64(rest)
+			chunk, _ := readString(rest[8:])
+			_, err := files[first].WriteAt([]byte(chunk), int64(offset))
+			status(err)
+		case fxpClose:
+			status(files[first].Close())
+		case fxpRemove:
+			status(os.Remove(local))
+		case fxpRename:
+			newPath, _ := readString(rest)
+			if _, err := os.Stat(filepath.Join(s.root, newPath)); err == nil {
+				//as OpenSSH does
+				channel.Write(marshal(fxpStatus, id, uint32(4), "exists", ""))
+				continue
+			}
+			status(os.Rename(local, filepath.Join(s.root, newPath)))
+		case fxpExtended:
+			oldPath, rest := readString(rest)
+			newPath, _ := readString(rest)
+			status(os.Rename(filepath.Join(s.root, oldPath), filepath.Join(s.root, newPath)))
+		default:
+			channel.Write(marshal(fxpStatus, id, uint32(8), "unsupported", ""))
+		}
+	}
+}
+
+func TestRunTaskPubSFTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goxc-sftp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	remoteRoot := filepath.Join(dir, "remote")
+	os.MkdirAll(remoteRoot, 0755)
+	clientPub, clientPriv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ed25519")
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(block), 0600)
+	sshClientPub, _ := ssh.NewPublicKey(clientPub)
+	server := newFakeServer(t, remoteRoot, sshClientPub)
+	defer server.listener.Close()
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	knownHosts := filepath.Join(dir, "known_hosts")
+	ioutil.WriteFile(knownHosts, []byte(knownhosts.Line([]string{knownhosts.Normalize(server.listener.Addr().String())}, server.hostKey.PublicKey())+"\n"), 0644)
+
+	versionDir := filepath.Join(dir, "1.0")
+	os.MkdirAll(filepath.Join(versionDir, "linux_amd64"), 0755)
+	ioutil.WriteFile(filepath.Join(versionDir, "linux_amd64", "app_1.0_linux_amd64.tar.gz"), []byte("tgz"), 0644)
+	ioutil.WriteFile(filepath.Join(versionDir, "app_1.0_windows_386.zip"), []byte("zip"), 0644)
+	os.Unsetenv("SSH_AUTH_SOCK")
+
+	s := config.Settings{AppName: "app", PackageVersion: "1.0", Verbosity: core.VerbosityQuiet}
+	config.FillSettingsDefaults(&s, dir)
+	tasks.FillTaskSettingsDefaults(&s)
+	portNumber, _ := strconv.Atoi(port)
+	for k, v := range map[string]interface{}{"host": host, "port": portNumber, "user": "deployer", "key-file": keyFile, "known-hosts": knownHosts, "dir": "/srv/{{.AppName}}/{{.Version}}/{{.Os}}-{{.Arch}}"} {
+		s.TaskSettings[tasks.TASK_PUBLISH_SFTP][k] = v
+	}
+	tp := tasks.TaskParams{AppName: "app", WorkingDirectory: dir, OutDestRoot: dir, Settings: &s}
+	err = RunTaskPubSFTP(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]string{"srv/app/1.0/linux-amd64/app_1.0_linux_amd64.tar.gz": "tgz", "srv/app/1.0/windows-386/app_1.0_windows_386.zip": "zip"} {
+		data, err := ioutil.ReadFile(filepath.Join(remoteRoot, name))
+		if err != nil || string(data) != expected {
+			t.Errorf("unexpected %s: '%s' (%v)", name, data, err)
+		}
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(remoteRoot, "srv/app/1.0/*/.*")); len(tmps) > 0 {
+		t.Errorf("temporary files left behind: %v", tmps)
+	}
+	//existing files
+	err = RunTaskPubSFTP(tp)
+	if err == nil {
+		t.Errorf("expected an error for existing files")
+	}
+	//replaced, without posix-rename
+	server.posixRename = false
+	ioutil.WriteFile(filepath.Join(versionDir, "app_1.0_windows_386.zip"), []byte("new zip"), 0644)
+	s.TaskSettings[tasks.TASK_PUBLISH_SFTP]["exists-action"] = "replace"
+	err = RunTaskPubSFTP(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(remoteRoot, "srv/app/1.0/windows-386/app_1.0_windows_386.zip"))
+	if string(data) != "new zip" {
+		t.Errorf("expected a replaced file, got '%s'", data)
+	}
+	//an unknown host
+	ioutil.WriteFile(knownHosts, []byte(knownhosts.Line([]string{knownhosts.Normalize(server.listener.Addr().String())}, sshClientPub)+"\n"), 0644)
+	err = RunTaskPubSFTP(tp)
+	if err == nil {
+		t.Errorf("expected a host key error")
+	}
+}
+
+func TestKnownHostKeyAlgorithms(t *testing.T) {
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	edKey, _ := ssh.NewPublicKey(edPub)
+	rsaPriv, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaKey, _ := ssh.NewPublicKey(&rsaPriv.PublicKey)
+	dir, err := ioutil.TempDir("", "goxc-known-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	knownHosts := filepath.Join(dir, "known_hosts")
+	lines := "# comment\n" +
+		"@revoked garbage\n" +
+		"bad.example.com ssh-unknown AAAA\n" +
+		knownhosts.Line([]string{"example.com"}, edKey) + "\n" +
+		knownhosts.Line([]string{knownhosts.HashHostname("[example.com]:2222")}, rsaKey) + "\n" +
+		knownhosts.Line([]string{"other.example.com"}, rsaKey) + "\n"
+	ioutil.WriteFile(knownHosts, []byte(lines), 0644)
+	for address, expected := range map[string]string{
+		"example.com:22":   "[ssh-ed25519]",
+		"example.com:2222": "[rsa-sha2-512 rsa-sha2-256 ssh-rsa]",
+		"unknown.com:22":   "[]",
+	} {
+		algorithms, err := knownHostKeyAlgorithms(knownHosts, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(algorithms) != expected {
+			t.Errorf("%s: expected %s, got %v", address, expected, algorithms)
+		}
+	}
+}
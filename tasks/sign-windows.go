@@ -0,0 +1,120 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/exefileparse"
+	"github.com/laher/goxc/packaging/authenticode"
+	"github.com/laher/goxc/packaging/cms"
+	"github.com/laher/goxc/platforms"
+)
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_SIGN_WINDOWS,
+		"Signs Windows binaries with Authenticode, using the certificate in 'p12'. Runs on any host. Set 'timestamp-url' to an RFC 3161 Time Stamping Authority to timestamp the signature. Only runs when a 'p12' is configured.",
+		runTaskSignWindows,
+		map[string]interface{}{
+			"p12":           "",
+			"p12-password":  "",
+			"timestamp-url": "",
+			"description":   "",
+			"url":           ""}})
+}
+
+func runTaskSignWindows(tp TaskParams) error {
+	p12 := tp.Settings.GetTaskSettingString(TASK_SIGN_WINDOWS, "p12")
+	if p12 == "" {
+		if tp.Settings.IsVerbose() {
+			log.Printf("No p12 configured. Not signing Windows binaries")
+		}
+		return nil
+	}
+	if !filepath.IsAbs(p12) {
+		p12 = filepath.Join(tp.WorkingDirectory, p12)
+	}
+	signer, err := cms.LoadPKCS12(p12, tp.Settings.GetTaskSettingString(TASK_SIGN_WINDOWS, "p12-password"))
+	if err != nil {
+		return err
+	}
+	signer.SigningTime = executils.GetBuildDate()
+	if tsaURL := tp.Settings.GetTaskSettingString(TASK_SIGN_WINDOWS, "timestamp-url"); tsaURL != "" {
+		signer.Timestamper = cms.RFC3161Timestamper(tsaURL, cms.OIDRFC3161Countersignature)
+	}
+	opus := authenticode.OpusInfo{
+		Description: tp.Settings.GetTaskSettingString(TASK_SIGN_WINDOWS, "description"),
+		URL:         tp.Settings.GetTaskSettingString(TASK_SIGN_WINDOWS, "url")}
+	for _, dest := range tp.DestPlatforms {
+		if dest.Os != platforms.WINDOWS {
+			continue
+		}
+		for _, mainDir := range tp.MainDirs {
+			var exeName string
+			if len(tp.MainDirs) == 1 {
+				exeName = tp.Settings.AppName
+			} else {
+				exeName = filepath.Base(mainDir)
+			}
+			binPath, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
+			if err != nil {
+				return err
+			}
+			err = signWindowsBinary(binPath, dest, signer, opus, tp)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Signs the binary in place, then checks that it's still a valid PE file and verifies the signature
+func signWindowsBinary(binPath string, dest platforms.Platform, signer *cms.Signer, opus authenticode.OpusInfo, tp TaskParams) error {
+	data, err := ioutil.ReadFile(binPath)
+	if err != nil {
+		return err
+	}
+	signed, err := authenticode.Sign(data, signer, opus)
+	if err != nil {
+		log.Printf("Authenticode signing failed for %s: %s", binPath, err)
+		return err
+	}
+	err = ioutil.WriteFile(binPath, signed, 0755)
+	if err != nil {
+		return err
+	}
+	err = exefileparse.TestPE(binPath, dest.Arch, dest.Os, tp.Settings.IsVerbose())
+	if err != nil {
+		return err
+	}
+	err = exefileparse.VerifyAuthenticode(binPath, tp.Settings.IsVerbose())
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Signed %s as %q", binPath, signer.Certificate.Subject.CommonName)
+	}
+	return nil
+}
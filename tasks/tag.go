@@ -22,10 +22,11 @@ import (
 
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/core"
 	"github.com/laher/goxc/executils"
 )
 
-const TASK_TAG = "tag"
+const TASK_TAG = core.TASK_TAG
 
 //runs automatically
 func init() {
@@ -21,8 +21,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
@@ -36,6 +40,7 @@ const (
 	TASK_BUILD_TOOLCHAIN = core.TASK_BUILD_TOOLCHAIN
 
 	TASK_CLEAN_DESTINATION = "clean-destination"
+	TASK_PRUNE_DESTINATION = "prune-destination"
 	TASK_GO_CLEAN          = "go-clean"
 
 	TASK_GO_VET  = "go-vet"
@@ -47,15 +52,32 @@ const (
 	TASK_CODESIGN    = "codesign"
 	TASK_RICE_APPEND = "rice-append"
 
-	TASK_COPY_RESOURCES = "copy-resources"
-	TASK_ARCHIVE_ZIP    = "archive-zip"
-	TASK_ARCHIVE_TAR_GZ = "archive-tar-gz"
-	TASK_REMOVE_BIN     = "rmbin" //after zipping
-	TASK_DOWNLOADS_PAGE = "downloads-page"
-	TASK_DEB_GEN        = "deb"
-	TASK_DEB_DEV        = "deb-dev"
-	TASK_DEB_SOURCE     = "deb-source"
-	TASK_PUBLISH_GITHUB = "publish-github"
+	TASK_VERIFY_REPRODUCIBLE = "verify-reproducible"
+	TASK_WINDOWS_RESOURCES   = "windows-resources"
+	TASK_DARWIN_APP          = "darwin-app"
+	TASK_SIGN_WINDOWS        = "sign-windows"
+
+	TASK_COPY_RESOURCES    = "copy-resources"
+	TASK_ARCHIVE_ZIP       = "archive-zip"
+	TASK_ARCHIVE_TAR_GZ    = "archive-tar-gz"
+	TASK_REMOVE_BIN        = "rmbin" //after zipping
+	TASK_DOWNLOADS_PAGE    = "downloads-page"
+	TASK_DEB_GEN           = "deb"
+	TASK_DEB_DEV           = "deb-dev"
+	TASK_DEB_SOURCE        = "deb-source"
+	TASK_DARWIN_PKG        = "darwin-pkg"
+	TASK_WINDOWS_INSTALLER = "windows-installer"
+	TASK_PUBLISH_GITHUB    = "publish-github"
+	TASK_PUBLISH_GITLAB    = "publish-gitlab"
+	TASK_PUBLISH_GITEA     = "publish-gitea"
+	TASK_PUBLISH_SFTP      = "publish-sftp"
+	TASK_RELEASE_NOTES     = "release-notes"
+	TASK_CHANGELOG         = "changelog"
+	TASK_HOMEBREW          = "homebrew"
+	TASK_WINDOWS_MANIFESTS = "windows-manifests"
+	TASK_CHOCOLATEY        = "chocolatey"
+	TASK_OCI_IMAGE         = "oci-image"
+	TASK_PUBLISH_OCI       = "publish-oci"
 
 	TASKALIAS_ALL        = "all"
 	TASKALIAS_ARCHIVE    = "archive"
@@ -72,18 +94,26 @@ const (
 var (
 	TASKS_ARCHIVE                     = []string{TASK_ARCHIVE_ZIP, TASK_ARCHIVE_TAR_GZ}
 	TASKS_CLEAN                       = []string{TASK_GO_CLEAN, TASK_CLEAN_DESTINATION}
-	TASKS_COMPILE                     = []string{TASK_GO_INSTALL, TASK_XC, TASK_CODESIGN, TASK_COPY_RESOURCES}
+	TASKS_COMPILE                     = []string{TASK_GO_INSTALL, TASK_WINDOWS_RESOURCES, TASK_XC, TASK_CODESIGN, TASK_SIGN_WINDOWS, TASK_DARWIN_APP, TASK_COPY_RESOURCES}
 	TASKS_DEBS                        = []string{TASK_DEB_GEN, TASK_DEB_DEV, TASK_DEB_SOURCE}
-	TASKS_PACKAGE                     = []string{TASK_ARCHIVE_ZIP, TASK_ARCHIVE_TAR_GZ, TASK_DEB_GEN, TASK_DEB_DEV, TASK_REMOVE_BIN, TASK_DOWNLOADS_PAGE}
+	TASKS_PACKAGE                     = []string{TASK_ARCHIVE_ZIP, TASK_ARCHIVE_TAR_GZ, TASK_DEB_GEN, TASK_DEB_DEV, TASK_DARWIN_PKG, TASK_WINDOWS_INSTALLER, TASK_OCI_IMAGE, TASK_REMOVE_BIN, TASK_DOWNLOADS_PAGE}
 	TASKS_PKG_BUILD                   = []string{TASK_DEB_GEN, TASK_DEB_DEV}
 	TASKS_PKG_SOURCE                  = []string{TASK_DEB_SOURCE}
 	TASKS_VALIDATE                    = []string{TASK_GO_VET, TASK_GO_TEST}
 	TASKS_DEFAULT                     = append(append(append([]string{}, TASKS_VALIDATE...), TASKS_COMPILE...), TASKS_PACKAGE...)
-	TASKS_OTHER                       = []string{TASK_BUILD_TOOLCHAIN, TASK_GO_FMT, TASK_RICE_APPEND, TASK_PUBLISH_GITHUB}
+	TASKS_OTHER                       = []string{TASK_BUILD_TOOLCHAIN, TASK_GO_FMT, TASK_RICE_APPEND, TASK_RELEASE_NOTES, TASK_CHANGELOG, TASK_PUBLISH_GITHUB, TASK_PUBLISH_GITLAB, TASK_PUBLISH_GITEA, TASK_PUBLISH_SFTP, TASK_PUBLISH_DIR, TASK_PRUNE_DESTINATION, TASK_PUBLISH_OCI, TASK_HOMEBREW, TASK_WINDOWS_MANIFESTS, TASK_CHOCOLATEY}
 	TASKS_ALL                         = append(append([]string{}, TASKS_OTHER...), TASKS_DEFAULT...)
 	TASK_ALIASES_FOR_MERGING_SETTINGS = map[string][]string{TASKALIAS_PKG_BUILD: TASKS_PKG_BUILD, TASKALIAS_PKG_SOURCE: TASKS_PKG_SOURCE, TASKALIAS_DEBS: TASKS_DEBS}
 
+	//tasks producing artifacts, which run once per build variant
+	TASKS_PER_VARIANT = []string{TASK_XC, TASK_CODESIGN, TASK_SIGN_WINDOWS, TASK_DARWIN_APP, TASK_RICE_APPEND, TASK_COPY_RESOURCES, TASK_ARCHIVE_ZIP, TASK_ARCHIVE_TAR_GZ, TASK_DEB_GEN, TASK_DARWIN_PKG, TASK_WINDOWS_INSTALLER, TASK_OCI_IMAGE, TASK_REMOVE_BIN, TASK_VERIFY_REPRODUCIBLE}
+	//tasks which run once per Go toolchain (when GoToolchains are configured)
+	TASKS_PER_GO_TOOLCHAIN = append([]string{TASK_GO_TEST}, TASKS_PER_VARIANT...)
+
 	allTasks = make(map[string]Task)
+	//run at the end of RunTasks
+	cleanups      = []func() error{}
+	cleanupsMutex sync.Mutex
 	//Aliases are one or more tasks, in a specific order.
 	Aliases = map[string][]string{
 		TASKALIAS_ALL:        TASKS_ALL,
@@ -107,6 +137,8 @@ type TaskParams struct {
 	WorkingDirectory, OutDestRoot string
 	Settings                      *config.Settings
 	MaxProcessors                 int
+	//Go modules: main packages with their import paths (empty for GOPATH-style projects)
+	MainPackages []source.MainPackage
 }
 
 // A task is basically a user-defined function given a unique name, plus some 'default settings'
@@ -131,6 +163,44 @@ func Register(task Task) {
 	allTasks[task.Name] = task
 }
 
+// Register a function to run once all tasks have completed (or failed). e.g. to remove generated files
+func registerCleanup(cleanup func() error) {
+	cleanupsMutex.Lock()
+	defer cleanupsMutex.Unlock()
+	cleanups = append(cleanups, cleanup)
+}
+
+func runCleanups() {
+	cleanupsMutex.Lock()
+	defer cleanupsMutex.Unlock()
+	for _, cleanup := range cleanups {
+		if err := cleanup(); err != nil {
+			log.Printf("Cleanup failed: %v", err)
+		}
+	}
+	cleanups = nil
+}
+
+// Runs the cleanups if goxc is interrupted (e.g. Ctrl-C) before the tasks complete. Call the returned func to stop
+func cleanupOnInterrupt() func() {
+	interrupts := make(chan os.Signal, 1)
+	done := make(chan bool)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-interrupts:
+			log.Printf("Received %v. Cleaning up", sig)
+			runCleanups()
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(interrupts)
+		close(done)
+	}
+}
+
 func generateParallelizedRunFunc(pTask ParallelizableTask) func(TaskParams) error {
 	fn := func(tp TaskParams) error {
 		platforms, err := pTask.setUp(tp)
@@ -230,6 +300,17 @@ func ListTasks() []Task {
 	return tasks
 }
 
+// Returns the directory to invoke 'go' from, and the package to build, for a given main dir.
+// For Go modules this is the module root plus the package's import path. Otherwise, the main dir itself and '.'
+func (tp TaskParams) GoBuildTarget(mainDir string) (string, string) {
+	for _, mainPackage := range tp.MainPackages {
+		if mainPackage.Dir == mainDir {
+			return mainPackage.Module.Dir, mainPackage.ImportPath
+		}
+	}
+	return mainDir, "."
+}
+
 // run all given tasks
 func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, settings *config.Settings, maxProcessors int) error {
 	if settings.IsVerbose() {
@@ -243,6 +324,8 @@ func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, setti
 		return err
 	}
 	defer log.SetPrefix("[goxc] ")
+	defer runCleanups()
+	defer cleanupOnInterrupt()()
 	exclusions := ResolveAliases(settings.TasksExclude)
 	appends := ResolveAliases(settings.TasksAppend)
 	mains := ResolveAliases(settings.Tasks)
@@ -258,6 +341,9 @@ func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, setti
 			tasksToRun = append(tasksToRun, taskName)
 		}
 	}
+	if core.ContainsString(tasksToRun, TASK_VERIFY_REPRODUCIBLE) {
+		enableReproducibleXc(settings)
+	}
 	//0.6 check all tasks are valid before continuing
 	for _, taskName := range tasksToRun {
 		if _, keyExists := allTasks[taskName]; !keyExists {
@@ -275,6 +361,7 @@ func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, setti
 	}
 	mainDirs := []string{}
 	allPackages := []string{}
+	mainPackages := []source.MainPackage{}
 	if len(tasksToRun) == 1 && tasksToRun[0] == "toolchain" {
 		log.Printf("Toolchain task only - not searching for main dirs")
 		//mainDirs = []string{workingDirectory}
@@ -288,7 +375,24 @@ func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, setti
 			log.Printf("Warning: could not establish list of source packages. Using working directory")
 			allPackages = []string{workingDirectory}
 		}
-		mainDirs, err = source.FindMainDirs(workingDirectory, excludes, settings.IsVerbose())
+		var modules []source.Module
+		modules, err = source.FindModules(workingDirectory, settings.GoRoot)
+		if err != nil {
+			log.Printf("Warning: could not read Go module info: %v", err)
+		}
+		if len(modules) > 0 {
+			if settings.IsVerbose() {
+				log.Printf("Using Go modules: %+v", modules)
+			}
+			includesNested := core.ParseCommaGlobs(settings.NestedModulesInclude)
+			mainPackages, err = source.FindMainPackages(workingDirectory, settings.GoRoot, modules, excludes, includesNested, settings.IsVerbose())
+			mainDirs = []string{}
+			for _, mainPackage := range mainPackages {
+				mainDirs = append(mainDirs, mainPackage.Dir)
+			}
+		} else {
+			mainDirs, err = source.FindMainDirs(workingDirectory, excludes, settings.IsVerbose())
+		}
 		if err != nil || len(mainDirs) == 0 {
 			log.Printf("Warning: could not find any main dirs: %v", err)
 		} else {
@@ -301,29 +405,55 @@ func RunTasks(workingDirectory string, destPlatforms []platforms.Platform, setti
 		log.Printf("Running tasks: %v", tasksToRun)
 		log.Printf("All packages: %v", allPackages)
 	}
+	toolchainSettings, err := resolveGoToolchains(settings)
+	if err != nil {
+		return err
+	}
+	results := []matrixResult{}
 	for _, taskName := range tasksToRun {
-		log.SetPrefix("[goxc:" + taskName + "] ")
-		if settings.IsVerbose() {
-			log.Printf("Running task %s with settings: %v", taskName, settings.TaskSettings[taskName])
+		errs := []error{}
+		for _, s := range matrixSettings(taskName, settings, toolchainSettings) {
+			label := strings.Join(s.GetMatrixVars().Parts(), ":")
+			if label != "" {
+				log.SetPrefix("[goxc:" + taskName + ":" + label + "] ")
+			} else {
+				log.SetPrefix("[goxc:" + taskName + "] ")
+			}
+			if settings.IsVerbose() {
+				log.Printf("Running task %s with settings: %v", taskName, settings.TaskSettings[taskName])
+			}
+			err := runTask(taskName, destPlatforms, allPackages, mainDirs, mainPackages, appName, workingDirectory, outDestRoot, s, maxProcessors)
+			if s.GoVersion != "" {
+				results = append(results, matrixResult{s.GoVersion, taskName, s.Variant, err})
+			}
+			if err != nil {
+				errs = append(errs, err)
+				if s.GoVersion == "" {
+					break
+				}
+				//carry on with other toolchains, to report results per version
+				log.Printf("Task '%s' failed with error '%v'", taskName, err)
+			} else {
+				if !settings.IsQuiet() {
+					log.Printf("Task %s succeeded", taskName)
+				}
+			}
 		}
-		err := runTask(taskName, destPlatforms, allPackages, mainDirs, appName, workingDirectory, outDestRoot, settings, maxProcessors)
-		if err != nil {
+		if len(errs) > 0 {
 			// TODO: implement 'force' option.
-			log.Printf("Stopping after '%s' failed with error '%v'", taskName, err)
-			return err
-		} else {
-			if !settings.IsQuiet() {
-				log.Printf("Task %s succeeded", taskName)
-			}
+			log.Printf("Stopping after '%s' failed with error '%v'", taskName, errs[0])
+			reportMatrixResults(results)
+			return errs[0]
 		}
 	}
+	reportMatrixResults(results)
 	return nil
 }
 
 // run named task
-func runTask(taskName string, destPlatforms []platforms.Platform, mainDirs []string, allPackages []string, appName, workingDirectory, outDestRoot string, settings *config.Settings, maxProcessors int) error {
+func runTask(taskName string, destPlatforms []platforms.Platform, mainDirs []string, allPackages []string, mainPackages []source.MainPackage, appName, workingDirectory, outDestRoot string, settings *config.Settings, maxProcessors int) error {
 	if taskV, keyExists := allTasks[taskName]; keyExists {
-		tp := TaskParams{destPlatforms, mainDirs, allPackages, appName, workingDirectory, outDestRoot, settings, maxProcessors}
+		tp := TaskParams{destPlatforms, mainDirs, allPackages, appName, workingDirectory, outDestRoot, settings, maxProcessors, mainPackages}
 		return taskV.Run(tp)
 	}
 	log.Printf("Unrecognised task '%s'", taskName)
@@ -0,0 +1,208 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/config"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/exefileparse"
+	"github.com/laher/goxc/platforms"
+)
+
+//runs automatically
+func init() {
+	RegisterParallelizable(ParallelizableTask{
+		TASK_VERIFY_REPRODUCIBLE,
+		"Rebuilds each binary in an isolated GOPATH/GOCACHE, and checks it's identical to the output of 'xc' (before any signing). 'xc' builds with -trimpath -buildvcs=false whenever this task runs.",
+		setupVerifyReproducible,
+		runVerifyReproducible,
+		nil,
+		map[string]interface{}{"keepTemp": false}})
+}
+
+func setupVerifyReproducible(tp TaskParams) ([]platforms.Platform, error) {
+	if len(tp.DestPlatforms) == 0 {
+		return []platforms.Platform{}, errors.New("No valid platforms specified")
+	}
+	if len(tp.MainPackages) == 0 {
+		log.Printf("WARNING: GOPATH-style project. Only GOCACHE will be isolated during the rebuild")
+	}
+	return tp.DestPlatforms, nil
+}
+
+func runVerifyReproducible(tp TaskParams, dest platforms.Platform, errchan chan error) {
+	for _, mainDir := range tp.MainDirs {
+		var exeName string
+		if len(tp.MainDirs) == 1 {
+			exeName = tp.Settings.AppName
+		} else {
+			exeName = filepath.Base(mainDir)
+		}
+		err := verifyReproduciblePlat(dest, tp, exeName, mainDir)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			errchan <- err
+			return
+		}
+	}
+	errchan <- nil
+}
+
+func verifyReproduciblePlat(dest platforms.Platform, tp TaskParams, exeName, mainDir string) error {
+	absoluteBin, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
+	if err != nil {
+		return err
+	}
+	if exists, err := core.FileExists(absoluteBin); !exists {
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("Binary %s does not exist. Run the 'xc' task first", absoluteBin)
+	}
+	tmpDir, err := ioutil.TempDir("", "goxc-verify-"+dest.Os+"_"+dest.Arch)
+	if err != nil {
+		return err
+	}
+	if tp.Settings.GetTaskSettingBool(TASK_VERIFY_REPRODUCIBLE, "keepTemp") {
+		log.Printf("Rebuilding %s into %s", exeName, tmpDir)
+	} else {
+		defer os.RemoveAll(tmpDir)
+	}
+	rebuiltBin := filepath.Join(tmpDir, "bin", filepath.Base(absoluteBin))
+	envExtra := append(xcEnv(dest, tp), "GOCACHE="+filepath.Join(tmpDir, "gocache"))
+	args := xcBuildArgs(tp)
+	if len(tp.MainPackages) > 0 {
+		//only isolate GOPATH for modules - GOPATH-style projects need their real GOPATH to build at all
+		envExtra = append(envExtra, "GOPATH="+filepath.Join(tmpDir, "gopath"))
+		//module cache is read-only by default, preventing cleanup
+		args = append(args, "-modcacherw")
+	}
+	goDir, target := tp.GoBuildTarget(mainDir)
+	args = append(args, "-o", rebuiltBin, target)
+	err = executils.InvokeGo(goDir, "build", args, envExtra, tp.Settings)
+	if err != nil {
+		return err
+	}
+	builtBin := absoluteBin
+	if unsigned, err := unsignedPath(absoluteBin, false); err == nil {
+		if exists, _ := core.FileExists(unsigned); exists {
+			builtBin = unsigned
+		}
+	}
+	same, err := exefileparse.FilesEqual(builtBin, rebuiltBin)
+	if err != nil {
+		return err
+	}
+	if same {
+		if !tp.Settings.IsQuiet() {
+			log.Printf("%s is reproducible", absoluteBin)
+		}
+		return nil
+	}
+	diffs, err := exefileparse.DiffSections(builtBin, rebuiltBin, dest.Os)
+	if err != nil {
+		log.Printf("Could not compare sections: %v", err)
+		return fmt.Errorf("%s is NOT reproducible", absoluteBin)
+	}
+	log.Printf("Sections differing for %s: %s", absoluteBin, strings.Join(diffs, ", "))
+	log.Printf("Note: a build date needs SOURCE_DATE_EPOCH to be reproducible")
+	return fmt.Errorf("%s is NOT reproducible (%d differing sections)", absoluteBin, len(diffs))
+}
+
+// Makes 'xc' build as the rebuild does, when this task is going to run
+func enableReproducibleXc(settings *config.Settings) {
+	if settings.GetTaskSettingBool(TASK_XC, "reproducible") {
+		return
+	}
+	//don't modify the registered defaults
+	xcSettings := map[string]interface{}{}
+	for k, v := range settings.TaskSettings[TASK_XC] {
+		xcSettings[k] = v
+	}
+	xcSettings["reproducible"] = true
+	settings.TaskSettings[TASK_XC] = xcSettings
+}
+
+var (
+	unsignedDir      string
+	unsignedDirMutex sync.Mutex
+)
+
+// Where a copy of the binary is kept before signing. A temporary directory, removed once all tasks are complete
+func unsignedPath(absoluteBin string, create bool) (string, error) {
+	dir, created, err := getUnsignedDir(create)
+	if err != nil {
+		return "", err
+	}
+	if created {
+		//registered without holding unsignedDirMutex, because the cleanup takes it (while the cleanups are locked)
+		registerCleanup(func() error {
+			unsignedDirMutex.Lock()
+			defer unsignedDirMutex.Unlock()
+			dir := unsignedDir
+			unsignedDir = ""
+			return os.RemoveAll(dir)
+		})
+	}
+	sum := sha256.Sum256([]byte(absoluteBin))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8]), filepath.Base(absoluteBin)), nil
+}
+
+// The temporary directory, and whether this call created it
+func getUnsignedDir(create bool) (string, bool, error) {
+	unsignedDirMutex.Lock()
+	defer unsignedDirMutex.Unlock()
+	if unsignedDir != "" {
+		return unsignedDir, false, nil
+	}
+	if !create {
+		return "", false, os.ErrNotExist
+	}
+	dir, err := ioutil.TempDir("", "goxc-unsigned")
+	if err != nil {
+		return "", false, err
+	}
+	unsignedDir = dir
+	return dir, true, nil
+}
+
+func snapshotUnsigned(absoluteBin string, isVerbose bool) error {
+	unsigned, err := unsignedPath(absoluteBin, true)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(unsigned), 0755)
+	if err != nil {
+		return err
+	}
+	_, err = copyFile(absoluteBin, unsigned, isVerbose)
+	return err
+}
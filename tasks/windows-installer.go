@@ -0,0 +1,164 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/archive"
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/executils"
+	"github.com/laher/goxc/packaging/wininstaller"
+	"github.com/laher/goxc/platforms"
+	"github.com/laher/goxc/typeutils"
+)
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_WINDOWS_INSTALLER,
+		"Generates a Windows installer source for each Windows arch, containing the binaries and resources: WiX '.wxs' (format 'wix') or NSIS '.nsi' (format 'nsis'). Compiles it into an '.msi' with 'wixl', or a setup '.exe' with 'makensis', when available. 'upgrade-code' defaults to a GUID derived from the app name. Only runs when a 'manufacturer' is configured in the 'metadata'.",
+		runTaskWindowsInstaller,
+		map[string]interface{}{
+			"format":       "wix",
+			"metadata":     map[string]interface{}{"manufacturer": "", "description": ""},
+			"upgrade-code": "",
+			"add-to-path":  true,
+			"shortcut":     true,
+			"compile":      true}})
+}
+
+func runTaskWindowsInstaller(tp TaskParams) error {
+	format := tp.Settings.GetTaskSettingString(TASK_WINDOWS_INSTALLER, "format")
+	if format != "wix" && format != "nsis" {
+		return fmt.Errorf("Unsupported windows-installer format '%s'. Use 'wix' or 'nsis'", format)
+	}
+	metadata := map[string]string{}
+	for key, value := range tp.Settings.GetTaskSettingMap(TASK_WINDOWS_INSTALLER, "metadata") {
+		s, err := typeutils.ToString(value, TASK_WINDOWS_INSTALLER+".metadata."+key)
+		if err != nil {
+			return err
+		}
+		metadata[key] = s
+	}
+	if metadata["manufacturer"] == "" {
+		if tp.Settings.IsVerbose() {
+			log.Printf("No manufacturer configured. Not building Windows installers")
+		}
+		return nil
+	}
+	resources := core.ParseIncludeResources(tp.WorkingDirectory, tp.Settings.ResourcesInclude, tp.Settings.ResourcesExclude, tp.Settings.IsVerbose())
+	for _, dest := range tp.DestPlatforms {
+		if dest.Os != platforms.WINDOWS {
+			continue
+		}
+		if !wininstaller.IsSupportedArch(dest.Arch) {
+			log.Printf("Windows installers are not supported for %s. Skipping", dest.Arch)
+			continue
+		}
+		installer := wininstaller.Installer{
+			Name:         tp.Settings.AppName,
+			Version:      tp.Settings.GetFullVersionName(),
+			Manufacturer: metadata["manufacturer"],
+			Description:  metadata["description"],
+			Arch:         dest.Arch,
+			UpgradeCode:  tp.Settings.GetTaskSettingString(TASK_WINDOWS_INSTALLER, "upgrade-code"),
+			AddToPath:    tp.Settings.GetTaskSettingBool(TASK_WINDOWS_INSTALLER, "add-to-path")}
+		for _, mainDir := range tp.MainDirs {
+			var exeName string
+			if len(tp.MainDirs) == 1 {
+				exeName = tp.Settings.AppName
+			} else {
+				exeName = filepath.Base(mainDir)
+			}
+			binPath, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
+			if err != nil {
+				return err
+			}
+			//(the binary's file name depends on OutPath)
+			installer.Files = append(installer.Files, wininstaller.File{Source: binPath, Target: exeName + ".exe"})
+			//the shortcut points at the first binary
+			if installer.Shortcut == "" && tp.Settings.GetTaskSettingBool(TASK_WINDOWS_INSTALLER, "shortcut") {
+				installer.Shortcut = exeName + ".exe"
+			}
+		}
+		for _, resource := range resources {
+			installer.Files = append(installer.Files, wininstaller.File{Source: filepath.Join(tp.WorkingDirectory, resource), Target: filepath.ToSlash(resource)})
+		}
+		err := windowsInstallerPlat(dest, installer, format, tp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func windowsInstallerPlat(dest platforms.Platform, installer wininstaller.Installer, format string, tp TaskParams) error {
+	outDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	err := os.MkdirAll(outDir, 0777)
+	if err != nil {
+		return err
+	}
+	name := archive.ArtifactName(tp.Settings.AppName, dest.Os+"_"+dest.Arch, *tp.Settings)
+	var source []byte
+	var sourceName, tool string
+	var args []string
+	if format == "nsis" {
+		sourceName = name + ".nsi"
+		source, err = installer.Nsi(name + "-setup.exe")
+		tool = "makensis"
+		args = []string{"-V2", sourceName}
+	} else {
+		sourceName = name + ".wxs"
+		source, err = installer.Wxs()
+		tool = "wixl"
+		args = []string{"-a", installer.WixlArch(), "-o", name + ".msi", sourceName}
+	}
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(outDir, sourceName), source, 0644)
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Created installer source %s", filepath.Join(outDir, sourceName))
+	}
+	if !tp.Settings.GetTaskSettingBool(TASK_WINDOWS_INSTALLER, "compile") {
+		return nil
+	}
+	toolPath, err := exec.LookPath(tool)
+	if err != nil {
+		if !tp.Settings.IsQuiet() {
+			log.Printf("'%s' not found. Not compiling %s", tool, sourceName)
+		}
+		return nil
+	}
+	cmd := exec.Command(toolPath, args...)
+	cmd.Dir = outDir
+	if tp.Settings.IsVerbose() {
+		log.Printf("Running %s %v", tool, args)
+	}
+	return executils.StartAndWait(cmd)
+}
@@ -0,0 +1,153 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/packaging/winmanifest"
+	"github.com/laher/goxc/platforms"
+)
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_WINDOWS_MANIFESTS,
+		"Generates a Scoop manifest and a winget manifest set, with the url and sha256 of each Windows zip. 'url-template' should match the publisher's download URLs (the default matches 'publish-github'). winget requires 'publisher', 'license' and 'description'. Scoop's 'checkver' defaults to the github repository, if configured.",
+		runTaskWindowsManifests,
+		map[string]interface{}{
+			"scoop":              true,
+			"winget":             true,
+			"description":        "",
+			"homepage":           "",
+			"license":            "",
+			"publisher":          "",
+			"package-identifier": "",
+			"locale":             "en-US",
+			"url-template":       RELEASE_URL_TEMPLATE_GITHUB,
+			"checkver":           "",
+			"autoupdate":         true}})
+}
+
+func runTaskWindowsManifests(tp TaskParams) error {
+	urlTemplate, err := template.New("url-template").Parse(tp.Settings.GetTaskSettingString(TASK_WINDOWS_MANIFESTS, "url-template"))
+	if err != nil {
+		return err
+	}
+	manifest := winmanifest.Manifest{
+		Name:        tp.Settings.AppName,
+		Version:     tp.Settings.GetFullVersionName(),
+		Description: tp.Settings.GetTaskSettingString(TASK_WINDOWS_MANIFESTS, "description"),
+		Homepage:    tp.Settings.GetTaskSettingString(TASK_WINDOWS_MANIFESTS, "homepage"),
+		License:     tp.Settings.GetTaskSettingString(TASK_WINDOWS_MANIFESTS, "license")}
+	for _, mainDir := range tp.MainDirs {
+		if len(tp.MainDirs) == 1 {
+			manifest.Binaries = append(manifest.Binaries, tp.Settings.AppName+".exe")
+		} else {
+			manifest.Binaries = append(manifest.Binaries, filepath.Base(mainDir)+".exe")
+		}
+	}
+	urlVars := newReleaseURLVars(tp)
+	for _, dest := range tp.DestPlatforms {
+		if dest.Os != platforms.WINDOWS {
+			continue
+		}
+		a, err := findReleaseArchive(dest, []string{"zip"}, urlTemplate, urlVars, tp)
+		if err != nil {
+			return err
+		}
+		if a != nil {
+			manifest.Archives = append(manifest.Archives, winmanifest.Archive{Arch: dest.Arch, URL: a.URL, Sha256: a.Sha256, Dir: a.Dir})
+		}
+	}
+	versionDir := filepath.Join(tp.OutDestRoot, tp.Settings.GetFullVersionName())
+	if tp.Settings.GetTaskSettingBool(TASK_WINDOWS_MANIFESTS, "scoop") {
+		err = writeScoopManifest(manifest, versionDir, urlVars, tp)
+		if err != nil {
+			return err
+		}
+	}
+	if tp.Settings.GetTaskSettingBool(TASK_WINDOWS_MANIFESTS, "winget") {
+		err = writeWingetManifests(manifest, versionDir, tp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeScoopManifest(manifest winmanifest.Manifest, versionDir string, urlVars releaseURLVars, tp TaskParams) error {
+	update := winmanifest.ScoopUpdate{
+		Checkver:   tp.Settings.GetTaskSetting(TASK_WINDOWS_MANIFESTS, "checkver"),
+		Autoupdate: tp.Settings.GetTaskSettingBool(TASK_WINDOWS_MANIFESTS, "autoupdate")}
+	if update.Checkver == "" || update.Checkver == nil {
+		update.Checkver = nil
+		if urlVars.Owner != "" && urlVars.Repository != "" {
+			update.Checkver = map[string]string{"github": "https://github.com/" + urlVars.Owner + "/" + urlVars.Repository}
+		}
+	}
+	data, err := manifest.Scoop(update)
+	if err != nil {
+		return err
+	}
+	jsonPath := filepath.Join(versionDir, manifest.Name+".json")
+	err = ioutil.WriteFile(jsonPath, data, 0644)
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Created Scoop manifest %s", jsonPath)
+	}
+	return nil
+}
+
+func writeWingetManifests(manifest winmanifest.Manifest, versionDir string, tp TaskParams) error {
+	w := winmanifest.Winget{
+		PackageIdentifier: tp.Settings.GetTaskSettingString(TASK_WINDOWS_MANIFESTS, "package-identifier"),
+		Publisher:         tp.Settings.GetTaskSettingString(TASK_WINDOWS_MANIFESTS, "publisher"),
+		Locale:            tp.Settings.GetTaskSettingString(TASK_WINDOWS_MANIFESTS, "locale")}
+	if w.PackageIdentifier == "" && w.Publisher != "" {
+		w.PackageIdentifier = strings.Replace(w.Publisher, " ", "", -1) + "." + manifest.Name
+	}
+	files, err := manifest.Winget(w)
+	if err != nil {
+		return err
+	}
+	//laid out as in the winget-pkgs repository
+	wingetDir := filepath.Join(versionDir, "winget", filepath.FromSlash(w.Dir(manifest.Version)))
+	err = os.MkdirAll(wingetDir, 0777)
+	if err != nil {
+		return err
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(wingetDir, name), content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Created winget manifests in %s", wingetDir)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package tasks
+
+/*
+   Copyright 2013 Am Laher
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"go/build"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
+	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
+	"github.com/laher/goxc/core"
+	"github.com/laher/goxc/packaging/winres"
+	"github.com/laher/goxc/platforms"
+	"github.com/laher/goxc/typeutils"
+)
+
+// generated files are named with this prefix
+const SYSO_PREFIX = "goxc_rsrc_"
+
+//runs automatically
+func init() {
+	Register(Task{
+		TASK_WINDOWS_RESOURCES,
+		"Generates a .syso for Windows builds, containing version info, an icon and a manifest. Only runs when an 'icon', the 'manifest' or some 'metadata' is configured. Removed once all tasks are complete (or goxc is interrupted).",
+		runTaskWindowsResources,
+		map[string]interface{}{
+			"metadata":        map[string]interface{}{"company": "", "description": "", "copyright": ""},
+			"icon":            "",
+			"manifest":        false,
+			"execution-level": "asInvoker",
+			"dpi-awareness":   ""}})
+}
+
+func runTaskWindowsResources(tp TaskParams) error {
+	for _, mainDir := range tp.MainDirs {
+		err := removeStaleSysos(mainDir)
+		if err != nil {
+			return err
+		}
+	}
+	if !isWindowsResourcesConfigured(tp) {
+		if tp.Settings.IsVerbose() {
+			log.Printf("No icon, manifest or metadata configured. Skipping")
+		}
+		return nil
+	}
+	archs := []string{}
+	for _, dest := range tp.DestPlatforms {
+		if dest.Os != platforms.WINDOWS || core.ContainsString(archs, dest.Arch) {
+			continue
+		}
+		if !winres.IsSupportedArch(dest.Arch) {
+			log.Printf("Windows resources are not supported for %s. Skipping", dest.Arch)
+			continue
+		}
+		archs = append(archs, dest.Arch)
+	}
+	if len(archs) == 0 {
+		return nil
+	}
+	for _, mainDir := range tp.MainDirs {
+		var exeName string
+		if len(tp.MainDirs) == 1 {
+			exeName = tp.Settings.AppName
+		} else {
+			exeName = filepath.Base(mainDir)
+		}
+		resources, err := buildWindowsResources(tp, exeName)
+		if err != nil {
+			return err
+		}
+		for _, arch := range archs {
+			err = writeSyso(tp, resources, mainDir, arch)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// The version info alone isn't worth writing into the user's source dir
+func isWindowsResourcesConfigured(tp TaskParams) bool {
+	if tp.Settings.GetTaskSettingString(TASK_WINDOWS_RESOURCES, "icon") != "" || tp.Settings.GetTaskSettingBool(TASK_WINDOWS_RESOURCES, "manifest") {
+		return true
+	}
+	for _, v := range tp.Settings.GetTaskSettingMap(TASK_WINDOWS_RESOURCES, "metadata") {
+		if s, ok := v.(string); !ok || s != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes .syso files left over from a crashed run, so they aren't linked into other builds
+func removeStaleSysos(mainDir string) error {
+	stale, err := filepath.Glob(filepath.Join(mainDir, SYSO_PREFIX+"*.syso"))
+	if err != nil {
+		return err
+	}
+	for _, f := range stale {
+		log.Printf("Removing %s, left over from an interrupted run", f)
+		err = os.Remove(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildWindowsResources(tp TaskParams, exeName string) (*winres.Resources, error) {
+	metadata := tp.Settings.GetTaskSettingMap(TASK_WINDOWS_RESOURCES, "metadata")
+	strs := map[string]string{
+		"FileVersion":      tp.Settings.GetFullVersionName(),
+		"ProductVersion":   tp.Settings.GetFullVersionName(),
+		"ProductName":      tp.Settings.AppName,
+		"InternalName":     exeName,
+		"OriginalFilename": exeName + ".exe",
+	}
+	for key, name := range map[string]string{"company": "CompanyName", "description": "FileDescription", "copyright": "LegalCopyright"} {
+		if v, keyExists := metadata[key]; keyExists {
+			s, err := typeutils.ToString(v, TASK_WINDOWS_RESOURCES+".metadata."+key)
+			if err != nil {
+				return nil, err
+			}
+			strs[name] = s
+		}
+	}
+	version := winres.ParseVersion(tp.Settings.PackageVersion)
+	resources := &winres.Resources{}
+	resources.Add(winres.RT_VERSION, 1, winres.LANG_EN_US, winres.VersionInfo{FileVersion: version, ProductVersion: version, Strings: strs}.Bytes())
+
+	icon := tp.Settings.GetTaskSettingString(TASK_WINDOWS_RESOURCES, "icon")
+	if icon != "" {
+		if !filepath.IsAbs(icon) {
+			icon = filepath.Join(tp.WorkingDirectory, icon)
+		}
+		ico, err := ioutil.ReadFile(icon)
+		if err != nil {
+			return nil, err
+		}
+		err = resources.AddIcon(1, 1, ico)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tp.Settings.GetTaskSettingBool(TASK_WINDOWS_RESOURCES, "manifest") {
+		manifest := winres.Manifest{
+			Name:           exeName,
+			Version:        version,
+			ExecutionLevel: tp.Settings.GetTaskSettingString(TASK_WINDOWS_RESOURCES, "execution-level"),
+			DpiAwareness:   tp.Settings.GetTaskSettingString(TASK_WINDOWS_RESOURCES, "dpi-awareness")}
+		data, err := manifest.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		resources.Add(winres.RT_MANIFEST, 1, winres.LANG_EN_US, data)
+	}
+	return resources, nil
+}
+
+// whether the go tool links the .syso when building for windows/arch, going by its name
+func sysoAppliesTo(dir, name, arch string) bool {
+	ctxt := build.Default
+	ctxt.GOOS = platforms.WINDOWS
+	ctxt.GOARCH = arch
+	matches, err := ctxt.MatchFile(dir, name)
+	return err != nil || matches
+}
+
+// writes the .syso into the main package dir. The _windows_<arch> suffix restricts it to that platform.
+// (It can't go elsewhere: 'go build -overlay' doesn't apply to .syso files)
+func writeSyso(tp TaskParams, resources *winres.Resources, mainDir, arch string) error {
+	existing, err := filepath.Glob(filepath.Join(mainDir, "*.syso"))
+	if err != nil {
+		return err
+	}
+	for _, e := range existing {
+		name := filepath.Base(e)
+		if strings.HasPrefix(name, SYSO_PREFIX) {
+			continue
+		}
+		//e.g. 'rsrc.syso', 'rsrc_windows.syso' or 'rsrc_windows_386.syso' would all be linked too
+		if !sysoAppliesTo(mainDir, name, arch) {
+			continue
+		}
+		log.Printf("WARNING: %s already exists. Not generating Windows resources for %s", e, arch)
+		return nil
+	}
+	sysoPath := filepath.Join(mainDir, SYSO_PREFIX+platforms.WINDOWS+"_"+arch+".syso")
+	f, err := os.Create(sysoPath)
+	if err != nil {
+		return err
+	}
+	registerCleanup(func() error {
+		if tp.Settings.IsVerbose() {
+			log.Printf("Removing %s", sysoPath)
+		}
+		return os.Remove(sysoPath)
+	})
+	defer f.Close()
+	err = resources.WriteSyso(f, arch)
+	if err != nil {
+		return err
+	}
+	if !tp.Settings.IsQuiet() {
+		log.Printf("Wrote Windows resources to %s", sysoPath)
+	}
+	return nil
+}
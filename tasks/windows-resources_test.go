@@ -0,0 +1,11 @@
+package tasks
+
+import "testing"
+
+func TestSysoAppliesTo(t *testing.T) {
+	for name, expected := range map[string]bool{"rsrc.syso": true, "rsrc_windows.syso": true, "rsrc_windows_amd64.syso": true, "rsrc_amd64.syso": true, "rsrc_windows_386.syso": false, "rsrc_linux.syso": false} {
+		if sysoAppliesTo(".", name, "amd64") != expected {
+			t.Errorf("%s: expected %v", name, expected)
+		}
+	}
+}
@@ -29,7 +29,6 @@ import (
 	// Tip for Forkers: please 'clone' from my url and then 'pull' from your url. That way you wont need to change the import path.
 	// see https://groups.google.com/forum/?fromgroups=#!starred/golang-nuts/CY7o2aVNGZY
 	"github.com/laher/argo/ar"
-	"github.com/laher/goxc/core"
 	"github.com/laher/goxc/executils"
 	"github.com/laher/goxc/exefileparse"
 	"github.com/laher/goxc/platforms"
@@ -48,7 +47,9 @@ func init() {
 			//"validation" : "tcBinExists,exeParse",
 			"validateToolchain":    false,
 			"verifyExe":            false,
-			"autoRebuildToolchain": false}})
+			"autoRebuildToolchain": false,
+			//build with -trimpath -buildvcs=false. Turned on when running verify-reproducible
+			"reproducible": false}})
 }
 
 func setupXc(tp TaskParams) ([]platforms.Platform, error) {
@@ -74,6 +75,12 @@ func setupXc(tp TaskParams) ([]platforms.Platform, error) {
 			}
 		}
 	}
+	if tp.Settings.Variant != "" && !strings.Contains(tp.Settings.OutPath, ".Variant") {
+		return []platforms.Platform{}, errors.New("Build variants would be compiled to the same path. Please make sure the {{.Variant}} variable is used in the OutPath. Currently the template is " + tp.Settings.OutPath)
+	}
+	if tp.Settings.GoVersion != "" && !strings.Contains(tp.Settings.OutPath, ".GoVersion") {
+		return []platforms.Platform{}, errors.New("Each Go toolchain would compile to the same path. Please make sure the {{.GoVersion}} variable is used in the OutPath. Currently the template is " + tp.Settings.OutPath)
+	}
 	//check for duplicate exePaths
 	exePaths := []string{}
 	for _, mainDir := range tp.MainDirs {
@@ -84,7 +91,7 @@ func setupXc(tp TaskParams) ([]platforms.Platform, error) {
 			exeName = filepath.Base(mainDir)
 		}
 		for _, dest := range tp.DestPlatforms {
-			absoluteBin, err := core.GetAbsoluteBin(dest.Os, dest.Arch, tp.Settings.AppName, exeName, tp.WorkingDirectory, tp.Settings.GetFullVersionName(), tp.Settings.OutPath, tp.Settings.ArtifactsDest)
+			absoluteBin, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
 			if err != nil {
 				return nil, err
 			}
@@ -238,8 +245,8 @@ func xcPlat(dest platforms.Platform, tp TaskParams, exeName string, packagePath
 	if tp.Settings.IsVerbose() {
 		log.Printf("building %s for platform %v.", exeName, dest)
 	}
-	args := []string{}
-	absoluteBin, err := core.GetAbsoluteBin(dest.Os, dest.Arch, tp.Settings.AppName, exeName, tp.WorkingDirectory, tp.Settings.GetFullVersionName(), tp.Settings.OutPath, tp.Settings.ArtifactsDest)
+	args := xcBuildArgs(tp)
+	absoluteBin, err := tp.Settings.GetAbsoluteBin(dest.Os, dest.Arch, exeName, tp.WorkingDirectory)
 	if err != nil {
 		return "", err
 	}
@@ -248,8 +255,27 @@ func xcPlat(dest platforms.Platform, tp TaskParams, exeName string, packagePath
 	if err != nil {
 		return "", err
 	}
-	args = append(args, "-o", absoluteBin, ".")
+	goDir, target := tp.GoBuildTarget(packagePath)
+	args = append(args, "-o", absoluteBin, target)
 	//log.Printf("building %s", exeName)
+	err = executils.InvokeGo(goDir, "build", args, xcEnv(dest, tp), tp.Settings)
+	if err == nil && tp.Settings.GetTaskSettingBool(TASK_XC, "reproducible") {
+		//signing tasks modify the binary, so keep a copy to verify against
+		err = snapshotUnsigned(absoluteBin, tp.Settings.IsVerbose())
+	}
+	return absoluteBin, err
+}
+
+// flags for 'go build', besides those in the BuildSettings
+func xcBuildArgs(tp TaskParams) []string {
+	if tp.Settings.GetTaskSettingBool(TASK_XC, "reproducible") {
+		return []string{"-trimpath", "-buildvcs=false"}
+	}
+	return []string{}
+}
+
+// env vars for cross-compiling to a given platform
+func xcEnv(dest platforms.Platform, tp TaskParams) []string {
 	//v0.8.5 no longer using CGO_ENABLED
 	envExtra := []string{"GOOS=" + dest.Os, "GOARCH=" + dest.Arch}
 	if dest.Os == platforms.LINUX && dest.Arch == platforms.ARM {
@@ -259,6 +285,5 @@ func xcPlat(dest platforms.Platform, tp TaskParams, exeName string, packagePath
 			envExtra = append(envExtra, "GOARM="+goarm)
 		}
 	}
-	err = executils.InvokeGo(packagePath, "build", args, envExtra, tp.Settings)
-	return absoluteBin, err
+	return envExtra
 }
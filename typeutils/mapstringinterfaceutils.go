@@ -79,6 +79,11 @@ func ToInt(v interface{}, k string) (int, error) {
 	switch typedV := v.(type) {
 	case int:
 		return typedV, nil
+	case float64:
+		//json numbers
+		if typedV == float64(int(typedV)) {
+			return int(typedV), nil
+		}
 	}
 	return 0, fmt.Errorf("%s should be a json int, not a %T", k, v)
 }